@@ -0,0 +1,420 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"emperror.dev/errors"
+)
+
+// DefaultSkip lists the fields of the upstream types that are never part of an override
+var DefaultSkip = []string{"TypeMeta", "Status"}
+
+// Config describes the override types to generate into a single file
+type Config struct {
+	// Package is the name of the generated package
+	Package string
+	// Header is prepended to the generated file, e.g. the license boilerplate
+	Header string
+	// Aliases of the imported packages by import path, defaults to `<group><version>` for k8s.io/api packages
+	// and the last path element otherwise
+	Aliases map[string]string
+	// Replacements map upstream types in the form of `<import path>.<type>` to local types, e.g. to replace
+	// metav1.ObjectMeta with the local ObjectMeta. Generated types replace their upstream counterpart implicitly.
+	Replacements map[string]string
+	Types        []TypeSpec
+}
+
+// TypeSpec is an override type derived from an upstream struct
+type TypeSpec struct {
+	// Name of the generated type, defaults to Type
+	Name string
+	// Package is the import path of the upstream type
+	Package string
+	// Type is the name of the upstream struct
+	Type string
+	// Doc of the generated type, defaults to the documentation of the upstream type
+	Doc string
+	// Skip lists the Go names of upstream fields to leave off in addition to DefaultSkip
+	Skip []string
+	// Markers are added to the type, e.g. CEL validation rules for the generated CRD schemas
+	Markers []string
+}
+
+func (s TypeSpec) name() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.Type
+}
+
+// sourceFile is a parsed upstream file with its imports resolved by local name
+type sourceFile struct {
+	pkgPath string
+	imports map[string]string
+}
+
+type generator struct {
+	config       Config
+	replacements map[string]string
+	imports      map[string]string
+	packages     map[string][]*ast.File
+	fset         *token.FileSet
+}
+
+// Generate renders the override types of the config into formatted Go source
+func Generate(config Config) ([]byte, error) {
+	g := &generator{
+		config:       config,
+		replacements: make(map[string]string),
+		imports:      make(map[string]string),
+		packages:     make(map[string][]*ast.File),
+		fset:         token.NewFileSet(),
+	}
+	for k, v := range config.Replacements {
+		g.replacements[k] = v
+	}
+	for _, t := range config.Types {
+		g.replacements[t.Package+"."+t.Type] = t.name()
+	}
+
+	var body bytes.Buffer
+	for _, t := range config.Types {
+		if err := g.generateType(&body, t); err != nil {
+			return nil, errors.WrapIff(err, "could not generate %s from %s.%s", t.name(), t.Package, t.Type)
+		}
+	}
+
+	aliases := make(map[string]string)
+	for pkgPath, alias := range g.imports {
+		if other, ok := aliases[alias]; ok {
+			return nil, errors.Errorf("import alias %s is used for both %s and %s, configure an alias for one of them", alias, other, pkgPath)
+		}
+		aliases[alias] = pkgPath
+	}
+
+	var out bytes.Buffer
+	if config.Header != "" {
+		out.WriteString(strings.TrimSpace(config.Header))
+		out.WriteString("\n\n")
+	}
+	out.WriteString("// Code generated by typeoverride-gen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "package %s\n\n", config.Package)
+	if len(g.imports) > 0 {
+		paths := make([]string, 0, len(g.imports))
+		for p := range g.imports {
+			paths = append(paths, p)
+		}
+		sort.Strings(paths)
+		out.WriteString("import (\n")
+		for _, p := range paths {
+			// package names of k8s.io/api are versions, so those are always aliased
+			if g.imports[p] == path.Base(p) && !strings.HasPrefix(p, "k8s.io/api/") {
+				fmt.Fprintf(&out, "\t%q\n", p)
+				continue
+			}
+			fmt.Fprintf(&out, "\t%s %q\n", g.imports[p], p)
+		}
+		out.WriteString(")\n")
+	}
+	out.Write(body.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, errors.WrapIf(err, "could not format generated source")
+	}
+	return src, nil
+}
+
+func (g *generator) generateType(w *bytes.Buffer, spec TypeSpec) error {
+	typeSpec, doc, file, err := g.lookup(spec.Package, spec.Type)
+	if err != nil {
+		return err
+	}
+	st, ok := typeSpec.Type.(*ast.StructType)
+	if !ok {
+		return errors.Errorf("%s is not a struct", spec.Type)
+	}
+
+	skip := make(map[string]bool)
+	for _, s := range append(append([]string{}, DefaultSkip...), spec.Skip...) {
+		skip[s] = true
+	}
+
+	typeDoc := spec.Doc
+	if typeDoc == "" && doc != nil {
+		// the doc of the upstream type starts with its name
+		typeDoc = strings.Replace(strings.TrimSpace(doc.Text()), spec.Type, spec.name(), 1)
+	}
+
+	fmt.Fprintf(w, "\n// +kubebuilder:object:generate=true\n")
+	for _, marker := range spec.Markers {
+		fmt.Fprintf(w, "// %s\n", marker)
+	}
+	w.WriteString("\n")
+	for _, line := range strings.Split(typeDoc, "\n") {
+		fmt.Fprintf(w, "// %s\n", line)
+	}
+	fmt.Fprintf(w, "type %s struct {\n", spec.name())
+	first := true
+	for _, field := range st.Fields.List {
+		name := fieldName(field)
+		if skip[name] || !ast.IsExported(name) {
+			continue
+		}
+
+		tag, ok := optionalTag(field.Tag)
+		if !ok {
+			continue
+		}
+
+		fieldType, err := g.typeExpr(field.Type, file)
+		if err != nil {
+			return errors.WrapIff(err, "invalid type of field %s", name)
+		}
+
+		if !first {
+			w.WriteString("\n")
+		}
+		first = false
+		for _, line := range optionalDoc(field.Doc, len(field.Names) > 0) {
+			fmt.Fprintf(w, "\t%s\n", line)
+		}
+		if len(field.Names) == 0 {
+			fmt.Fprintf(w, "\t%s %s\n", fieldType, tag)
+		} else {
+			for _, n := range field.Names {
+				fmt.Fprintf(w, "\t%s %s %s\n", n.Name, fieldType, tag)
+			}
+		}
+	}
+	fmt.Fprintf(w, "}\n")
+
+	return nil
+}
+
+// lookup finds the declaration of an upstream type in the sources of its package
+func (g *generator) lookup(pkgPath, typeName string) (*ast.TypeSpec, *ast.CommentGroup, *sourceFile, error) {
+	files, ok := g.packages[pkgPath]
+	if !ok {
+		bp, err := build.Import(pkgPath, ".", 0)
+		if err != nil {
+			return nil, nil, nil, errors.WrapIff(err, "could not find package %s", pkgPath)
+		}
+		for _, name := range bp.GoFiles {
+			f, err := parser.ParseFile(g.fset, filepath.Join(bp.Dir, name), nil, parser.ParseComments)
+			if err != nil {
+				return nil, nil, nil, errors.WrapIff(err, "could not parse package %s", pkgPath)
+			}
+			files = append(files, f)
+		}
+		g.packages[pkgPath] = files
+	}
+
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.TYPE {
+				continue
+			}
+			for _, s := range gd.Specs {
+				ts := s.(*ast.TypeSpec)
+				if ts.Name.Name != typeName {
+					continue
+				}
+				doc := ts.Doc
+				if doc == nil {
+					doc = gd.Doc
+				}
+				return ts, doc, &sourceFile{pkgPath: pkgPath, imports: fileImports(f)}, nil
+			}
+		}
+	}
+
+	return nil, nil, nil, errors.Errorf("type %s not found in package %s", typeName, pkgPath)
+}
+
+// typeExpr renders the type of an upstream field qualified with the imports of the generated file
+func (g *generator) typeExpr(expr ast.Expr, file *sourceFile) (string, error) {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if types.Universe.Lookup(e.Name) != nil {
+			return e.Name, nil
+		}
+		return g.qualified(file.pkgPath, e.Name), nil
+	case *ast.SelectorExpr:
+		x, ok := e.X.(*ast.Ident)
+		if !ok {
+			return "", errors.Errorf("unsupported selector %T", e.X)
+		}
+		pkgPath, ok := file.imports[x.Name]
+		if !ok {
+			return "", errors.Errorf("unknown package %s", x.Name)
+		}
+		return g.qualified(pkgPath, e.Sel.Name), nil
+	case *ast.StarExpr:
+		t, err := g.typeExpr(e.X, file)
+		return "*" + t, err
+	case *ast.ArrayType:
+		t, err := g.typeExpr(e.Elt, file)
+		if err != nil {
+			return "", err
+		}
+		if e.Len == nil {
+			return "[]" + t, nil
+		}
+		l, ok := e.Len.(*ast.BasicLit)
+		if !ok {
+			return "", errors.Errorf("unsupported array length %T", e.Len)
+		}
+		return "[" + l.Value + "]" + t, nil
+	case *ast.MapType:
+		k, err := g.typeExpr(e.Key, file)
+		if err != nil {
+			return "", err
+		}
+		v, err := g.typeExpr(e.Value, file)
+		return "map[" + k + "]" + v, err
+	default:
+		return "", errors.Errorf("unsupported type expression %T", expr)
+	}
+}
+
+func (g *generator) qualified(pkgPath, name string) string {
+	if local, ok := g.replacements[pkgPath+"."+name]; ok {
+		return local
+	}
+	alias, ok := g.imports[pkgPath]
+	if !ok {
+		alias = g.alias(pkgPath)
+		g.imports[pkgPath] = alias
+	}
+	return alias + "." + name
+}
+
+func (g *generator) alias(pkgPath string) string {
+	if alias, ok := g.config.Aliases[pkgPath]; ok {
+		return alias
+	}
+	if strings.HasPrefix(pkgPath, "k8s.io/api/") {
+		parts := strings.Split(strings.TrimPrefix(pkgPath, "k8s.io/api/"), "/")
+		return strings.ReplaceAll(strings.Join(parts, ""), ".", "")
+	}
+	return path.Base(pkgPath)
+}
+
+func fileImports(f *ast.File) map[string]string {
+	imports := make(map[string]string)
+	for _, imp := range f.Imports {
+		p, _ := strconv.Unquote(imp.Path.Value)
+		name := path.Base(p)
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		imports[name] = p
+	}
+	return imports
+}
+
+func fieldName(field *ast.Field) string {
+	if len(field.Names) > 0 {
+		return field.Names[0].Name
+	}
+	// embedded fields are named after their type
+	expr := field.Type
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.SelectorExpr:
+		return e.Sel.Name
+	}
+	return ""
+}
+
+// optionalTag keeps the json and patch strategy tags of a field and makes the field optional by adding omitempty.
+// It returns false for fields that are not serialized.
+func optionalTag(lit *ast.BasicLit) (string, bool) {
+	if lit == nil {
+		return "", false
+	}
+	raw, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return "", false
+	}
+	tag := reflect.StructTag(raw)
+
+	jsonTag, ok := tag.Lookup("json")
+	if !ok || jsonTag == "-" {
+		return "", false
+	}
+	parts := strings.Split(jsonTag, ",")
+	inline, omitempty := false, false
+	for _, opt := range parts[1:] {
+		inline = inline || opt == "inline"
+		omitempty = omitempty || opt == "omitempty"
+	}
+	if !inline && !omitempty {
+		parts = append(parts, "omitempty")
+	}
+
+	tags := []string{fmt.Sprintf("json:%q", strings.Join(parts, ","))}
+	for _, key := range []string{"patchStrategy", "patchMergeKey"} {
+		if v, ok := tag.Lookup(key); ok {
+			tags = append(tags, fmt.Sprintf("%s:%q", key, v))
+		}
+	}
+
+	return "`" + strings.Join(tags, " ") + "`", true
+}
+
+// optionalDoc returns the comment lines of a field without the markers that don't apply to override types,
+// marking named fields as optional
+func optionalDoc(doc *ast.CommentGroup, named bool) []string {
+	var lines []string
+	optional := false
+	if doc != nil {
+		for _, c := range doc.List {
+			text := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
+			switch {
+			case strings.HasPrefix(text, "+k8s:"), strings.HasPrefix(text, "+protobuf"), text == "+required":
+				continue
+			case text == "+optional":
+				optional = true
+			}
+			lines = append(lines, c.Text)
+		}
+	}
+	if named && !optional {
+		lines = append(lines, "// +optional")
+	}
+	return lines
+}
@@ -0,0 +1,142 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptionalTag(t *testing.T) {
+	tests := []struct {
+		name string
+		tag  string
+		want string
+		ok   bool
+	}{
+		{
+			name: "required field",
+			tag:  "`json:\"containers\" protobuf:\"bytes,2,rep,name=containers\"`",
+			want: "`json:\"containers,omitempty\"`",
+			ok:   true,
+		},
+		{
+			name: "patch strategy",
+			tag:  "`json:\"volumes,omitempty\" patchStrategy:\"merge,retainKeys\" patchMergeKey:\"name\" protobuf:\"bytes,1,rep,name=volumes\"`",
+			want: "`json:\"volumes,omitempty\" patchStrategy:\"merge,retainKeys\" patchMergeKey:\"name\"`",
+			ok:   true,
+		},
+		{
+			name: "inline",
+			tag:  "`json:\",inline\"`",
+			want: "`json:\",inline\"`",
+			ok:   true,
+		},
+		{
+			name: "not serialized",
+			tag:  "`json:\"-\"`",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := optionalTag(&ast.BasicLit{Kind: token.STRING, Value: tt.tag})
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestOptionalDoc(t *testing.T) {
+	doc := &ast.CommentGroup{List: []*ast.Comment{
+		{Text: "// The name of the port."},
+		{Text: "// +k8s:conversion-gen=false"},
+		{Text: "// +required"},
+		{Text: "// +listType=map"},
+	}}
+
+	assert.Equal(t, []string{
+		"// The name of the port.",
+		"// +listType=map",
+		"// +optional",
+	}, optionalDoc(doc, true))
+
+	assert.Empty(t, optionalDoc(nil, false))
+}
+
+func TestGenerate(t *testing.T) {
+	src, err := Generate(Config{
+		Package: "overrides",
+		Aliases: map[string]string{
+			metav1: "metav1",
+		},
+		Replacements: map[string]string{
+			metav1 + ".ObjectMeta": "ObjectMeta",
+		},
+		Types: []TypeSpec{
+			{Package: appsv1, Type: "Deployment", Doc: "Deployment override"},
+			{Package: appsv1, Type: "DeploymentStrategy", Name: "Strategy", Skip: []string{"RollingUpdate"}, Markers: []string{"+kubebuilder:validation:MinProperties=1"}},
+		},
+	})
+	require.NoError(t, err)
+
+	out := string(src)
+	assert.Contains(t, out, "// Code generated by typeoverride-gen. DO NOT EDIT.")
+	assert.Contains(t, out, "package overrides")
+	assert.Contains(t, out, "// +kubebuilder:object:generate=true\n\n// Deployment override\ntype Deployment struct {")
+	assert.Contains(t, out, "\tObjectMeta `json:\"metadata,omitempty\"`")
+	assert.NotContains(t, out, "TypeMeta")
+	assert.NotContains(t, out, "DeploymentStatus")
+	// the generated Strategy type replaces DeploymentStrategy in DeploymentSpec
+	assert.Contains(t, out, "Spec appsv1.DeploymentSpec `json:\"spec,omitempty\"`")
+	assert.Contains(t, out, "// +kubebuilder:object:generate=true\n// +kubebuilder:validation:MinProperties=1\n\n// Strategy describes how to replace existing pods with new ones.\ntype Strategy struct {")
+	assert.Contains(t, out, "Type appsv1.DeploymentStrategyType `json:\"type,omitempty\"`")
+	assert.NotContains(t, out, "RollingUpdate *")
+}
+
+func TestGenerateAliasConflict(t *testing.T) {
+	_, err := Generate(Config{
+		Package: "overrides",
+		Types: []TypeSpec{
+			{Package: corev1, Type: "ObjectReference"},
+			{Package: appsv1, Type: "DeploymentSpec"},
+		},
+		Aliases: map[string]string{
+			corev1: "v1",
+		},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "import alias v1")
+}
+
+// TestTypeOverridesUpToDate fails when the generated types are out of sync with the configuration or k8s.io/api
+func TestTypeOverridesUpToDate(t *testing.T) {
+	header, err := os.ReadFile("../../hack/boilerplate.go.txt")
+	require.NoError(t, err)
+
+	config := typeOverrides
+	config.Header = string(header)
+	src, err := Generate(config)
+	require.NoError(t, err)
+
+	current, err := os.ReadFile("../../pkg/typeoverride/zz_generated.override.go")
+	require.NoError(t, err)
+
+	assert.Equal(t, string(current), string(src), "run `go run ./cmd/typeoverride-gen` to regenerate the types")
+}
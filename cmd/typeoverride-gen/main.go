@@ -0,0 +1,62 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// typeoverride-gen derives the types of pkg/typeoverride from the k8s.io/api version in go.mod.
+// Required fields are declared as optional and the patch strategy of the upstream fields is preserved,
+// so that the package can be regenerated after every Kubernetes upgrade:
+//
+//	go run ./cmd/typeoverride-gen
+//
+// Run it from the root of the repository, followed by controller-gen to update the deepcopy functions.
+package main
+
+import (
+	"flag"
+	"os"
+
+	"emperror.dev/errors"
+
+	"github.com/cisco-open/operator-tools/pkg/utils"
+)
+
+var logger = utils.Log
+
+func main() {
+	output := flag.String("output", "pkg/typeoverride/zz_generated.override.go", "file to write the generated types to")
+	header := flag.String("header", "hack/boilerplate.go.txt", "file to prepend to the generated source")
+	flag.Parse()
+
+	if err := run(*output, *header); err != nil {
+		logger.Error(err, "failed to generate type overrides")
+		os.Exit(1)
+	}
+}
+
+func run(output, header string) error {
+	config := typeOverrides
+	if header != "" {
+		h, err := os.ReadFile(header)
+		if err != nil {
+			return errors.WrapIf(err, "could not read header")
+		}
+		config.Header = string(h)
+	}
+
+	src, err := Generate(config)
+	if err != nil {
+		return err
+	}
+
+	return errors.WrapIff(os.WriteFile(output, src, 0o644), "could not write %s", output)
+}
@@ -0,0 +1,139 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+const (
+	appsv1        = "k8s.io/api/apps/v1"
+	autoscalingv2 = "k8s.io/api/autoscaling/v2"
+	batchv1       = "k8s.io/api/batch/v1"
+	corev1        = "k8s.io/api/core/v1"
+	networkingv1  = "k8s.io/api/networking/v1"
+	policyv1      = "k8s.io/api/policy/v1"
+	metav1        = "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// typeOverrides are the types of pkg/typeoverride generated from their upstream counterparts
+var typeOverrides = Config{
+	Package: "typeoverride",
+	Aliases: map[string]string{
+		corev1: "v1",
+		metav1: "metav1",
+	},
+	// ObjectMeta, PodTemplateSpec and PersistentVolumeClaim are maintained by hand in override.go
+	Replacements: map[string]string{
+		metav1 + ".ObjectMeta":            "ObjectMeta",
+		corev1 + ".PodTemplateSpec":       "PodTemplateSpec",
+		corev1 + ".PersistentVolumeClaim": "PersistentVolumeClaim",
+	},
+	Types: []TypeSpec{
+		{
+			Package: appsv1,
+			Type:    "DaemonSetSpec",
+			Doc: "DaemonSetSpec is a subset of [DaemonSetSpec in k8s.io/api/apps/v1](https://kubernetes.io/docs/reference/generated/kubernetes-api/v1.31/#daemonsetspec-v1-apps) but with required fields declared as optional\n" +
+				"and [PodTemplateSpec replaced by the local variant](#podtemplatespec).",
+		},
+		{
+			Package: appsv1,
+			Type:    "DeploymentSpec",
+			Doc: "DeploymentSpec is a subset of [DeploymentSpec in k8s.io/api/apps/v1](https://kubernetes.io/docs/reference/generated/kubernetes-api/v1.31/#deploymentspec-v1-apps) but with required fields declared as optional\n" +
+				"and [PodTemplateSpec replaced by the local variant](#podtemplatespec).",
+		},
+		{
+			Package: appsv1,
+			Type:    "StatefulSetSpec",
+			Doc: "StatefulSetSpec is a subset of [StatefulSetSpec in k8s.io/api/apps/v1](https://kubernetes.io/docs/reference/generated/kubernetes-api/v1.31/#statefulsetspec-v1-apps) but with required fields declared as optional\n" +
+				"and [PodTemplateSpec](#podtemplatespec) and [PersistentVolumeClaim replaced by the local variant](#persistentvolumeclaim).",
+		},
+		{
+			Package: corev1,
+			Type:    "PodSpec",
+			Doc:     "PodSpec is a subset of [PodSpec in k8s.io/api/corev1](https://kubernetes.io/docs/reference/generated/kubernetes-api/v1.31/#podspec-v1-core). It's the same as the original PodSpec expect it allows for containers to be missing.",
+		},
+		{
+			Package: batchv1,
+			Type:    "Job",
+			Doc:     "Job is a subset of [Job in k8s.io/api/batch/v1](https://kubernetes.io/docs/reference/generated/kubernetes-api/v1.31/#job-v1-batch), with [JobSpec replaced by the local variant](#jobspec).",
+		},
+		{
+			Package: batchv1,
+			Type:    "JobSpec",
+			Doc: "JobSpec is a subset of [JobSpec in k8s.io/api/batch/v1](https://kubernetes.io/docs/reference/generated/kubernetes-api/v1.31/#jobspec-v1-batch) but with required fields declared as optional\n" +
+				"and [PodTemplateSpec replaced by the local variant](#podtemplatespec).",
+		},
+		{
+			Package: batchv1,
+			Type:    "CronJob",
+			Doc:     "CronJob is a subset of [CronJob in k8s.io/api/batch/v1](https://kubernetes.io/docs/reference/generated/kubernetes-api/v1.31/#cronjob-v1-batch), with [CronJobSpec replaced by the local variant](#cronjobspec).",
+		},
+		{
+			Package: batchv1,
+			Type:    "CronJobSpec",
+			Doc: "CronJobSpec is a subset of [CronJobSpec in k8s.io/api/batch/v1](https://kubernetes.io/docs/reference/generated/kubernetes-api/v1.31/#cronjobspec-v1-batch) but with required fields declared as optional\n" +
+				"and [JobTemplateSpec replaced by the local variant](#jobtemplatespec).",
+		},
+		{
+			Package: batchv1,
+			Type:    "JobTemplateSpec",
+			Doc:     "JobTemplateSpec is the same as [JobTemplateSpec in k8s.io/api/batch/v1](https://kubernetes.io/docs/reference/generated/kubernetes-api/v1.31/#jobtemplatespec-v1-batch) but with the [local ObjectMeta](#objectmeta) and [JobSpec](#jobspec) types embedded.",
+		},
+		{
+			Package: autoscalingv2,
+			Type:    "HorizontalPodAutoscaler",
+			Doc:     "HorizontalPodAutoscaler is a subset of [HorizontalPodAutoscaler in k8s.io/api/autoscaling/v2](https://kubernetes.io/docs/reference/generated/kubernetes-api/v1.31/#horizontalpodautoscaler-v2-autoscaling), with [HorizontalPodAutoscalerSpec replaced by the local variant](#horizontalpodautoscalerspec).",
+		},
+		{
+			Package: autoscalingv2,
+			Type:    "HorizontalPodAutoscalerSpec",
+			Doc: "HorizontalPodAutoscalerSpec is a subset of [HorizontalPodAutoscalerSpec in k8s.io/api/autoscaling/v2](https://kubernetes.io/docs/reference/generated/kubernetes-api/v1.31/#horizontalpodautoscalerspec-v2-autoscaling) but with required fields declared as optional\n" +
+				"and [CrossVersionObjectReference replaced by the local variant](#crossversionobjectreference).",
+			Markers: []string{
+				`+kubebuilder:validation:XValidation:rule="!has(self.minReplicas) || !has(self.maxReplicas) || self.minReplicas <= self.maxReplicas",message="minReplicas must not exceed maxReplicas"`,
+			},
+		},
+		{
+			Package: autoscalingv2,
+			Type:    "CrossVersionObjectReference",
+			Doc:     "CrossVersionObjectReference is the same as [CrossVersionObjectReference in k8s.io/api/autoscaling/v2](https://kubernetes.io/docs/reference/generated/kubernetes-api/v1.31/#crossversionobjectreference-v2-autoscaling) but with required fields declared as optional.",
+		},
+		{
+			Package: policyv1,
+			Type:    "PodDisruptionBudget",
+			Doc:     "PodDisruptionBudget is a subset of [PodDisruptionBudget in k8s.io/api/policy/v1](https://kubernetes.io/docs/reference/generated/kubernetes-api/v1.31/#poddisruptionbudget-v1-policy), with [PodDisruptionBudgetSpec replaced by the local variant](#poddisruptionbudgetspec).",
+		},
+		{
+			Package: policyv1,
+			Type:    "PodDisruptionBudgetSpec",
+			Doc:     "PodDisruptionBudgetSpec is the same as [PodDisruptionBudgetSpec in k8s.io/api/policy/v1](https://kubernetes.io/docs/reference/generated/kubernetes-api/v1.31/#poddisruptionbudgetspec-v1-policy).",
+			Markers: []string{
+				`+kubebuilder:validation:XValidation:rule="!(has(self.minAvailable) && has(self.maxUnavailable))",message="minAvailable and maxUnavailable are mutually exclusive"`,
+			},
+		},
+		{
+			Package: networkingv1,
+			Type:    "Ingress",
+			Doc:     "Ingress is a subset of [Ingress in k8s.io/api/networking/v1](https://kubernetes.io/docs/reference/generated/kubernetes-api/v1.31/#ingress-v1-networking-k8s-io).",
+		},
+		{
+			Package: networkingv1,
+			Type:    "NetworkPolicy",
+			Doc:     "NetworkPolicy is a subset of [NetworkPolicy in k8s.io/api/networking/v1](https://kubernetes.io/docs/reference/generated/kubernetes-api/v1.31/#networkpolicy-v1-networking-k8s-io), with [NetworkPolicySpec replaced by the local variant](#networkpolicyspec).",
+		},
+		{
+			Package: networkingv1,
+			Type:    "NetworkPolicySpec",
+			Doc:     "NetworkPolicySpec is the same as [NetworkPolicySpec in k8s.io/api/networking/v1](https://kubernetes.io/docs/reference/generated/kubernetes-api/v1.31/#networkpolicyspec-v1-networking-k8s-io) but with required fields declared as optional.",
+		},
+	},
+}
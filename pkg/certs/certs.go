@@ -0,0 +1,188 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certs
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"time"
+
+	"emperror.dev/errors"
+)
+
+// KeyPair is a PEM encoded certificate and private key
+type KeyPair struct {
+	Certificate []byte
+	PrivateKey  []byte
+}
+
+// GenerateCA creates a self-signed CA certificate valid from `now` for the given duration
+func GenerateCA(commonName string, now time.Time, validity time.Duration) (*KeyPair, error) {
+	template := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: commonName},
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	return generate(template, nil, nil, now, validity)
+}
+
+// GenerateServingCertificate creates a server certificate for the DNS names signed by the CA, valid from `now` for the given duration
+func GenerateServingCertificate(ca *KeyPair, commonName string, dnsNames []string, now time.Time, validity time.Duration) (*KeyPair, error) {
+	caCert, caKey, err := ca.Parse()
+	if err != nil {
+		return nil, errors.WrapIf(err, "invalid CA")
+	}
+
+	template := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: commonName},
+		DNSNames:    dnsNames,
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	return generate(template, caCert, caKey, now, validity)
+}
+
+func generate(template, parent *x509.Certificate, parentKey crypto.Signer, now time.Time, validity time.Duration) (*KeyPair, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, errors.WrapIf(err, "could not generate private key")
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, errors.WrapIf(err, "could not generate serial number")
+	}
+	template.SerialNumber = serial
+	// tolerate clock skew between the operator and the API server
+	template.NotBefore = now.Add(-5 * time.Minute)
+	template.NotAfter = now.Add(validity)
+
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	if err != nil {
+		return nil, errors.WrapIf(err, "could not create certificate")
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, errors.WrapIf(err, "could not encode private key")
+	}
+
+	return &KeyPair{
+		Certificate: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		PrivateKey:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}, nil
+}
+
+// Parse decodes the certificate and the private key of the key pair
+func (kp *KeyPair) Parse() (*x509.Certificate, crypto.Signer, error) {
+	cert, err := ParseCertificate(kp.Certificate)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	block, _ := pem.Decode(kp.PrivateKey)
+	if block == nil {
+		return nil, nil, errors.New("no PEM encoded private key found")
+	}
+	var key interface{}
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, nil, errors.WrapIf(err, "could not parse private key")
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, nil, errors.Errorf("unsupported private key type %T", key)
+	}
+
+	return cert, signer, nil
+}
+
+// ParseCertificate decodes the first certificate of PEM encoded data
+func ParseCertificate(data []byte) (*x509.Certificate, error) {
+	certs, err := ParseCertificates(data)
+	if err != nil {
+		return nil, err
+	}
+	return certs[0], nil
+}
+
+// ParseCertificates decodes all the certificates of PEM encoded data, e.g. a CA bundle
+func ParseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.WrapIf(err, "could not parse certificate")
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no PEM encoded certificate found")
+	}
+	return certs, nil
+}
+
+// NeedsRenewal returns true if the certificate expires within `renewBefore` from `now`
+func NeedsRenewal(cert *x509.Certificate, now time.Time, renewBefore time.Duration) bool {
+	return !now.Add(renewBefore).Before(cert.NotAfter)
+}
+
+// CABundle concatenates the PEM encoded certificates of the CAs, skipping the ones expired at `now`
+// and the ones already included, so that both the current and the previous CA are trusted during rotation
+func CABundle(now time.Time, cas ...[]byte) []byte {
+	var bundle bytes.Buffer
+	seen := make(map[string]bool)
+	for _, ca := range cas {
+		certs, err := ParseCertificates(ca)
+		if err != nil {
+			continue
+		}
+		for _, cert := range certs {
+			if !now.Before(cert.NotAfter) || seen[string(cert.Raw)] {
+				continue
+			}
+			seen[string(cert.Raw)] = true
+			bundle.Write(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+		}
+	}
+	return bundle.Bytes()
+}
@@ -0,0 +1,317 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certs
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/cisco-open/operator-tools/pkg/reconciler"
+	"github.com/cisco-open/operator-tools/pkg/resources"
+)
+
+const (
+	DefaultCAValidity      = 5 * 365 * 24 * time.Hour
+	DefaultServingValidity = 365 * 24 * time.Hour
+	DefaultRenewBefore     = 30 * 24 * time.Hour
+
+	// CABundleKey holds the trusted CAs in both the CA and the serving certificate Secrets
+	CABundleKey = reconciler.DefaultCABundleSecretKey
+
+	// minRequeueAfter keeps the reconciler from spinning when the renewal window is misconfigured
+	minRequeueAfter = time.Minute
+)
+
+// Config describes the certificates managed by a Reconciler
+type Config struct {
+	// Namespace of the Secrets
+	Namespace string
+	// CASecretName is the name of the Secret holding the self-signed CA
+	CASecretName string
+	// ServingSecretName is the name of the kubernetes.io/tls Secret holding the serving certificate signed by the CA
+	ServingSecretName string
+	// CommonName of the serving certificate, defaults to the first DNS name
+	CommonName string
+	// DNSNames of the serving certificate, e.g. `webhook.namespace.svc`
+	DNSNames []string
+	// CAValidity defaults to DefaultCAValidity
+	CAValidity time.Duration
+	// ServingValidity defaults to DefaultServingValidity
+	ServingValidity time.Duration
+	// RenewBefore is how long before expiry certificates are rotated, defaults to DefaultRenewBefore
+	RenewBefore time.Duration
+	// CABundleTargets are webhook configurations, CRDs with conversion webhooks and APIServices
+	// that get the CA bundle injected whenever it changes
+	CABundleTargets []reconciler.ObjectKeyWithGVK
+}
+
+func (c Config) withDefaults() Config {
+	if c.CommonName == "" && len(c.DNSNames) > 0 {
+		c.CommonName = c.DNSNames[0]
+	}
+	if c.CAValidity == 0 {
+		c.CAValidity = DefaultCAValidity
+	}
+	if c.ServingValidity == 0 {
+		c.ServingValidity = DefaultServingValidity
+	}
+	if c.RenewBefore == 0 {
+		c.RenewBefore = DefaultRenewBefore
+	}
+	return c
+}
+
+// Reconciler maintains a self-signed CA and a serving certificate in Secrets, rotates them before they expire
+// and keeps the CA bundle of the configured targets up to date. It implements reconciler.ComponentReconciler.
+type Reconciler struct {
+	client client.Client
+	log    logr.Logger
+	config Config
+	now    func() time.Time
+}
+
+type ReconcilerOption func(*Reconciler)
+
+// WithClock overrides the source of the current time
+func WithClock(now func() time.Time) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.now = now
+	}
+}
+
+func NewReconciler(client client.Client, log logr.Logger, config Config, opts ...ReconcilerOption) *Reconciler {
+	r := &Reconciler{
+		client: client,
+		log:    log,
+		config: config.withDefaults(),
+		now:    time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Reconcile makes sure the certificates exist and are not about to expire, and requeues at the time of the next rotation
+func (r *Reconciler) Reconcile(object runtime.Object) (*reconcile.Result, error) {
+	if len(r.config.DNSNames) == 0 {
+		return nil, errors.New("at least one DNS name is required for the serving certificate")
+	}
+
+	now := r.now()
+
+	ca, caBundle, err := r.reconcileCA(now)
+	if err != nil {
+		return nil, err
+	}
+
+	caCert, _, err := ca.Parse()
+	if err != nil {
+		return nil, errors.WrapIf(err, "invalid CA")
+	}
+
+	serving, err := r.reconcileServingCertificate(ca, caCert, caBundle, now)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, target := range r.config.CABundleTargets {
+		if err := r.injectCABundle(target, caBundle); err != nil {
+			return nil, err
+		}
+	}
+
+	requeueAfter := serving.NotAfter.Add(-r.config.RenewBefore).Sub(now)
+	if caRequeueAfter := caCert.NotAfter.Add(-r.config.RenewBefore).Sub(now); caRequeueAfter < requeueAfter {
+		requeueAfter = caRequeueAfter
+	}
+	if requeueAfter < minRequeueAfter {
+		requeueAfter = minRequeueAfter
+	}
+
+	return &reconcile.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// RegisterWatches is a no-op, rotation is driven by the requeue time returned from Reconcile
+func (r *Reconciler) RegisterWatches(_ *builder.Builder) {}
+
+// reconcileCA returns the current CA with the bundle of CAs to trust, rotating the CA if it is missing, invalid or about to expire
+func (r *Reconciler) reconcileCA(now time.Time) (*KeyPair, []byte, error) {
+	secret, err := r.getSecret(r.config.CASecretName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if secret != nil {
+		ca := keyPairFromSecret(secret)
+		if cert, _, err := ca.Parse(); err == nil && cert.IsCA && !NeedsRenewal(cert, now, r.config.RenewBefore) {
+			return ca, CABundle(now, ca.Certificate, secret.Data[CABundleKey]), nil
+		}
+	}
+
+	ca, err := GenerateCA(r.config.CommonName+"-ca", now, r.config.CAValidity)
+	if err != nil {
+		return nil, nil, errors.WrapIf(err, "could not generate CA")
+	}
+
+	var previous []byte
+	if secret != nil {
+		previous = secret.Data[corev1.TLSCertKey]
+	}
+	// the previous CA stays trusted until it expires, so that serving certificates signed by it remain valid during rotation
+	caBundle := CABundle(now, ca.Certificate, previous)
+
+	if err := r.writeSecret(secret, r.config.CASecretName, ca, caBundle); err != nil {
+		return nil, nil, err
+	}
+	r.log.Info("CA certificate generated", "secret", r.config.CASecretName)
+
+	return ca, caBundle, nil
+}
+
+// reconcileServingCertificate returns the current serving certificate, issuing a new one if it is missing, invalid,
+// about to expire, not signed by the current CA or not valid for the configured DNS names
+func (r *Reconciler) reconcileServingCertificate(ca *KeyPair, caCert *x509.Certificate, caBundle []byte, now time.Time) (*x509.Certificate, error) {
+	secret, err := r.getSecret(r.config.ServingSecretName)
+	if err != nil {
+		return nil, err
+	}
+
+	if secret != nil {
+		if cert, _, err := keyPairFromSecret(secret).Parse(); err == nil && r.isServingCertificateValid(cert, caCert, now) {
+			if !bytes.Equal(secret.Data[CABundleKey], caBundle) {
+				if err := r.writeSecret(secret, r.config.ServingSecretName, keyPairFromSecret(secret), caBundle); err != nil {
+					return nil, err
+				}
+			}
+			return cert, nil
+		}
+	}
+
+	// serving certificates must not outlive their CA
+	validity := r.config.ServingValidity
+	if caValidity := caCert.NotAfter.Sub(now); caValidity < validity {
+		validity = caValidity
+	}
+	serving, err := GenerateServingCertificate(ca, r.config.CommonName, r.config.DNSNames, now, validity)
+	if err != nil {
+		return nil, errors.WrapIf(err, "could not generate serving certificate")
+	}
+	if err := r.writeSecret(secret, r.config.ServingSecretName, serving, caBundle); err != nil {
+		return nil, err
+	}
+	r.log.Info("serving certificate generated", "secret", r.config.ServingSecretName)
+
+	cert, _, err := serving.Parse()
+	return cert, err
+}
+
+func (r *Reconciler) isServingCertificateValid(cert, ca *x509.Certificate, now time.Time) bool {
+	if NeedsRenewal(cert, now, r.config.RenewBefore) || cert.CheckSignatureFrom(ca) != nil {
+		return false
+	}
+	for _, name := range r.config.DNSNames {
+		if cert.VerifyHostname(name) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Reconciler) injectCABundle(target reconciler.ObjectKeyWithGVK, caBundle []byte) error {
+	current := &unstructured.Unstructured{}
+	current.SetGroupVersionKind(target.GVK)
+	if err := r.client.Get(context.TODO(), target.ObjectKey, current); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.log.V(1).Info("CA bundle target not found", "kind", target.GVK.Kind, "name", target.ObjectKey.Name)
+			return nil
+		}
+		return errors.WrapIfWithDetails(err, "could not get CA bundle target", "kind", target.GVK.Kind, "name", target.ObjectKey.Name)
+	}
+
+	desired := current.DeepCopy()
+	if _, err := resources.CABundleModifier(caBundle)(desired); err != nil {
+		return errors.WrapIfWithDetails(err, "could not inject CA bundle", "kind", target.GVK.Kind, "name", target.ObjectKey.Name)
+	}
+	if equality.Semantic.DeepEqual(current.Object, desired.Object) {
+		return nil
+	}
+
+	if err := r.client.Update(context.TODO(), desired); err != nil {
+		return errors.WrapIfWithDetails(err, "could not update CA bundle", "kind", target.GVK.Kind, "name", target.ObjectKey.Name)
+	}
+	r.log.Info("CA bundle updated", "kind", target.GVK.Kind, "name", target.ObjectKey.Name)
+
+	return nil
+}
+
+func (r *Reconciler) getSecret(name string) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	if err := r.client.Get(context.TODO(), client.ObjectKey{Namespace: r.config.Namespace, Name: name}, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.WrapIfWithDetails(err, "could not get certificate secret", "namespace", r.config.Namespace, "name", name)
+	}
+	return secret, nil
+}
+
+func (r *Reconciler) writeSecret(current *corev1.Secret, name string, kp *KeyPair, caBundle []byte) error {
+	data := map[string][]byte{
+		corev1.TLSCertKey:       kp.Certificate,
+		corev1.TLSPrivateKeyKey: kp.PrivateKey,
+		CABundleKey:             caBundle,
+	}
+
+	var err error
+	if current == nil {
+		err = r.client.Create(context.TODO(), &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: r.config.Namespace,
+			},
+			Type: corev1.SecretTypeTLS,
+			Data: data,
+		})
+	} else {
+		current.Data = data
+		err = r.client.Update(context.TODO(), current)
+	}
+
+	return errors.WrapIfWithDetails(err, "could not store certificate secret", "namespace", r.config.Namespace, "name", name)
+}
+
+func keyPairFromSecret(secret *corev1.Secret) *KeyPair {
+	return &KeyPair{
+		Certificate: secret.Data[corev1.TLSCertKey],
+		PrivateKey:  secret.Data[corev1.TLSPrivateKeyKey],
+	}
+}
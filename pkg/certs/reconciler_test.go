@@ -0,0 +1,128 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certs
+
+import (
+	"context"
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/cisco-open/operator-tools/pkg/reconciler"
+)
+
+func TestReconciler(t *testing.T) {
+	webhook := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-webhook"},
+		Webhooks:   []admissionregistrationv1.ValidatingWebhook{{Name: "test.example.com"}},
+	}
+	c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(webhook).Build()
+
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := NewReconciler(c, logr.Discard(), Config{
+		Namespace:         "test-ns",
+		CASecretName:      "test-ca",
+		ServingSecretName: "test-tls",
+		DNSNames:          []string{"test.test-ns.svc", "test.test-ns.svc.cluster.local"},
+		CAValidity:        100 * 24 * time.Hour,
+		ServingValidity:   30 * 24 * time.Hour,
+		RenewBefore:       10 * 24 * time.Hour,
+		CABundleTargets: []reconciler.ObjectKeyWithGVK{{
+			ObjectKey: client.ObjectKey{Name: "test-webhook"},
+			GVK:       admissionregistrationv1.SchemeGroupVersion.WithKind("ValidatingWebhookConfiguration"),
+		}},
+	}, WithClock(func() time.Time { return now }))
+
+	secrets := func() (*corev1.Secret, *corev1.Secret) {
+		ca, serving := &corev1.Secret{}, &corev1.Secret{}
+		require.NoError(t, c.Get(context.TODO(), client.ObjectKey{Namespace: "test-ns", Name: "test-ca"}, ca))
+		require.NoError(t, c.Get(context.TODO(), client.ObjectKey{Namespace: "test-ns", Name: "test-tls"}, serving))
+		return ca, serving
+	}
+	verify := func(serving *corev1.Secret, caBundle []byte) {
+		cert, err := ParseCertificate(serving.Data[corev1.TLSCertKey])
+		require.NoError(t, err)
+		roots := x509.NewCertPool()
+		require.True(t, roots.AppendCertsFromPEM(caBundle))
+		_, err = cert.Verify(x509.VerifyOptions{DNSName: "test.test-ns.svc", Roots: roots, CurrentTime: now})
+		assert.NoError(t, err)
+	}
+
+	result, err := r.Reconcile(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 20*24*time.Hour, result.RequeueAfter)
+
+	ca, serving := secrets()
+	assert.Equal(t, corev1.SecretTypeTLS, serving.Type)
+	assert.Equal(t, ca.Data[CABundleKey], serving.Data[CABundleKey])
+	verify(serving, serving.Data[CABundleKey])
+
+	require.NoError(t, c.Get(context.TODO(), client.ObjectKeyFromObject(webhook), webhook))
+	assert.Equal(t, ca.Data[CABundleKey], webhook.Webhooks[0].ClientConfig.CABundle)
+
+	// nothing changes until the renewal window
+	now = now.Add(5 * 24 * time.Hour)
+	_, err = r.Reconcile(nil)
+	require.NoError(t, err)
+	ca2, serving2 := secrets()
+	assert.Equal(t, ca.Data, ca2.Data)
+	assert.Equal(t, serving.Data, serving2.Data)
+
+	// the serving certificate is renewed by the same CA
+	now = now.Add(20 * 24 * time.Hour)
+	_, err = r.Reconcile(nil)
+	require.NoError(t, err)
+	ca2, serving2 = secrets()
+	assert.Equal(t, ca.Data, ca2.Data)
+	assert.NotEqual(t, serving.Data[corev1.TLSCertKey], serving2.Data[corev1.TLSCertKey])
+	verify(serving2, ca.Data[CABundleKey])
+
+	// the CA is rotated and the previous one is kept in the bundle until it expires
+	now = now.Add(70 * 24 * time.Hour)
+	_, err = r.Reconcile(nil)
+	require.NoError(t, err)
+	ca3, serving3 := secrets()
+	assert.NotEqual(t, ca.Data[corev1.TLSCertKey], ca3.Data[corev1.TLSCertKey])
+	bundle, err := ParseCertificates(ca3.Data[CABundleKey])
+	require.NoError(t, err)
+	assert.Len(t, bundle, 2)
+	verify(serving3, ca3.Data[corev1.TLSCertKey])
+
+	require.NoError(t, c.Get(context.TODO(), client.ObjectKeyFromObject(webhook), webhook))
+	assert.Equal(t, ca3.Data[CABundleKey], webhook.Webhooks[0].ClientConfig.CABundle)
+}
+
+func TestCABundle(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	expired, err := GenerateCA("expired", now.Add(-2*time.Hour), time.Hour)
+	require.NoError(t, err)
+	current, err := GenerateCA("current", now, time.Hour)
+	require.NoError(t, err)
+
+	bundle, err := ParseCertificates(CABundle(now, current.Certificate, expired.Certificate, current.Certificate))
+	require.NoError(t, err)
+	require.Len(t, bundle, 1)
+	assert.Equal(t, "current", bundle[0].Subject.CommonName)
+}
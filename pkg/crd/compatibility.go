@@ -0,0 +1,127 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+
+	"github.com/cisco-open/operator-tools/pkg/utils"
+)
+
+// DestructiveChangeError is returned when a CRD update would make existing custom resources unreadable or lose data
+type DestructiveChangeError struct {
+	Name    string
+	Changes []string
+}
+
+func (e *DestructiveChangeError) Error() string {
+	return fmt.Sprintf("refusing destructive changes of crd %s: %s", e.Name, strings.Join(e.Changes, "; "))
+}
+
+// DestructiveChanges lists the changes between the current and the desired CRD that would either
+// drop a version custom resources are stored in, stop serving a version or prune existing fields.
+func DestructiveChanges(current, desired *apiextensionsv1.CustomResourceDefinition) []string {
+	var changes []string
+
+	if current.Spec.Scope != desired.Spec.Scope {
+		changes = append(changes, fmt.Sprintf("scope changes from %s to %s", current.Spec.Scope, desired.Spec.Scope))
+	}
+
+	desiredVersions := make(map[string]apiextensionsv1.CustomResourceDefinitionVersion, len(desired.Spec.Versions))
+	for _, v := range desired.Spec.Versions {
+		desiredVersions[v.Name] = v
+	}
+
+	for _, stored := range current.Status.StoredVersions {
+		if _, ok := desiredVersions[stored]; !ok {
+			changes = append(changes, fmt.Sprintf("stored version %s is dropped", stored))
+		}
+	}
+
+	for _, cv := range current.Spec.Versions {
+		dv, ok := desiredVersions[cv.Name]
+		if !ok {
+			if !utils.Contains(current.Status.StoredVersions, cv.Name) {
+				changes = append(changes, fmt.Sprintf("version %s is removed", cv.Name))
+			}
+			continue
+		}
+		if cv.Served && !dv.Served {
+			changes = append(changes, fmt.Sprintf("version %s is no longer served", cv.Name))
+		}
+		if cv.Schema != nil && dv.Schema != nil {
+			for _, change := range schemaChanges("", cv.Schema.OpenAPIV3Schema, dv.Schema.OpenAPIV3Schema) {
+				changes = append(changes, fmt.Sprintf("version %s: %s", cv.Name, change))
+			}
+		}
+	}
+
+	return changes
+}
+
+func schemaChanges(path string, current, desired *apiextensionsv1.JSONSchemaProps) []string {
+	if current == nil || desired == nil {
+		return nil
+	}
+
+	var changes []string
+	if current.Type != "" && desired.Type != "" && current.Type != desired.Type {
+		changes = append(changes, fmt.Sprintf("type of %s changes from %s to %s", pathOrRoot(path), current.Type, desired.Type))
+		return changes
+	}
+
+	// fields pruned by the new schema would be silently dropped from stored objects
+	if !preservesUnknownFields(desired) {
+		names := make([]string, 0, len(current.Properties))
+		for name := range current.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			cp := current.Properties[name]
+			dp, ok := desired.Properties[name]
+			if !ok {
+				changes = append(changes, fmt.Sprintf("field %s is removed", path+"."+name))
+				continue
+			}
+			changes = append(changes, schemaChanges(path+"."+name, &cp, &dp)...)
+		}
+	}
+
+	if current.Items != nil && desired.Items != nil {
+		changes = append(changes, schemaChanges(path+"[]", current.Items.Schema, desired.Items.Schema)...)
+	}
+
+	if current.AdditionalProperties != nil && desired.AdditionalProperties != nil {
+		changes = append(changes, schemaChanges(path+"{}", current.AdditionalProperties.Schema, desired.AdditionalProperties.Schema)...)
+	}
+
+	return changes
+}
+
+func preservesUnknownFields(schema *apiextensionsv1.JSONSchemaProps) bool {
+	return schema.XPreserveUnknownFields != nil && *schema.XPreserveUnknownFields
+}
+
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "the root"
+	}
+	return path
+}
@@ -0,0 +1,136 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func testCRD(versions ...apiextensionsv1.CustomResourceDefinitionVersion) *apiextensionsv1.CustomResourceDefinition {
+	return &apiextensionsv1.CustomResourceDefinition{
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group:    "example.com",
+			Scope:    apiextensionsv1.NamespaceScoped,
+			Versions: versions,
+		},
+	}
+}
+
+func testVersion(name string, served bool, properties map[string]apiextensionsv1.JSONSchemaProps) apiextensionsv1.CustomResourceDefinitionVersion {
+	return apiextensionsv1.CustomResourceDefinitionVersion{
+		Name:   name,
+		Served: served,
+		Schema: &apiextensionsv1.CustomResourceValidation{
+			OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+				Type: "object",
+				Properties: map[string]apiextensionsv1.JSONSchemaProps{
+					"spec": {
+						Type:       "object",
+						Properties: properties,
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestDestructiveChanges(t *testing.T) {
+	v1Props := map[string]apiextensionsv1.JSONSchemaProps{
+		"replicas": {Type: "integer"},
+		"image":    {Type: "string"},
+	}
+
+	tests := map[string]struct {
+		current *apiextensionsv1.CustomResourceDefinition
+		desired *apiextensionsv1.CustomResourceDefinition
+		want    []string
+	}{
+		"new field is compatible": {
+			current: testCRD(testVersion("v1", true, v1Props)),
+			desired: testCRD(testVersion("v1", true, map[string]apiextensionsv1.JSONSchemaProps{
+				"replicas": {Type: "integer"},
+				"image":    {Type: "string"},
+				"tag":      {Type: "string"},
+			})),
+		},
+		"removed and retyped fields": {
+			current: testCRD(testVersion("v1", true, v1Props)),
+			desired: testCRD(testVersion("v1", true, map[string]apiextensionsv1.JSONSchemaProps{
+				"replicas": {Type: "string"},
+			})),
+			want: []string{
+				"version v1: field .spec.image is removed",
+				"version v1: type of .spec.replicas changes from integer to string",
+			},
+		},
+		"dropped stored version": {
+			current: func() *apiextensionsv1.CustomResourceDefinition {
+				crd := testCRD(testVersion("v1alpha1", true, v1Props), testVersion("v1", true, v1Props))
+				crd.Status.StoredVersions = []string{"v1alpha1", "v1"}
+				return crd
+			}(),
+			desired: testCRD(testVersion("v1", true, v1Props)),
+			want: []string{
+				"stored version v1alpha1 is dropped",
+			},
+		},
+		"unserved version": {
+			current: testCRD(testVersion("v1alpha1", true, v1Props), testVersion("v1", true, v1Props)),
+			desired: testCRD(testVersion("v1alpha1", false, v1Props), testVersion("v1", true, v1Props)),
+			want: []string{
+				"version v1alpha1 is no longer served",
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, DestructiveChanges(tt.current, tt.desired))
+		})
+	}
+}
+
+func TestSplitObjects(t *testing.T) {
+	legacy := &unstructured.Unstructured{}
+	legacy.SetAPIVersion("apiextensions.k8s.io/v1beta1")
+	legacy.SetKind("CustomResourceDefinition")
+	legacy.SetName("loggings.logging.banzaicloud.io")
+	require.NoError(t, unstructured.SetNestedField(legacy.Object, "logging.banzaicloud.io", "spec", "group"))
+	require.NoError(t, unstructured.SetNestedField(legacy.Object, "v1beta1", "spec", "version"))
+	require.NoError(t, unstructured.SetNestedField(legacy.Object, "Cluster", "spec", "scope"))
+	require.NoError(t, unstructured.SetNestedField(legacy.Object, "Logging", "spec", "names", "kind"))
+	require.NoError(t, unstructured.SetNestedField(legacy.Object, "loggings", "spec", "names", "plural"))
+
+	cm := &corev1.ConfigMap{}
+	cm.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("ConfigMap"))
+
+	crds, rest, err := SplitObjects([]runtime.Object{legacy, cm})
+	require.NoError(t, err)
+
+	assert.Equal(t, []runtime.Object{cm}, rest)
+	require.Len(t, crds, 1)
+	assert.Equal(t, "loggings.logging.banzaicloud.io", crds[0].Name)
+	assert.Equal(t, "apiextensions.k8s.io/v1", crds[0].APIVersion)
+	require.Len(t, crds[0].Spec.Versions, 1)
+	assert.Equal(t, "v1beta1", crds[0].Spec.Versions[0].Name)
+	assert.True(t, crds[0].Spec.Versions[0].Storage)
+}
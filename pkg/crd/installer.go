@@ -0,0 +1,262 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crd
+
+import (
+	"context"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/go-logr/logr"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/install"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/cisco-open/operator-tools/pkg/resources"
+)
+
+var DefaultEstablishedBackoff = wait.Backoff{
+	Duration: time.Second,
+	Factor:   1.5,
+	Steps:    10,
+}
+
+var conversionScheme = runtime.NewScheme()
+
+func init() {
+	install.Install(conversionScheme)
+}
+
+// Installer manages the lifecycle of CustomResourceDefinitions separately from other objects.
+// CRDs are applied before anything else, updates are checked for changes that would make
+// already stored custom resources unreadable, and removal on uninstall is opt-in.
+type Installer struct {
+	client                  client.Client
+	log                     logr.Logger
+	establishedBackoff      wait.Backoff
+	allowDestructiveChanges bool
+	removeOnUninstall       bool
+}
+
+type InstallerOption func(*Installer)
+
+func WithEstablishedBackoff(backoff wait.Backoff) InstallerOption {
+	return func(i *Installer) {
+		i.establishedBackoff = backoff
+	}
+}
+
+// WithDestructiveChangesAllowed lets updates through that drop versions or schema fields
+func WithDestructiveChangesAllowed() InstallerOption {
+	return func(i *Installer) {
+		i.allowDestructiveChanges = true
+	}
+}
+
+// WithRemoveOnUninstall deletes the CRDs (and with them all the custom resources) on uninstall
+func WithRemoveOnUninstall() InstallerOption {
+	return func(i *Installer) {
+		i.removeOnUninstall = true
+	}
+}
+
+func NewInstaller(client client.Client, log logr.Logger, opts ...InstallerOption) *Installer {
+	i := &Installer{
+		client:             client,
+		log:                log,
+		establishedBackoff: DefaultEstablishedBackoff,
+	}
+
+	for _, opt := range opts {
+		opt(i)
+	}
+
+	return i
+}
+
+// RemovesOnUninstall returns true if the installer is responsible for removing the CRDs
+func (i *Installer) RemovesOnUninstall() bool {
+	return i.removeOnUninstall
+}
+
+// Install creates or updates the given CRDs and waits for all of them to get established
+func (i *Installer) Install(crds []*apiextensionsv1.CustomResourceDefinition) error {
+	for _, crd := range crds {
+		if err := i.apply(crd.DeepCopy()); err != nil {
+			return err
+		}
+	}
+
+	for _, crd := range crds {
+		if err := i.waitForEstablished(crd.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Uninstall removes the given CRDs if removal on uninstall has been enabled
+func (i *Installer) Uninstall(crds []*apiextensionsv1.CustomResourceDefinition) error {
+	if !i.removeOnUninstall {
+		return nil
+	}
+
+	var combinedErr error
+	for _, crd := range crds {
+		current := &apiextensionsv1.CustomResourceDefinition{}
+		current.SetName(crd.Name)
+		if err := i.client.Delete(context.TODO(), current); client.IgnoreNotFound(err) != nil {
+			combinedErr = errors.Combine(combinedErr, errors.WrapIfWithDetails(err, "failed to remove crd", "name", crd.Name))
+			continue
+		}
+		i.log.Info("crd removed", "name", crd.Name)
+	}
+
+	return combinedErr
+}
+
+func (i *Installer) apply(desired *apiextensionsv1.CustomResourceDefinition) error {
+	log := i.log.WithValues("name", desired.Name)
+
+	desired.Status = apiextensionsv1.CustomResourceDefinitionStatus{}
+
+	current := &apiextensionsv1.CustomResourceDefinition{}
+	err := i.client.Get(context.TODO(), client.ObjectKey{Name: desired.Name}, current)
+	if apierrors.IsNotFound(err) {
+		if err := i.client.Create(context.TODO(), desired); err != nil {
+			return errors.WrapIfWithDetails(err, "failed to create crd", "name", desired.Name)
+		}
+		log.Info("crd created")
+		return nil
+	}
+	if err != nil {
+		return errors.WrapIfWithDetails(err, "failed to get crd", "name", desired.Name)
+	}
+
+	if changes := DestructiveChanges(current, desired); len(changes) > 0 {
+		if !i.allowDestructiveChanges {
+			return &DestructiveChangeError{Name: desired.Name, Changes: changes}
+		}
+		log.Info("applying destructive crd changes", "changes", changes)
+	}
+
+	// conversion webhook CA bundles are usually injected by cert-manager or the certs reconciler
+	if err := resources.KeepCABundles(current, desired); err != nil {
+		return errors.WrapIfWithDetails(err, "failed to keep ca bundle", "name", desired.Name)
+	}
+
+	desired.SetResourceVersion(current.GetResourceVersion())
+	if err := i.client.Update(context.TODO(), desired); err != nil {
+		return errors.WrapIfWithDetails(err, "failed to update crd", "name", desired.Name)
+	}
+	log.V(1).Info("crd updated")
+
+	return nil
+}
+
+func (i *Installer) waitForEstablished(name string) error {
+	var lastErr error
+	err := wait.ExponentialBackoff(i.establishedBackoff, func() (bool, error) {
+		crd := &apiextensionsv1.CustomResourceDefinition{}
+		if err := i.client.Get(context.TODO(), client.ObjectKey{Name: name}, crd); err != nil {
+			lastErr = err
+			return false, nil
+		}
+		for _, cond := range crd.Status.Conditions {
+			if cond.Type == apiextensionsv1.NamesAccepted && cond.Status == apiextensionsv1.ConditionFalse {
+				return false, errors.Errorf("names are not accepted for crd %s: %s", name, cond.Message)
+			}
+		}
+		return IsEstablished(crd), nil
+	})
+	if err != nil {
+		return errors.WrapIfWithDetails(errors.Combine(err, lastErr), "failed to wait for the crd to get established", "name", name)
+	}
+
+	return nil
+}
+
+// IsEstablished returns true if the CRD has been accepted by the API server and is ready to serve custom resources
+func IsEstablished(crd *apiextensionsv1.CustomResourceDefinition) bool {
+	for _, cond := range crd.Status.Conditions {
+		if cond.Type == apiextensionsv1.Established && cond.Status == apiextensionsv1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// SplitObjects separates CRDs from the rest of the objects.
+// Legacy v1beta1 and unstructured CRDs are converted to typed v1 CRDs.
+func SplitObjects(objects []runtime.Object) ([]*apiextensionsv1.CustomResourceDefinition, []runtime.Object, error) {
+	var crds []*apiextensionsv1.CustomResourceDefinition
+	var rest []runtime.Object
+
+	for _, o := range objects {
+		gvk := o.GetObjectKind().GroupVersionKind()
+		if gvk.Group != apiextensionsv1.GroupName || gvk.Kind != "CustomResourceDefinition" {
+			rest = append(rest, o)
+			continue
+		}
+
+		crd, err := ToV1(o)
+		if err != nil {
+			return nil, nil, err
+		}
+		crds = append(crds, crd)
+	}
+
+	return crds, rest, nil
+}
+
+// ToV1 converts a CRD object of any supported version and representation to a typed v1 CRD
+func ToV1(o runtime.Object) (*apiextensionsv1.CustomResourceDefinition, error) {
+	if u, ok := o.(*unstructured.Unstructured); ok {
+		typed, err := conversionScheme.New(u.GroupVersionKind())
+		if err != nil {
+			return nil, errors.WrapIfWithDetails(err, "unsupported crd version", "apiVersion", u.GetAPIVersion())
+		}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, typed); err != nil {
+			return nil, errors.WrapIfWithDetails(err, "failed to convert unstructured crd", "name", u.GetName())
+		}
+		o = typed
+	}
+
+	switch crd := o.(type) {
+	case *apiextensionsv1.CustomResourceDefinition:
+		return crd, nil
+	case *apiextensionsv1beta1.CustomResourceDefinition:
+		conversionScheme.Default(crd)
+		internal := &apiextensions.CustomResourceDefinition{}
+		if err := conversionScheme.Convert(crd, internal, nil); err != nil {
+			return nil, errors.WrapIfWithDetails(err, "failed to convert v1beta1 crd", "name", crd.Name)
+		}
+		out := &apiextensionsv1.CustomResourceDefinition{}
+		if err := conversionScheme.Convert(internal, out, nil); err != nil {
+			return nil, errors.WrapIfWithDetails(err, "failed to convert v1beta1 crd", "name", crd.Name)
+		}
+		out.SetGroupVersionKind(apiextensionsv1.SchemeGroupVersion.WithKind("CustomResourceDefinition"))
+		return out, nil
+	default:
+		return nil, errors.Errorf("unsupported crd type %T", o)
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func installerTestCRD(name string) *apiextensionsv1.CustomResourceDefinition {
+	crd := testCRD(testVersion("v1", true, map[string]apiextensionsv1.JSONSchemaProps{"replicas": {Type: "integer"}}))
+	crd.Name = name
+	crd.Spec.Names = apiextensionsv1.CustomResourceDefinitionNames{Kind: "Test", Plural: "tests"}
+	return crd
+}
+
+// newInstallerTestClient returns a fake client that reports the CRDs with the given conditions, as the API server would
+func newInstallerTestClient(t *testing.T, conditions []apiextensionsv1.CustomResourceDefinitionCondition, objects ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	require.NoError(t, apiextensionsv1.AddToScheme(scheme))
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			if err := c.Get(ctx, key, obj, opts...); err != nil {
+				return err
+			}
+			if crd, ok := obj.(*apiextensionsv1.CustomResourceDefinition); ok {
+				crd.Status.Conditions = conditions
+			}
+			return nil
+		},
+	}).Build()
+}
+
+var (
+	established      = apiextensionsv1.CustomResourceDefinitionCondition{Type: apiextensionsv1.Established, Status: apiextensionsv1.ConditionTrue}
+	namesNotAccepted = apiextensionsv1.CustomResourceDefinitionCondition{Type: apiextensionsv1.NamesAccepted, Status: apiextensionsv1.ConditionFalse, Message: "conflict"}
+	testBackoff      = wait.Backoff{Duration: time.Millisecond, Steps: 2}
+)
+
+func TestInstallerInstall(t *testing.T) {
+	caBundle := []byte("injected")
+	current := installerTestCRD("tests.example.com")
+	current.Spec.Conversion = &apiextensionsv1.CustomResourceConversion{
+		Strategy: apiextensionsv1.WebhookConverter,
+		Webhook: &apiextensionsv1.WebhookConversion{
+			ClientConfig:             &apiextensionsv1.WebhookClientConfig{CABundle: caBundle},
+			ConversionReviewVersions: []string{"v1"},
+		},
+	}
+	c := newInstallerTestClient(t, []apiextensionsv1.CustomResourceDefinitionCondition{established}, current)
+
+	updated := installerTestCRD("tests.example.com")
+	updated.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"].Properties["image"] = apiextensionsv1.JSONSchemaProps{Type: "string"}
+	updated.Spec.Conversion = &apiextensionsv1.CustomResourceConversion{
+		Strategy: apiextensionsv1.WebhookConverter,
+		Webhook: &apiextensionsv1.WebhookConversion{
+			ClientConfig:             &apiextensionsv1.WebhookClientConfig{},
+			ConversionReviewVersions: []string{"v1"},
+		},
+	}
+	created := installerTestCRD("others.example.com")
+
+	installer := NewInstaller(c, logr.Discard(), WithEstablishedBackoff(testBackoff))
+	require.NoError(t, installer.Install([]*apiextensionsv1.CustomResourceDefinition{updated, created}))
+
+	live := &apiextensionsv1.CustomResourceDefinition{}
+	require.NoError(t, c.Get(context.Background(), client.ObjectKey{Name: "tests.example.com"}, live))
+	assert.Contains(t, live.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"].Properties, "image")
+	assert.Equal(t, caBundle, live.Spec.Conversion.Webhook.ClientConfig.CABundle, "injected ca bundle should be kept")
+	assert.Empty(t, updated.Spec.Conversion.Webhook.ClientConfig.CABundle, "desired crd should not be modified")
+	require.NoError(t, c.Get(context.Background(), client.ObjectKey{Name: "others.example.com"}, live))
+
+	// dropping a served version is refused unless destructive changes are allowed
+	dropped := installerTestCRD("tests.example.com")
+	dropped.Spec.Versions[0].Name = "v2"
+	var destructive *DestructiveChangeError
+	assert.ErrorAs(t, installer.Install([]*apiextensionsv1.CustomResourceDefinition{dropped}), &destructive)
+	assert.NoError(t, NewInstaller(c, logr.Discard(), WithEstablishedBackoff(testBackoff), WithDestructiveChangesAllowed()).
+		Install([]*apiextensionsv1.CustomResourceDefinition{dropped}))
+}
+
+func TestInstallerWaitForEstablished(t *testing.T) {
+	crd := installerTestCRD("tests.example.com")
+
+	c := newInstallerTestClient(t, nil, crd)
+	err := NewInstaller(c, logr.Discard(), WithEstablishedBackoff(testBackoff)).waitForEstablished(crd.Name)
+	assert.ErrorContains(t, err, "failed to wait for the crd to get established")
+
+	c = newInstallerTestClient(t, []apiextensionsv1.CustomResourceDefinitionCondition{namesNotAccepted}, crd)
+	err = NewInstaller(c, logr.Discard(), WithEstablishedBackoff(testBackoff)).waitForEstablished(crd.Name)
+	assert.ErrorContains(t, err, "names are not accepted for crd tests.example.com: conflict")
+
+	c = newInstallerTestClient(t, []apiextensionsv1.CustomResourceDefinitionCondition{established})
+	err = NewInstaller(c, logr.Discard(), WithEstablishedBackoff(testBackoff)).waitForEstablished(crd.Name)
+	assert.ErrorContains(t, err, "not found")
+}
+
+func TestInstallerUninstall(t *testing.T) {
+	crds := []*apiextensionsv1.CustomResourceDefinition{installerTestCRD("tests.example.com"), installerTestCRD("missing.example.com")}
+
+	c := newInstallerTestClient(t, nil, installerTestCRD("tests.example.com"))
+	require.NoError(t, NewInstaller(c, logr.Discard()).Uninstall(crds))
+	require.NoError(t, c.Get(context.Background(), client.ObjectKey{Name: "tests.example.com"}, &apiextensionsv1.CustomResourceDefinition{}),
+		"crds should be kept unless removal is enabled")
+
+	require.NoError(t, NewInstaller(c, logr.Discard(), WithRemoveOnUninstall()).Uninstall(crds))
+	err := c.Get(context.Background(), client.ObjectKey{Name: "tests.example.com"}, &apiextensionsv1.CustomResourceDefinition{})
+	assert.True(t, apierrors.IsNotFound(err))
+}
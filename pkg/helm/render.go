@@ -19,6 +19,7 @@ import (
 	"net/http"
 	"os"
 	"path"
+	"sort"
 	"strings"
 
 	"emperror.dev/errors"
@@ -34,7 +35,13 @@ import (
 	"github.com/cisco-open/operator-tools/pkg/resources"
 )
 
-const legacyRequirementsFileName = "requirements.yaml"
+// optional files at the root of the chart that are only loaded when present
+var optionalChartFiles = []string{
+	"Chart.lock",
+	// dependencies of legacy apiVersion v1 charts are defined in requirements.yaml
+	"requirements.yaml",
+	"requirements.lock",
+}
 
 type ReleaseOptions struct {
 	Name         string
@@ -44,6 +51,11 @@ type ReleaseOptions struct {
 	IsInstall    bool
 	Scheme       *runtime.Scheme
 	Capabilities chartutil.Capabilities
+	// ParsingMode controls whether rendered objects are typed or unstructured
+	ParsingMode resources.ParsingMode
+	// APIMigrations rewrite objects of deprecated APIs based on the APIVersions of the capabilities, resources.DefaultAPIMigrations if empty.
+	// Nothing is migrated if the capabilities have no APIVersions, e.g. when they are left at their zero value.
+	APIMigrations []resources.APIMigration
 }
 
 func GetDefaultValues(fs http.FileSystem) ([]byte, error) {
@@ -62,16 +74,33 @@ func GetDefaultValues(fs http.FileSystem) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-func Render(fs http.FileSystem, values map[string]interface{}, releaseOptions ReleaseOptions, chartName string) ([]runtime.Object, error) {
+// LoadChart loads the chart from the given filesystem
+func LoadChart(fs http.FileSystem) (*chart.Chart, error) {
 	files, err := GetFiles(fs)
 	if err != nil {
 		return nil, err
 	}
 
+	return loader.LoadFiles(files)
+}
+
+func Render(fs http.FileSystem, values map[string]interface{}, releaseOptions ReleaseOptions, chartName string) ([]runtime.Object, error) {
+	objects, _, err := RenderWithAPIMigrationReport(fs, values, releaseOptions, chartName)
+	return objects, err
+}
+
+// RenderWithAPIMigrationReport renders the chart like Render and reports the objects of deprecated APIs
+// that have been rewritten to a version served by the cluster or left out as their API has been removed
+func RenderWithAPIMigrationReport(fs http.FileSystem, values map[string]interface{}, releaseOptions ReleaseOptions, chartName string) ([]runtime.Object, *resources.APIMigrationReport, error) {
+	files, err := GetFiles(fs)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Create chart and render templates
 	chrt, err := loader.LoadFiles(files)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	renderOpts := chartutil.ReleaseOptions{
@@ -81,16 +110,19 @@ func Render(fs http.FileSystem, values map[string]interface{}, releaseOptions Re
 		Namespace: releaseOptions.Namespace,
 	}
 
+	if err := checkDependencies(chrt); err != nil {
+		return nil, nil, err
+	}
 	if err := chartutil.ProcessDependencies(chrt, values); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	renderedValues, err := chartutil.ToRenderValues(chrt, values, renderOpts, &releaseOptions.Capabilities)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	renderedTemplates, err := engine.Render(chrt, renderedValues)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	crds := make(map[string]*chart.File)
@@ -98,33 +130,99 @@ func Render(fs http.FileSystem, values map[string]interface{}, releaseOptions Re
 		crds[crd.Filename] = crd.File
 	}
 
-	typedParser := resources.NewObjectParser(releaseOptions.Scheme)
+	typedParser := resources.NewObjectParser(releaseOptions.Scheme, resources.WithParsingMode(releaseOptions.ParsingMode))
 
 	parser := func(json []byte) (runtime.Object, error) {
-		return typedParser.ParseYAMLToK8sObject(json, resources.ReplaceAPIVersionYAMLModifier("autoscaling/v2beta1", "autoscaling/v1"))
+		return typedParser.ParseYAMLToK8sObject(json)
 	}
 
 	// Merge templates and inject
 	var objects []runtime.Object
-	for _, tmpl := range files {
-		if !strings.HasSuffix(tmpl.Name, "yaml") && !strings.HasSuffix(tmpl.Name, "yml") && !strings.HasSuffix(tmpl.Name, "tpl") {
-			continue
-		}
-		t := path.Join(chartName, tmpl.Name)
+	for _, t := range manifestPaths(files, chartName, renderedTemplates, crds) {
 		if renderedTemplate, ok := renderedTemplates[t]; ok {
 			objects, err = parseAndAppendObjects(parser, objects, renderedTemplate, t)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 		} else if crd, ok := crds[t]; ok {
 			objects, err = parseAndAppendObjects(parser, objects, string(crd.Data), t)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 		}
 	}
 
-	return objects, nil
+	return resources.NewAPIMigrator(releaseOptions.Capabilities.APIVersions, typedParser, releaseOptions.APIMigrations...).MigrateObjects(objects)
+}
+
+// checkDependencies makes sure that all the dependencies listed in Chart.yaml are available under charts/
+func checkDependencies(chrt *chart.Chart) error {
+	var missing []string
+	for _, req := range chrt.Metadata.Dependencies {
+		found := false
+		for _, dep := range chrt.Dependencies() {
+			if dep.Name() == req.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, req.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return errors.Errorf("found in Chart.yaml, but missing in charts/ directory: %s", strings.Join(missing, ", "))
+	}
+
+	for _, dep := range chrt.Dependencies() {
+		if err := checkDependencies(dep); err != nil {
+			return errors.WrapIff(err, "invalid dependencies of subchart %s", dep.Name())
+		}
+	}
+
+	return nil
+}
+
+// manifestPaths returns the paths of the rendered templates and CRDs in a stable order.
+// Files of the chart keep their order from the filesystem, while the manifests of aliased and packaged
+// subcharts, which have no matching file, follow them in lexical order, CRDs first.
+func manifestPaths(files []*loader.BufferedFile, chartName string, renderedTemplates map[string]string, crds map[string]*chart.File) []string {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, f := range files {
+		t := path.Join(chartName, f.Name)
+		if !isManifestFile(t) {
+			continue
+		}
+		if _, ok := renderedTemplates[t]; !ok {
+			if _, ok := crds[t]; !ok {
+				continue
+			}
+		}
+		paths = append(paths, t)
+		seen[t] = true
+	}
+
+	var remainingCRDs, remainingTemplates []string
+	for t := range crds {
+		if !seen[t] && isManifestFile(t) {
+			remainingCRDs = append(remainingCRDs, t)
+			seen[t] = true
+		}
+	}
+	for t := range renderedTemplates {
+		if !seen[t] && isManifestFile(t) {
+			remainingTemplates = append(remainingTemplates, t)
+		}
+	}
+	sort.Strings(remainingCRDs)
+	sort.Strings(remainingTemplates)
+
+	return append(append(paths, remainingCRDs...), remainingTemplates...)
+}
+
+func isManifestFile(name string) bool {
+	return strings.HasSuffix(name, "yaml") || strings.HasSuffix(name, "yml") || strings.HasSuffix(name, "tpl")
 }
 
 func parseAndAppendObjects(parser func([]byte) (runtime.Object, error), objects []runtime.Object, renderedTemplate, path string) ([]runtime.Object, error) {
@@ -165,11 +263,14 @@ func GetFiles(fs http.FileSystem) ([]*loader.BufferedFile, error) {
 		{
 			Name: chartutil.ChartfileName,
 		},
-		{
-			// Without requirements.yaml legacy charts's subdependencies will be processed but cannot be disabled
-			// See https://github.com/helm/helm/blob/e2442699fa4703456b16884990c5218c16ed16fc/pkg/chart/loader/load.go#L105
-			Name: legacyRequirementsFileName,
-		},
+	}
+	for _, name := range optionalChartFiles {
+		if f, err := fs.Open(name); err == nil {
+			f.Close()
+			files = append(files, &loader.BufferedFile{Name: name})
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
 	}
 
 	// if the Helm chart templates use some resource files (like dashboards), those should be put under resources
@@ -188,21 +289,15 @@ func GetFiles(fs http.FileSystem) ([]*loader.BufferedFile, error) {
 		}
 	}
 
-	filteredFiles := []*loader.BufferedFile{}
 	for _, f := range files {
 		data, err := readIntoBytes(fs, f.Name)
 		if err != nil {
-			if strings.HasSuffix(f.Name, legacyRequirementsFileName) {
-				continue
-			}
 			return nil, err
 		}
-
 		f.Data = data
-		filteredFiles = append(filteredFiles, f)
 	}
 
-	return filteredFiles, nil
+	return files, nil
 }
 
 func getFilesFromDir(fs http.FileSystem, dir http.File, files []*loader.BufferedFile, dirName string) ([]*loader.BufferedFile, error) {
@@ -213,7 +308,9 @@ func getFilesFromDir(fs http.FileSystem, dir http.File, files []*loader.Buffered
 
 	for _, file := range dirFiles {
 		filename := file.Name()
-		if strings.HasSuffix(filename, "yaml") || strings.HasSuffix(filename, "yml") || strings.HasSuffix(filename, "tpl") || strings.HasSuffix(filename, "json") {
+		// packaged subcharts are only loaded from the charts directories
+		packagedChart := strings.HasSuffix(filename, ".tgz") && path.Base(dirName) == chartutil.ChartsDir
+		if strings.HasSuffix(filename, "yaml") || strings.HasSuffix(filename, "yml") || strings.HasSuffix(filename, "tpl") || strings.HasSuffix(filename, "json") || packagedChart {
 			files = append(files, &loader.BufferedFile{
 				Name: dirName + "/" + filename,
 			})
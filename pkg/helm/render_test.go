@@ -21,6 +21,7 @@ import (
 	"github.com/ghodss/yaml"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart/loader"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -104,3 +105,86 @@ func TestRenderWithScheme(t *testing.T) {
 	_, ok := objects[0].(*v1.ServiceAccount)
 	assert.True(t, ok, "object should be a ServiceAccount")
 }
+
+func TestRenderChartWithDependencies(t *testing.T) {
+	chart := http.Dir("testdata/dependencies/parent")
+
+	render := func(values string) []string {
+		defaultValues, err := GetDefaultValues(chart)
+		require.NoError(t, err)
+
+		valuesMap := map[string]interface{}{}
+		require.NoError(t, yaml.Unmarshal(defaultValues, &valuesMap))
+		overrides := map[string]interface{}{}
+		require.NoError(t, yaml.Unmarshal([]byte(values), &overrides))
+
+		objects, err := Render(chart, MergeMaps(valuesMap, overrides), ReleaseOptions{
+			Name:      "release-name",
+			Namespace: "release-namespace",
+		}, "parent")
+		require.NoError(t, err)
+
+		var names []string
+		for _, o := range objects {
+			u, ok := o.(*unstructured.Unstructured)
+			require.True(t, ok, "object should be unstructured")
+			names = append(names, u.GetName())
+			if u.GetName() == "release-name-parent" {
+				assert.Equal(t, "8080", u.Object["data"].(map[string]interface{})["childPort"], "values should be imported from the child chart")
+			}
+		}
+		return names
+	}
+
+	testCases := []struct {
+		name     string
+		values   string
+		expected []string
+	}{
+		{
+			name:   "defaults",
+			values: "",
+			expected: []string{
+				"release-name-parent",
+				"release-name-child",
+				"release-name-grandchild",
+			},
+		},
+		{
+			name:   "nested dependency disabled by condition",
+			values: "child: {grandchild: {enabled: false}}",
+			expected: []string{
+				"release-name-parent",
+				"release-name-child",
+			},
+		},
+		{
+			name:   "aliased and packaged subcharts enabled",
+			values: "aliased: {enabled: true}\ntags: {optional: true}",
+			expected: []string{
+				"release-name-parent",
+				"release-name-child",
+				"release-name-grandchild",
+				"release-name-aliased-grandchild",
+				"release-name-aliased",
+				"release-name-packaged",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, render(tc.values))
+		})
+	}
+}
+
+func TestRenderChartWithMissingDependency(t *testing.T) {
+	files := []*loader.BufferedFile{
+		{Name: "Chart.yaml", Data: []byte("apiVersion: v2\nname: test\nversion: 0.1.0\ndependencies:\n- name: missing\n  version: 0.1.0\n")},
+	}
+	chrt, err := loader.LoadFiles(files)
+	require.NoError(t, err)
+
+	assert.EqualError(t, checkDependencies(chrt), "found in Chart.yaml, but missing in charts/ directory: missing")
+}
@@ -22,11 +22,12 @@ import (
 
 	"github.com/cisco-open/operator-tools/pkg/helm"
 	"github.com/cisco-open/operator-tools/pkg/reconciler"
+	"github.com/cisco-open/operator-tools/pkg/resources"
 	"github.com/cisco-open/operator-tools/pkg/utils"
 )
 
-func orderedChartObjectsWithState(releaseData *ReleaseData, scheme *runtime.Scheme, caps chartutil.Capabilities) ([]runtime.Object, reconciler.DesiredState, error) {
-	objects, err := chartObjects(releaseData, scheme, caps)
+func orderedChartObjectsWithState(releaseData *ReleaseData, scheme *runtime.Scheme, caps chartutil.Capabilities, mode resources.ParsingMode) ([]runtime.Object, reconciler.DesiredState, error) {
+	objects, err := chartObjects(releaseData, scheme, caps, mode)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -36,7 +37,7 @@ func orderedChartObjectsWithState(releaseData *ReleaseData, scheme *runtime.Sche
 	return objects, reconciler.StatePresent, nil
 }
 
-func chartObjects(releaseData *ReleaseData, scheme *runtime.Scheme, caps chartutil.Capabilities) ([]runtime.Object, error) {
+func chartObjects(releaseData *ReleaseData, scheme *runtime.Scheme, caps chartutil.Capabilities, mode resources.ParsingMode) ([]runtime.Object, error) {
 	chartDefaultValues, err := helm.GetDefaultValues(releaseData.Chart)
 	if err != nil {
 		return nil, errors.WrapIff(err, "could not get chart default values for %s", releaseData.ChartName)
@@ -54,6 +55,7 @@ func chartObjects(releaseData *ReleaseData, scheme *runtime.Scheme, caps chartut
 		Namespace:    releaseData.Namespace,
 		Scheme:       scheme,
 		Capabilities: caps,
+		ParsingMode:  mode,
 	}, releaseData.ChartName)
 	if err != nil {
 		return nil, errors.WrapIff(err, "could not render %s helm manifest objects", releaseData.ChartName)
@@ -0,0 +1,206 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package templatereconciler
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"emperror.dev/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+type DiffAction string
+
+const (
+	DiffActionAdded   DiffAction = "Added"
+	DiffActionRemoved DiffAction = "Removed"
+	DiffActionChanged DiffAction = "Changed"
+)
+
+// FieldChange describes a single field level difference of an object between two revisions
+type FieldChange struct {
+	Path     string
+	Previous interface{}
+	Current  interface{}
+}
+
+// ObjectDiff describes the difference of a single object between two revisions
+type ObjectDiff struct {
+	Group     string
+	Kind      string
+	Namespace string
+	Name      string
+	Action    DiffAction
+	// Fields is only populated for changed objects
+	Fields []FieldChange
+}
+
+func (d ObjectDiff) String() string {
+	gk := d.Kind
+	if d.Group != "" {
+		gk = d.Kind + "." + d.Group
+	}
+	if d.Namespace != "" {
+		return fmt.Sprintf("%s %s:%s/%s", strings.ToLower(string(d.Action)), gk, d.Namespace, d.Name)
+	}
+	return fmt.Sprintf("%s %s:%s", strings.ToLower(string(d.Action)), gk, d.Name)
+}
+
+type ManifestDiff struct {
+	Objects []ObjectDiff
+}
+
+func (d *ManifestDiff) IsEmpty() bool {
+	return d == nil || len(d.Objects) == 0
+}
+
+// Diff calculates object and field level changes between two sets of rendered objects.
+// Objects are identified by group, kind, namespace and name so that an api version bump
+// of an object shows up as a change instead of a removal and an addition.
+func Diff(previous, current []runtime.Object) (*ManifestDiff, error) {
+	prev, err := indexObjects(previous)
+	if err != nil {
+		return nil, errors.WrapIf(err, "failed to index previous objects")
+	}
+	curr, err := indexObjects(current)
+	if err != nil {
+		return nil, errors.WrapIf(err, "failed to index current objects")
+	}
+
+	diff := &ManifestDiff{}
+	for key, c := range curr {
+		p, ok := prev[key]
+		if !ok {
+			diff.Objects = append(diff.Objects, key.diff(DiffActionAdded, nil))
+			continue
+		}
+		fields := diffValues("", p, c, nil)
+		if len(fields) > 0 {
+			diff.Objects = append(diff.Objects, key.diff(DiffActionChanged, fields))
+		}
+	}
+	for key := range prev {
+		if _, ok := curr[key]; !ok {
+			diff.Objects = append(diff.Objects, key.diff(DiffActionRemoved, nil))
+		}
+	}
+
+	sort.Slice(diff.Objects, func(i, j int) bool {
+		return diff.Objects[i].String() < diff.Objects[j].String()
+	})
+
+	return diff, nil
+}
+
+type objectDiffKey struct {
+	group     string
+	kind      string
+	namespace string
+	name      string
+}
+
+func (k objectDiffKey) diff(action DiffAction, fields []FieldChange) ObjectDiff {
+	return ObjectDiff{
+		Group:     k.group,
+		Kind:      k.kind,
+		Namespace: k.namespace,
+		Name:      k.name,
+		Action:    action,
+		Fields:    fields,
+	}
+}
+
+func indexObjects(objects []runtime.Object) (map[objectDiffKey]map[string]interface{}, error) {
+	index := make(map[objectDiffKey]map[string]interface{}, len(objects))
+	for _, o := range objects {
+		objMeta, err := meta.Accessor(o)
+		if err != nil {
+			return nil, err
+		}
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(o)
+		if err != nil {
+			return nil, errors.WrapIfWithDetails(err, "could not convert object to unstructured", "name", objMeta.GetName())
+		}
+		gvk := o.GetObjectKind().GroupVersionKind()
+		index[objectDiffKey{
+			group:     gvk.Group,
+			kind:      gvk.Kind,
+			namespace: objMeta.GetNamespace(),
+			name:      objMeta.GetName(),
+		}] = content
+	}
+	return index, nil
+}
+
+func diffValues(path string, previous, current interface{}, changes []FieldChange) []FieldChange {
+	switch p := previous.(type) {
+	case map[string]interface{}:
+		c, ok := current.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(p)+len(c))
+		for k := range p {
+			keys = append(keys, k)
+		}
+		for k := range c {
+			if _, ok := p[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			changes = diffValues(joinFieldPath(path, k), p[k], c[k], changes)
+		}
+		return changes
+	case []interface{}:
+		c, ok := current.([]interface{})
+		if !ok {
+			break
+		}
+		for i := 0; i < len(p) || i < len(c); i++ {
+			var pv, cv interface{}
+			if i < len(p) {
+				pv = p[i]
+			}
+			if i < len(c) {
+				cv = c[i]
+			}
+			changes = diffValues(fmt.Sprintf("%s[%d]", path, i), pv, cv, changes)
+		}
+		return changes
+	}
+
+	if !reflect.DeepEqual(previous, current) {
+		changes = append(changes, FieldChange{
+			Path:     path,
+			Previous: previous,
+			Current:  current,
+		})
+	}
+
+	return changes
+}
+
+func joinFieldPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
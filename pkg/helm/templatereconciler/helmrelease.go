@@ -0,0 +1,304 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package templatereconciler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"emperror.dev/errors"
+	"helm.sh/helm/v3/pkg/release"
+	helmtime "helm.sh/helm/v3/pkg/time"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/cisco-open/operator-tools/pkg/helm"
+	"github.com/cisco-open/operator-tools/pkg/reconciler"
+)
+
+const (
+	helmReleaseSecretType  = "helm.sh/release.v1"
+	helmReleaseOwner       = "helm"
+	helmReleaseDataKey     = "release"
+	helmReleaseNameLabel   = "name"
+	helmReleaseOwnerLabel  = "owner"
+	helmReleaseStatusLabel = "status"
+	helmReleaseVerLabel    = "version"
+)
+
+// helmReleaseStorage reads and writes release records the same way Helm's Secret storage driver does,
+// so that `helm list`, `helm history` and `helm get` work for releases installed by the operator.
+type helmReleaseStorage struct {
+	client       client.Client
+	historyLimit int
+}
+
+type storedHelmRelease struct {
+	release *release.Release
+	secret  *corev1.Secret
+}
+
+func helmReleaseSecretName(name string, version int) string {
+	return fmt.Sprintf("sh.helm.release.v1.%s.v%d", name, version)
+}
+
+// List returns the stored records of a release in ascending version order
+func (s *helmReleaseStorage) List(namespace, name string) ([]storedHelmRelease, error) {
+	secrets := &corev1.SecretList{}
+	if err := s.client.List(context.TODO(), secrets, client.InNamespace(namespace), client.MatchingLabels{
+		helmReleaseOwnerLabel: helmReleaseOwner,
+		helmReleaseNameLabel:  name,
+	}); err != nil {
+		return nil, errors.WrapIfWithDetails(err, "could not list helm release records", "namespace", namespace, "release", name)
+	}
+
+	releases := make([]storedHelmRelease, 0, len(secrets.Items))
+	for i := range secrets.Items {
+		rls, err := decodeHelmRelease(string(secrets.Items[i].Data[helmReleaseDataKey]))
+		if err != nil {
+			return nil, errors.WrapIfWithDetails(err, "could not decode helm release record", "name", secrets.Items[i].Name)
+		}
+		releases = append(releases, storedHelmRelease{release: rls, secret: &secrets.Items[i]})
+	}
+
+	sort.Slice(releases, func(i, j int) bool {
+		return releases[i].release.Version < releases[j].release.Version
+	})
+
+	return releases, nil
+}
+
+// Deployed returns the currently deployed record of a release or nil if there is none
+func (s *helmReleaseStorage) Deployed(namespace, name string) (*release.Release, error) {
+	releases, err := s.List(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(releases) - 1; i >= 0; i-- {
+		if releases[i].release.Info != nil && releases[i].release.Info.Status == release.StatusDeployed {
+			return releases[i].release, nil
+		}
+	}
+	return nil, nil
+}
+
+// Record stores a new deployed version of the release unless the manifest is identical to the deployed one.
+// The previously deployed version is marked superseded and versions over the history limit are removed.
+func (s *helmReleaseStorage) Record(releaseData *ReleaseData, manifest string) error {
+	releases, err := s.List(releaseData.Namespace, releaseData.ReleaseName)
+	if err != nil {
+		return err
+	}
+
+	now := helmtime.Now()
+	rls := &release.Release{
+		Name:      releaseData.ReleaseName,
+		Namespace: releaseData.Namespace,
+		Version:   1,
+		Config:    releaseData.Values,
+		Manifest:  manifest,
+		Info: &release.Info{
+			FirstDeployed: now,
+			LastDeployed:  now,
+			Status:        release.StatusDeployed,
+			Description:   "Install complete",
+		},
+	}
+
+	if len(releases) > 0 {
+		latest := releases[len(releases)-1].release
+		if latest.Info != nil && latest.Info.Status == release.StatusDeployed && latest.Manifest == manifest {
+			return nil
+		}
+		rls.Version = latest.Version + 1
+		rls.Info.Description = "Upgrade complete"
+		if latest.Info != nil && !latest.Info.FirstDeployed.IsZero() {
+			rls.Info.FirstDeployed = latest.Info.FirstDeployed
+		}
+	}
+
+	chrt, err := helm.LoadChart(releaseData.Chart)
+	if err != nil {
+		return errors.WrapIff(err, "could not load chart %s", releaseData.ChartName)
+	}
+	rls.Chart = chrt
+
+	for _, stored := range releases {
+		if stored.release.Info == nil || stored.release.Info.Status != release.StatusDeployed {
+			continue
+		}
+		stored.release.Info.Status = release.StatusSuperseded
+		if err := s.write(stored.release, stored.secret); err != nil {
+			return err
+		}
+	}
+
+	if err := s.write(rls, nil); err != nil {
+		return err
+	}
+
+	releases = append(releases, storedHelmRelease{release: rls})
+	for len(releases) > s.historyLimit {
+		if err := s.client.Delete(context.TODO(), releases[0].secret); client.IgnoreNotFound(err) != nil {
+			return errors.WrapIfWithDetails(err, "could not remove helm release record", "name", releases[0].secret.Name)
+		}
+		releases = releases[1:]
+	}
+
+	return nil
+}
+
+// Remove deletes all the records of a release
+func (s *helmReleaseStorage) Remove(namespace, name string) error {
+	return errors.WrapIfWithDetails(s.client.DeleteAllOf(context.TODO(), &corev1.Secret{},
+		client.InNamespace(namespace),
+		client.MatchingLabels{
+			helmReleaseOwnerLabel: helmReleaseOwner,
+			helmReleaseNameLabel:  name,
+		},
+	), "could not remove helm release records", "namespace", namespace, "release", name)
+}
+
+func (s *helmReleaseStorage) write(rls *release.Release, current *corev1.Secret) error {
+	encoded, err := encodeHelmRelease(rls)
+	if err != nil {
+		return errors.WrapIfWithDetails(err, "could not encode helm release record", "release", rls.Name, "version", rls.Version)
+	}
+
+	secret := current
+	if secret == nil {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      helmReleaseSecretName(rls.Name, rls.Version),
+				Namespace: rls.Namespace,
+				Labels: map[string]string{
+					"createdAt": strconv.Itoa(int(rls.Info.LastDeployed.Unix())),
+				},
+			},
+			Type: helmReleaseSecretType,
+		}
+	}
+	if secret.Labels == nil {
+		secret.Labels = make(map[string]string)
+	}
+	secret.Labels[helmReleaseNameLabel] = rls.Name
+	secret.Labels[helmReleaseOwnerLabel] = helmReleaseOwner
+	secret.Labels[helmReleaseStatusLabel] = rls.Info.Status.String()
+	secret.Labels[helmReleaseVerLabel] = strconv.Itoa(rls.Version)
+	secret.Data = map[string][]byte{helmReleaseDataKey: []byte(encoded)}
+
+	if current == nil {
+		err = s.client.Create(context.TODO(), secret)
+	} else {
+		secret.Labels["modifiedAt"] = strconv.Itoa(int(helmtime.Now().Unix()))
+		err = s.client.Update(context.TODO(), secret)
+	}
+
+	return errors.WrapIfWithDetails(err, "could not store helm release record", "name", secret.Name)
+}
+
+// encodeHelmRelease follows the format of Helm's storage drivers: base64 encoded gzipped JSON
+func encodeHelmRelease(rls *release.Release) (string, error) {
+	b, err := json.Marshal(rls)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.Write(b); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+func decodeHelmRelease(data string) (*release.Release, error) {
+	b, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// releases stored by old Helm versions are not compressed
+	if len(b) > 3 && bytes.Equal(b[0:3], []byte{0x1f, 0x8b, 0x08}) {
+		r, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		if b, err = io.ReadAll(r); err != nil {
+			return nil, err
+		}
+	}
+
+	rls := &release.Release{}
+	if err := json.Unmarshal(b, rls); err != nil {
+		return nil, err
+	}
+
+	return rls, nil
+}
+
+func (rec *HelmReconciler) helmReleaseStorage() *helmReleaseStorage {
+	limit := rec.helmReleaseLimit
+	if limit == 0 {
+		limit = DefaultRevisionHistoryLimit
+	}
+	return &helmReleaseStorage{
+		client:       rec.client,
+		historyLimit: limit,
+	}
+}
+
+// adoptHelmRelease seeds the inventory of the component with the objects of the deployed Helm release, if there is one
+func (rec *HelmReconciler) adoptHelmRelease(parent reconciler.ResourceOwner, releaseData *ReleaseData) error {
+	if rec.inventory == nil {
+		return nil
+	}
+
+	deployed, err := rec.helmReleaseStorage().Deployed(releaseData.Namespace, releaseData.ReleaseName)
+	if err != nil || deployed == nil {
+		return err
+	}
+
+	objects, err := rec.objectParser.ParseYAMLManifest(deployed.Manifest)
+	if err != nil {
+		return errors.WrapIfWithDetails(err, "could not parse helm release manifest", "release", deployed.Name, "version", deployed.Version)
+	}
+
+	adopted, err := rec.inventory.Adopt(releaseData.Namespace, releaseData.ReleaseName, parent, objects)
+	if err != nil {
+		return errors.WrapIfWithDetails(err, "could not adopt helm release", "release", deployed.Name, "version", deployed.Version)
+	}
+	if adopted {
+		rec.logger.Info("helm release adopted", "release", deployed.Name, "version", deployed.Version)
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package templatereconciler
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/release"
+	corev1 "k8s.io/api/core/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestHelmReleaseEncoding(t *testing.T) {
+	rls := &release.Release{
+		Name:      "test",
+		Namespace: "test-ns",
+		Version:   3,
+		Manifest:  "kind: ConfigMap",
+		Info:      &release.Info{Status: release.StatusDeployed},
+	}
+
+	encoded, err := encodeHelmRelease(rls)
+	require.NoError(t, err)
+
+	decoded, err := decodeHelmRelease(encoded)
+	require.NoError(t, err)
+	assert.Equal(t, rls.Name, decoded.Name)
+	assert.Equal(t, rls.Version, decoded.Version)
+	assert.Equal(t, rls.Manifest, decoded.Manifest)
+	assert.Equal(t, release.StatusDeployed, decoded.Info.Status)
+}
+
+func TestHelmReleaseStorage(t *testing.T) {
+	c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).Build()
+	storage := &helmReleaseStorage{client: c, historyLimit: 2}
+	releaseData := &ReleaseData{
+		Chart:       http.Dir("../testdata/templates/logging-operator"),
+		ChartName:   "logging-operator",
+		ReleaseName: "test",
+		Namespace:   "test-ns",
+		Values:      map[string]interface{}{"replicaCount": 2},
+	}
+
+	for _, manifest := range []string{"v1", "v2", "v3", "v3"} {
+		require.NoError(t, storage.Record(releaseData, manifest))
+	}
+
+	releases, err := storage.List("test-ns", "test")
+	require.NoError(t, err)
+	require.Len(t, releases, 2)
+	assert.Equal(t, 2, releases[0].release.Version)
+	assert.Equal(t, release.StatusSuperseded, releases[0].release.Info.Status)
+	assert.Equal(t, "superseded", releases[0].secret.Labels["status"])
+	assert.Equal(t, 3, releases[1].release.Version)
+	assert.Equal(t, "Upgrade complete", releases[1].release.Info.Description)
+	assert.Equal(t, "logging-operator", releases[1].release.Chart.Name())
+
+	secret := &corev1.Secret{}
+	require.NoError(t, c.Get(context.TODO(), client.ObjectKey{Namespace: "test-ns", Name: "sh.helm.release.v1.test.v3"}, secret))
+	assert.Equal(t, corev1.SecretType("helm.sh/release.v1"), secret.Type)
+	assert.Equal(t, "deployed", secret.Labels["status"])
+
+	deployed, err := storage.Deployed("test-ns", "test")
+	require.NoError(t, err)
+	require.NotNil(t, deployed)
+	assert.Equal(t, "v3", deployed.Manifest)
+
+	require.NoError(t, storage.Remove("test-ns", "test"))
+	releases, err = storage.List("test-ns", "test")
+	require.NoError(t, err)
+	assert.Empty(t, releases)
+}
@@ -0,0 +1,374 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package templatereconciler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"emperror.dev/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/cisco-open/operator-tools/pkg/reconciler"
+	"github.com/cisco-open/operator-tools/pkg/resources"
+	"github.com/cisco-open/operator-tools/pkg/types"
+	"github.com/cisco-open/operator-tools/pkg/utils"
+)
+
+const (
+	DefaultRevisionHistoryLimit = 10
+
+	revisionManifestKeySuffix = ".manifest.gz"
+	revisionHashKeySuffix     = ".sha256"
+	failedRevisionHashKey     = "failed" + revisionHashKeySuffix
+	pinnedRevisionKey         = "pinned"
+	pinnedRevisionHashKey     = pinnedRevisionKey + revisionHashKeySuffix
+)
+
+// Revision is a rendered manifest of a release that has been successfully applied
+type Revision struct {
+	Number   int
+	Hash     string
+	Manifest string
+}
+
+// Objects parses the manifest of the revision back to objects
+func (r Revision) Objects(parser *resources.ObjectParser) ([]runtime.Object, error) {
+	objects, err := parser.ParseYAMLManifest(r.Manifest)
+	if err != nil {
+		return nil, errors.WrapIfWithDetails(err, "could not parse revision manifest", "revision", r.Number)
+	}
+	return objects, nil
+}
+
+// revisionHistory keeps the last N rendered manifests of a release in a Secret next to the release
+type revisionHistory struct {
+	client client.Client
+	limit  int
+}
+
+func revisionHistoryName(parent reconciler.ResourceOwner, releaseData *ReleaseData) string {
+	return fmt.Sprintf("%s-%s-%s-revisions", parent.GetName(), releaseData.Namespace, releaseData.ReleaseName)
+}
+
+// RenderManifest serializes objects into a multi-document YAML manifest in install order
+func RenderManifest(objects []runtime.Object) (string, error) {
+	sorted := make([]runtime.Object, len(objects))
+	copy(sorted, objects)
+	utils.RuntimeObjects(sorted).Sort(utils.InstallResourceOrder)
+
+	docs := make([]string, 0, len(sorted))
+	for _, o := range sorted {
+		y, err := yaml.Marshal(o)
+		if err != nil {
+			return "", errors.WrapIf(err, "could not marshal object to yaml")
+		}
+		docs = append(docs, strings.TrimSpace(string(y)))
+	}
+
+	return strings.Join(docs, "\n"+resources.YAMLSeparator+"\n"), nil
+}
+
+func manifestHash(manifest string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(manifest)))
+}
+
+func (h *revisionHistory) get(key client.ObjectKey) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	err := h.client.Get(context.TODO(), key, secret)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WrapIfWithDetails(err, "could not get revision history", "namespace", key.Namespace, "name", key.Name)
+	}
+	return secret, nil
+}
+
+// List returns the stored revisions in ascending order
+func (h *revisionHistory) List(key client.ObjectKey) ([]Revision, error) {
+	secret, err := h.get(key)
+	if err != nil || secret == nil {
+		return nil, err
+	}
+
+	var revisions []Revision
+	for k, v := range secret.Data {
+		if !strings.HasSuffix(k, revisionManifestKeySuffix) {
+			continue
+		}
+		number, err := strconv.Atoi(strings.TrimSuffix(k, revisionManifestKeySuffix))
+		if err != nil {
+			continue
+		}
+		manifest, err := decompress(v)
+		if err != nil {
+			return nil, errors.WrapIfWithDetails(err, "could not decompress revision manifest", "revision", number)
+		}
+		revisions = append(revisions, Revision{
+			Number:   number,
+			Hash:     string(secret.Data[strconv.Itoa(number)+revisionHashKeySuffix]),
+			Manifest: manifest,
+		})
+	}
+
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Number < revisions[j].Number
+	})
+
+	return revisions, nil
+}
+
+// Latest returns the last stored revision or nil if there is none
+func (h *revisionHistory) Latest(key client.ObjectKey) (*Revision, error) {
+	revisions, err := h.List(key)
+	if err != nil || len(revisions) == 0 {
+		return nil, err
+	}
+	return &revisions[len(revisions)-1], nil
+}
+
+// Record stores the manifest as a new revision unless it is identical to the latest one.
+// Revisions exceeding the history limit or the size limit of the Secret are dropped, oldest first.
+func (h *revisionHistory) Record(key client.ObjectKey, manifest string) (*Revision, error) {
+	secret, err := h.get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	create := secret == nil
+	if create {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      key.Name,
+				Namespace: key.Namespace,
+				Labels: map[string]string{
+					types.ManagedByLabel: "operator-tools",
+				},
+			},
+			Type: corev1.SecretTypeOpaque,
+		}
+	}
+	if secret.Data == nil {
+		secret.Data = make(map[string][]byte)
+	}
+
+	hash := manifestHash(manifest)
+	numbers := revisionNumbers(secret)
+	latest := 0
+	if len(numbers) > 0 {
+		latest = numbers[len(numbers)-1]
+		if string(secret.Data[strconv.Itoa(latest)+revisionHashKeySuffix]) == hash {
+			return &Revision{Number: latest, Hash: hash, Manifest: manifest}, nil
+		}
+	}
+
+	compressed, err := compress(manifest)
+	if err != nil {
+		return nil, errors.WrapIf(err, "could not compress revision manifest")
+	}
+
+	revision := &Revision{Number: latest + 1, Hash: hash, Manifest: manifest}
+	delete(secret.Data, failedRevisionHashKey)
+	secret.Data[strconv.Itoa(revision.Number)+revisionManifestKeySuffix] = compressed
+	secret.Data[strconv.Itoa(revision.Number)+revisionHashKeySuffix] = []byte(hash)
+
+	numbers = append(numbers, revision.Number)
+	for len(numbers) > h.limit || (len(numbers) > 1 && secretDataSize(secret) > corev1.MaxSecretSize) {
+		delete(secret.Data, strconv.Itoa(numbers[0])+revisionManifestKeySuffix)
+		delete(secret.Data, strconv.Itoa(numbers[0])+revisionHashKeySuffix)
+		numbers = numbers[1:]
+	}
+	if size := secretDataSize(secret); size > corev1.MaxSecretSize {
+		return nil, errors.NewWithDetails("compressed revision manifest exceeds the size limit of the revision history",
+			"revision", revision.Number, "size", size, "limit", corev1.MaxSecretSize)
+	}
+
+	if err := h.save(secret, create); err != nil {
+		return nil, err
+	}
+
+	return revision, nil
+}
+
+// MarkFailed remembers the hash of a manifest that failed to become ready so that it won't be applied again
+func (h *revisionHistory) MarkFailed(key client.ObjectKey, manifest string) error {
+	secret, err := h.get(key)
+	if err != nil || secret == nil {
+		return err
+	}
+	if secret.Data == nil {
+		secret.Data = make(map[string][]byte)
+	}
+	secret.Data[failedRevisionHashKey] = []byte(manifestHash(manifest))
+
+	return h.save(secret, false)
+}
+
+// IsFailed returns true if the manifest has been marked as failed before
+func (h *revisionHistory) IsFailed(key client.ObjectKey, manifest string) (bool, error) {
+	secret, err := h.get(key)
+	if err != nil || secret == nil {
+		return false, err
+	}
+	return string(secret.Data[failedRevisionHashKey]) == manifestHash(manifest), nil
+}
+
+// Pin keeps the release at the revision until the desired manifest changes or the pin is removed
+func (h *revisionHistory) Pin(key client.ObjectKey, revision int, desired string) error {
+	secret, err := h.get(key)
+	if err != nil {
+		return err
+	}
+	if secret == nil {
+		return errors.NewWithDetails("revision history not found", "namespace", key.Namespace, "name", key.Name)
+	}
+	if secret.Data == nil {
+		secret.Data = make(map[string][]byte)
+	}
+	secret.Data[pinnedRevisionKey] = []byte(strconv.Itoa(revision))
+	secret.Data[pinnedRevisionHashKey] = []byte(manifestHash(desired))
+
+	return h.save(secret, false)
+}
+
+// Pinned returns the revision the release is pinned to, or nil if it isn't pinned.
+// The pin is removed if the desired manifest has changed since the release was pinned.
+func (h *revisionHistory) Pinned(key client.ObjectKey, desired string) (*Revision, error) {
+	secret, err := h.get(key)
+	if err != nil || secret == nil {
+		return nil, err
+	}
+	pinned, ok := secret.Data[pinnedRevisionKey]
+	if !ok {
+		return nil, nil
+	}
+	number, err := strconv.Atoi(string(pinned))
+	compressed, found := secret.Data[string(pinned)+revisionManifestKeySuffix]
+	if err != nil || !found || string(secret.Data[pinnedRevisionHashKey]) != manifestHash(desired) {
+		return nil, h.unpin(secret)
+	}
+	manifest, err := decompress(compressed)
+	if err != nil {
+		return nil, errors.WrapIfWithDetails(err, "could not decompress revision manifest", "revision", number)
+	}
+	return &Revision{
+		Number:   number,
+		Hash:     string(secret.Data[string(pinned)+revisionHashKeySuffix]),
+		Manifest: manifest,
+	}, nil
+}
+
+// Unpin lets the release follow its desired state again
+func (h *revisionHistory) Unpin(key client.ObjectKey) error {
+	secret, err := h.get(key)
+	if err != nil || secret == nil {
+		return err
+	}
+	return h.unpin(secret)
+}
+
+func (h *revisionHistory) unpin(secret *corev1.Secret) error {
+	if _, ok := secret.Data[pinnedRevisionKey]; !ok {
+		return nil
+	}
+	delete(secret.Data, pinnedRevisionKey)
+	delete(secret.Data, pinnedRevisionHashKey)
+
+	return h.save(secret, false)
+}
+
+func (h *revisionHistory) save(secret *corev1.Secret, create bool) error {
+	var err error
+	if create {
+		err = h.client.Create(context.TODO(), secret)
+	} else {
+		err = h.client.Update(context.TODO(), secret)
+	}
+	if err != nil {
+		return errors.WrapIfWithDetails(err, "could not store revision history", "namespace", secret.Namespace, "name", secret.Name)
+	}
+	return nil
+}
+
+// Purge removes the whole revision history
+func (h *revisionHistory) Purge(key client.ObjectKey) error {
+	secret, err := h.get(key)
+	if err != nil || secret == nil {
+		return err
+	}
+	return client.IgnoreNotFound(h.client.Delete(context.TODO(), secret))
+}
+
+func revisionNumbers(secret *corev1.Secret) []int {
+	var numbers []int
+	for k := range secret.Data {
+		if !strings.HasSuffix(k, revisionManifestKeySuffix) {
+			continue
+		}
+		if number, err := strconv.Atoi(strings.TrimSuffix(k, revisionManifestKeySuffix)); err == nil {
+			numbers = append(numbers, number)
+		}
+	}
+	sort.Ints(numbers)
+	return numbers
+}
+
+// secretDataSize is the size of the Secret data as counted against corev1.MaxSecretSize by the API server
+func secretDataSize(secret *corev1.Secret) int {
+	size := 0
+	for _, v := range secret.Data {
+		size += len(v)
+	}
+	return size
+}
+
+func compress(in string) ([]byte, error) {
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	if _, err := w.Write([]byte(in)); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func decompress(in []byte) (string, error) {
+	r, err := gzip.NewReader(bytes.NewReader(in))
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	out, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
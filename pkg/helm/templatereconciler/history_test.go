@@ -0,0 +1,213 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package templatereconciler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/cisco-open/operator-tools/pkg/resources"
+	"github.com/cisco-open/operator-tools/pkg/utils"
+)
+
+func testDeployment(image string, replicas int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Deployment",
+			APIVersion: "apps/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test-ns",
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: utils.IntPointer(replicas),
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  "test",
+							Image: image,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func testConfigMap(name string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "test-ns",
+		},
+	}
+}
+
+func TestDiff(t *testing.T) {
+	previous := []runtime.Object{
+		testDeployment("test:1.0", 1),
+		testConfigMap("removed"),
+	}
+	current := []runtime.Object{
+		testDeployment("test:2.0", 1),
+		testConfigMap("added"),
+	}
+
+	diff, err := Diff(previous, current)
+	require.NoError(t, err)
+	require.Len(t, diff.Objects, 3)
+
+	assert.Equal(t, "added ConfigMap:test-ns/added", diff.Objects[0].String())
+	assert.Equal(t, "changed Deployment.apps:test-ns/test", diff.Objects[1].String())
+	assert.Equal(t, "removed ConfigMap:test-ns/removed", diff.Objects[2].String())
+
+	assert.Equal(t, []FieldChange{
+		{
+			Path:     "spec.template.spec.containers[0].image",
+			Previous: "test:1.0",
+			Current:  "test:2.0",
+		},
+	}, diff.Objects[1].Fields)
+
+	diff, err = Diff(current, current)
+	require.NoError(t, err)
+	assert.True(t, diff.IsEmpty())
+}
+
+func TestRevisionHistory(t *testing.T) {
+	history := &revisionHistory{
+		client: fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).Build(),
+		limit:  2,
+	}
+	key := client.ObjectKey{Namespace: "test-ns", Name: "test-revisions"}
+
+	var manifests []string
+	for _, image := range []string{"test:1.0", "test:2.0", "test:3.0"} {
+		manifest, err := RenderManifest([]runtime.Object{testDeployment(image, 1), testConfigMap("test")})
+		require.NoError(t, err)
+		manifests = append(manifests, manifest)
+
+		_, err = history.Record(key, manifest)
+		require.NoError(t, err)
+	}
+
+	// recording the same manifest again must not create a new revision
+	revision, err := history.Record(key, manifests[2])
+	require.NoError(t, err)
+	assert.Equal(t, 3, revision.Number)
+
+	revisions, err := history.List(key)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, 2, revisions[0].Number)
+	assert.Equal(t, manifests[1], revisions[0].Manifest)
+	assert.Equal(t, 3, revisions[1].Number)
+
+	objects, err := revisions[0].Objects(resources.NewObjectParser(clientgoscheme.Scheme))
+	require.NoError(t, err)
+	require.Len(t, objects, 2)
+	deployment, ok := objects[1].(*appsv1.Deployment)
+	require.True(t, ok, "object should be a typed deployment")
+	assert.Equal(t, "test:2.0", deployment.Spec.Template.Spec.Containers[0].Image)
+
+	require.NoError(t, history.MarkFailed(key, manifests[0]))
+	failed, err := history.IsFailed(key, manifests[0])
+	require.NoError(t, err)
+	assert.True(t, failed)
+
+	require.NoError(t, history.Purge(key))
+	revisions, err = history.List(key)
+	require.NoError(t, err)
+	assert.Empty(t, revisions)
+}
+
+func TestRevisionHistorySizeLimit(t *testing.T) {
+	history := &revisionHistory{
+		client: fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).Build(),
+		limit:  10,
+	}
+	key := client.ObjectKey{Namespace: "test-ns", Name: "test-revisions"}
+
+	// hex encoded random data compresses to about half of its size, so only two of these fit into a Secret
+	incompressible := func(size int) string {
+		data := make([]byte, size/2)
+		_, err := rand.Read(data)
+		require.NoError(t, err)
+		return hex.EncodeToString(data)
+	}
+	for i := 0; i < 3; i++ {
+		_, err := history.Record(key, incompressible(800*1024))
+		require.NoError(t, err)
+	}
+
+	revisions, err := history.List(key)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, 2, revisions[0].Number)
+	assert.Equal(t, 3, revisions[1].Number)
+
+	_, err = history.Record(key, incompressible(3*1024*1024))
+	require.ErrorContains(t, err, "exceeds the size limit")
+}
+
+func TestRevisionHistoryPin(t *testing.T) {
+	history := &revisionHistory{
+		client: fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).Build(),
+		limit:  2,
+	}
+	key := client.ObjectKey{Namespace: "test-ns", Name: "test-revisions"}
+
+	stable, err := history.Record(key, "stable")
+	require.NoError(t, err)
+	_, err = history.Record(key, "broken")
+	require.NoError(t, err)
+
+	require.NoError(t, history.Pin(key, stable.Number, "broken"))
+	pinned, err := history.Pinned(key, "broken")
+	require.NoError(t, err)
+	require.NotNil(t, pinned)
+	assert.Equal(t, *stable, *pinned)
+
+	require.NoError(t, history.Unpin(key))
+	pinned, err = history.Pinned(key, "broken")
+	require.NoError(t, err)
+	assert.Nil(t, pinned)
+
+	// a changed desired state releases the pin for good
+	require.NoError(t, history.Pin(key, stable.Number, "broken"))
+	pinned, err = history.Pinned(key, "fixed")
+	require.NoError(t, err)
+	assert.Nil(t, pinned)
+	pinned, err = history.Pinned(key, "broken")
+	require.NoError(t, err)
+	assert.Nil(t, pinned)
+}
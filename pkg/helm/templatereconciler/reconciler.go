@@ -24,6 +24,7 @@ import (
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chartutil"
 	v1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -32,11 +33,13 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"github.com/cisco-open/operator-tools/pkg/crd"
 	"github.com/cisco-open/operator-tools/pkg/inventory"
 	"github.com/cisco-open/operator-tools/pkg/logger"
 	"github.com/cisco-open/operator-tools/pkg/reconciler"
 	"github.com/cisco-open/operator-tools/pkg/resources"
 	"github.com/cisco-open/operator-tools/pkg/types"
+	"github.com/cisco-open/operator-tools/pkg/wait"
 )
 
 type ReleaseData struct {
@@ -45,6 +48,8 @@ type ReleaseData struct {
 	Namespace   string
 	ChartName   string
 	ReleaseName string
+	// PostRenderer can be embedded into CRDs directly to transform the full set of rendered objects
+	PostRenderer *resources.PostRenderer
 	// Layers can be embedded into CRDs directly to provide flexible override mechanisms
 	Layers []resources.K8SResourceOverlay
 	// Modifiers can be used from client code to modify resources before being applied
@@ -62,6 +67,12 @@ type Component interface {
 	UpdateStatus(object runtime.Object, status types.ReconcileStatus, message string) error
 }
 
+// ComponentWithOverlayReport is a component that surfaces the result of applying its release layers, e.g. on the owner status
+type ComponentWithOverlayReport interface {
+	Component
+	UpdateOverlayReport(object runtime.Object, report *resources.OverlayReport) error
+}
+
 type HelmReconciler struct {
 	client                client.Client
 	scheme                *runtime.Scheme
@@ -72,6 +83,13 @@ type HelmReconciler struct {
 	objectParser          *resources.ObjectParser
 	discovery             discovery.DiscoveryInterface
 	manageNamespace       bool
+	revisionHistoryLimit  int
+	rollbackBackoff       *wait.Backoff
+	crdInstaller          *crd.Installer
+	helmReleaseLimit      int
+	adoptHelmReleases     bool
+	strictOverlays        bool
+	parsingMode           resources.ParsingMode
 }
 
 type preConditionsFatalErr struct {
@@ -108,6 +126,63 @@ func ManageNamespace(manageNamespace bool) HelmReconcilerOpt {
 	}
 }
 
+// WithCRDInstaller hands over the CRDs of the charts to the given installer, which applies them
+// before any other object of the release. CRDs are left out of the resource builders in this case.
+func WithCRDInstaller(installer *crd.Installer) HelmReconcilerOpt {
+	return func(r *HelmReconciler) {
+		r.crdInstaller = installer
+	}
+}
+
+// WithRevisionHistory keeps the last `limit` successfully applied manifests of each release
+func WithRevisionHistory(limit int) HelmReconcilerOpt {
+	return func(r *HelmReconciler) {
+		r.revisionHistoryLimit = limit
+	}
+}
+
+// WithRollbackOnFailedReadiness waits for the applied objects to become ready after an upgrade
+// and rolls back to the previous revision if they don't. Implies revision history.
+func WithRollbackOnFailedReadiness(backoff wait.Backoff) HelmReconcilerOpt {
+	return func(r *HelmReconciler) {
+		r.rollbackBackoff = &backoff
+		if r.revisionHistoryLimit == 0 {
+			r.revisionHistoryLimit = DefaultRevisionHistoryLimit
+		}
+	}
+}
+
+// WithStrictOverlays fails reconciliation if any of the release layers matches no objects
+func WithStrictOverlays() HelmReconcilerOpt {
+	return func(r *HelmReconciler) {
+		r.strictOverlays = true
+	}
+}
+
+// WithParsingMode controls whether rendered chart objects are typed or unstructured before modifiers and layers are applied
+func WithParsingMode(mode resources.ParsingMode) HelmReconcilerOpt {
+	return func(r *HelmReconciler) {
+		r.parsingMode = mode
+	}
+}
+
+// WithHelmReleaseRecords writes a Helm v3 compatible release Secret after each successful reconcile,
+// keeping the last `limit` versions, so that the helm CLI can inspect the releases of the operator
+func WithHelmReleaseRecords(limit int) HelmReconcilerOpt {
+	return func(r *HelmReconciler) {
+		r.helmReleaseLimit = limit
+	}
+}
+
+// WithHelmReleaseAdoption takes over releases previously installed with the helm CLI: on the first
+// reconcile the objects of the deployed Helm release are added to the inventory so that objects
+// removed from the chart since then get cleaned up.
+func WithHelmReleaseAdoption() HelmReconcilerOpt {
+	return func(r *HelmReconciler) {
+		r.adoptHelmReleases = true
+	}
+}
+
 func NewHelmReconciler(
 	client client.Client,
 	scheme *runtime.Scheme,
@@ -131,7 +206,6 @@ func NewHelmReconcilerWith(
 		logger:                logger,
 		inventory:             inventory.NewDiscoveryInventory(client, logger, discovery),
 		discovery:             discovery,
-		objectParser:          resources.NewObjectParser(scheme),
 		nativeReconcilerOpts:  make([]reconciler.NativeReconcilerOpt, 0),
 		genericReconcilerOpts: make([]reconciler.ResourceReconcilerOption, 0),
 		manageNamespace:       true,
@@ -141,6 +215,8 @@ func NewHelmReconcilerWith(
 		opt(r)
 	}
 
+	r.objectParser = resources.NewObjectParser(scheme, resources.WithParsingMode(r.parsingMode))
+
 	if len(r.genericReconcilerOpts) == 0 {
 		r.genericReconcilerOpts = append(r.genericReconcilerOpts, reconciler.WithEnableRecreateWorkload())
 	}
@@ -227,6 +303,94 @@ func (rec *HelmReconciler) Reconcile(object runtime.Object, component Component)
 }
 
 func (rec *HelmReconciler) GetResourceBuilders(parent reconciler.ResourceOwner, component Component, releaseData *ReleaseData, doInventory bool) ([]reconciler.ResourceBuilder, error) {
+	release, err := rec.getReleaseResources(parent, component, releaseData, doInventory)
+	if err != nil {
+		return nil, err
+	}
+	if err := release.overlayReport.Err(rec.strictOverlays); err != nil {
+		return nil, errors.WrapIf(err, "failed to apply layers")
+	}
+	return release.resourceBuilders, nil
+}
+
+type releaseResources struct {
+	// all the resource builders of the release
+	resourceBuilders []reconciler.ResourceBuilder
+	// resource builders of the chart objects only
+	chartResourceBuilders []reconciler.ResourceBuilder
+	// CRDs to be handled by the CRD installer
+	crds []*apiextensionsv1.CustomResourceDefinition
+	// result of applying the layers of the release
+	overlayReport *resources.OverlayReport
+}
+
+func (rec *HelmReconciler) getReleaseResources(parent reconciler.ResourceOwner, component Component, releaseData *ReleaseData, doInventory bool) (*releaseResources, error) {
+	enabled := component.Enabled(parent)
+
+	// CRDs have to be rendered even for a disabled component if the installer is about to remove them
+	if !enabled && (rec.crdInstaller == nil || !rec.crdInstaller.RemovesOnUninstall()) {
+		return rec.resourceBuildersFromObjects(parent, false, releaseData, nil, nil, doInventory)
+	}
+
+	serverVersion, err := rec.discovery.ServerVersion()
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to detect server version")
+	}
+
+	apiVersions, err := action.GetVersionSet(rec.discovery)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to detect supported API versions")
+	}
+
+	capabilities := chartutil.Capabilities{
+		KubeVersion: chartutil.KubeVersion{
+			Version: serverVersion.GitVersion,
+			Major:   serverVersion.Major,
+			Minor:   serverVersion.Minor,
+		},
+		APIVersions: apiVersions,
+	}
+
+	objects, state, err := orderedChartObjectsWithState(releaseData, rec.scheme, capabilities, rec.parsingMode)
+	if err != nil {
+		return nil, err
+	}
+
+	if objects, err = releaseData.PostRenderer.Apply(objects); err != nil {
+		return nil, errors.WrapIf(err, "failed to post-render chart objects")
+	}
+
+	layerModifier, overlayReport, err := resources.OverlayModifier(releaseData.Layers, rec.objectParser)
+	if err != nil {
+		return nil, errors.WrapIf(err, "failed to create modifier from layers")
+	}
+	modifiers := append(append([]resources.ObjectModifierFunc{}, releaseData.Modifiers...), layerModifier)
+	// modifiers and layers may target CRDs as well, so they are applied before the CRDs are separated
+	if objects, err = applyModifiers(objects, modifiers); err != nil {
+		return nil, err
+	}
+
+	var crds []*apiextensionsv1.CustomResourceDefinition
+	if rec.crdInstaller != nil {
+		if crds, objects, err = crd.SplitObjects(objects); err != nil {
+			return nil, errors.WrapIf(err, "failed to separate crds from the chart objects")
+		}
+	}
+
+	release, err := rec.resourceBuildersFromObjects(parent, enabled, releaseData, objects, state, doInventory)
+	if err != nil {
+		return nil, err
+	}
+	release.crds = crds
+	// a disabled component is only rendered to find the CRDs to remove, its layers are neither reported nor enforced
+	if enabled {
+		release.overlayReport = overlayReport
+	}
+
+	return release, nil
+}
+
+func (rec *HelmReconciler) resourceBuildersFromObjects(parent reconciler.ResourceOwner, enabled bool, releaseData *ReleaseData, objects []runtime.Object, state reconciler.DesiredState, doInventory bool) (*releaseResources, error) {
 	var err error
 	resourceBuilders := make([]reconciler.ResourceBuilder, 0)
 
@@ -247,68 +411,44 @@ func (rec *HelmReconciler) GetResourceBuilders(parent reconciler.ResourceOwner,
 		}
 	}
 
-	if component.Enabled(parent) {
-		serverVersion, err := rec.discovery.ServerVersion()
-		if err != nil {
-			return nil, errors.Wrapf(err, "unable to detect server version")
-		}
-
-		apiVersions, err := action.GetVersionSet(rec.discovery)
-		if err != nil {
-			return nil, errors.Wrapf(err, "unable to detect supported API versions")
-		}
-
-		capabilities := chartutil.Capabilities{
-			KubeVersion: chartutil.KubeVersion{
-				Version: serverVersion.GitVersion,
-				Major:   serverVersion.Major,
-				Minor:   serverVersion.Minor,
-			},
-			APIVersions: apiVersions,
-		}
-
-		objects, state, err := orderedChartObjectsWithState(releaseData, rec.scheme, capabilities)
-		if err != nil {
-			return nil, err
-		}
-
-		modifiers := releaseData.Modifiers
-
-		for _, layer := range releaseData.Layers {
-			modifier, err := resources.PatchYAMLModifier(layer, rec.objectParser)
-			if err != nil {
-				return nil, errors.WrapIf(err, "failed to create modifier from layer")
-			}
-			modifiers = append(modifiers, modifier)
-		}
-
-		chartResourceBuilders, err := reconciler.GetResourceBuildersFromObjects(objects, state, modifiers...)
+	var chartResourceBuilders []reconciler.ResourceBuilder
+	if enabled {
+		chartResourceBuilders, err = reconciler.GetResourceBuildersFromObjects(objects, state)
 		if err != nil {
 			return nil, err
 		}
 
 		resourceBuilders = append(resourceBuilders, chartResourceBuilders...)
-		if doInventory {
-			if resourceBuilders, err = rec.inventory.Append(releaseData.Namespace, releaseData.ReleaseName, parent, resourceBuilders); err != nil {
-				return nil, err
-			}
-		}
-	} else if doInventory {
+	}
+
+	if doInventory {
 		if resourceBuilders, err = rec.inventory.Append(releaseData.Namespace, releaseData.ReleaseName, parent, resourceBuilders); err != nil {
 			return nil, err
 		}
 	}
 
-	return rec.setDesiredStateOverrides(resourceBuilders, releaseData), nil
+	return &releaseResources{
+		resourceBuilders:      rec.setDesiredStateOverrides(resourceBuilders, releaseData),
+		chartResourceBuilders: chartResourceBuilders,
+	}, nil
 }
 
-func (rec *HelmReconciler) reconcile(parent reconciler.ResourceOwner, component Component, releaseData *ReleaseData) (*reconcile.Result, error) {
-	resourceBuilders, err := rec.GetResourceBuilders(parent, component, releaseData, true)
-	if err != nil {
-		return nil, err
+func applyModifiers(objects []runtime.Object, modifiers []resources.ObjectModifierFunc) ([]runtime.Object, error) {
+	modified := make([]runtime.Object, 0, len(objects))
+	for _, o := range objects {
+		for _, modifier := range modifiers {
+			var err error
+			if o, err = modifier(o); err != nil {
+				return nil, err
+			}
+		}
+		modified = append(modified, o)
 	}
+	return modified, nil
+}
 
-	r := reconciler.NewNativeReconciler(
+func (rec *HelmReconciler) nativeReconciler(component Component, resourceBuilders []reconciler.ResourceBuilder) *reconciler.NativeReconciler {
+	return reconciler.NewNativeReconciler(
 		component.Name(),
 		reconciler.NewReconcilerWith(
 			rec.client,
@@ -327,12 +467,83 @@ func (rec *HelmReconciler) reconcile(parent reconciler.ResourceOwner, component
 		},
 		append(rec.nativeReconcilerOpts, reconciler.NativeReconcilerWithScheme(rec.scheme))...,
 	)
+}
+
+func (rec *HelmReconciler) reconcile(parent reconciler.ResourceOwner, component Component, releaseData *ReleaseData) (*reconcile.Result, error) {
+	if rec.adoptHelmReleases && component.Enabled(parent) {
+		if err := rec.adoptHelmRelease(parent, releaseData); err != nil {
+			return nil, err
+		}
+	}
+
+	release, err := rec.getReleaseResources(parent, component, releaseData, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if c, ok := component.(ComponentWithOverlayReport); ok && release.overlayReport != nil {
+		if err := c.UpdateOverlayReport(parent, release.overlayReport); err != nil {
+			rec.logger.Error(err, "overlay report update failed")
+		}
+	}
+	if err := release.overlayReport.Err(rec.strictOverlays); err != nil {
+		return nil, errors.WrapIf(err, "failed to apply layers")
+	}
+
+	if component.Enabled(parent) && len(release.crds) > 0 {
+		if err := rec.crdInstaller.Install(release.crds); err != nil {
+			return nil, errors.WrapIf(err, "failed to install crds")
+		}
+	}
+
+	var manifest string
+	if (rec.revisionHistoryLimit > 0 || rec.helmReleaseLimit > 0) && component.Enabled(parent) {
+		// the manifest has to be captured before reconciliation adds its own annotations to the objects
+		if manifest, err = manifestFromResourceBuilders(release.chartResourceBuilders); err != nil {
+			return nil, err
+		}
+	}
+	if rec.revisionHistoryLimit > 0 && component.Enabled(parent) {
+		pinned, err := rec.pinnedRevision(parent, releaseData, manifest)
+		if err != nil {
+			return nil, err
+		}
+		if pinned != nil {
+			rec.logger.Info("release is pinned to a rolled back revision until its desired state changes", "revision", pinned.Number)
+			return rec.applyRevision(parent, component, releaseData, *pinned)
+		}
+		if err := rec.checkPreviouslyFailed(parent, releaseData, manifest); err != nil {
+			return nil, err
+		}
+	}
+
+	r := rec.nativeReconciler(component, release.resourceBuilders)
 
 	result, err := r.Reconcile(parent)
 	if err != nil {
 		return result, err
 	}
 
+	if rec.revisionHistoryLimit > 0 {
+		if component.Enabled(parent) {
+			if err := rec.recordRevision(parent, component, releaseData, r, manifest); err != nil {
+				return result, err
+			}
+		} else if err := rec.revisionHistory().Purge(rec.revisionHistoryKey(parent, releaseData)); err != nil {
+			return result, errors.WrapIf(err, "failed to remove revision history of the release")
+		}
+	}
+
+	if rec.helmReleaseLimit > 0 {
+		if component.Enabled(parent) {
+			if err := rec.helmReleaseStorage().Record(releaseData, manifest); err != nil {
+				return result, errors.WrapIf(err, "failed to record helm release")
+			}
+		} else if err := rec.helmReleaseStorage().Remove(releaseData.Namespace, releaseData.ReleaseName); err != nil {
+			return result, errors.WrapIf(err, "failed to remove helm release records")
+		}
+	}
+
 	if !component.Enabled(parent) {
 		// cleanup orphaned pods left from removed jobs
 		if err := rec.client.DeleteAllOf(context.TODO(), &v1.Pod{},
@@ -342,6 +553,12 @@ func (rec *HelmReconciler) reconcile(parent reconciler.ResourceOwner, component
 		); err != nil {
 			return result, errors.WrapIf(err, "failed to remove pods left from the release")
 		}
+
+		if len(release.crds) > 0 {
+			if err := rec.crdInstaller.Uninstall(release.crds); err != nil {
+				return result, errors.WrapIf(err, "failed to remove crds")
+			}
+		}
 	}
 
 	rec.logger.Info("reconciled")
@@ -0,0 +1,98 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package templatereconciler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clienttesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/cisco-open/operator-tools/pkg/crd"
+	"github.com/cisco-open/operator-tools/pkg/resources"
+	"github.com/cisco-open/operator-tools/pkg/types"
+)
+
+type testComponent struct {
+	enabled     bool
+	releaseData *ReleaseData
+}
+
+func (c *testComponent) Name() string                   { return "test" }
+func (c *testComponent) Skipped(runtime.Object) bool    { return false }
+func (c *testComponent) Enabled(runtime.Object) bool    { return c.enabled }
+func (c *testComponent) PreChecks(runtime.Object) error { return nil }
+func (c *testComponent) ReleaseData(runtime.Object) (*ReleaseData, error) {
+	return c.releaseData, nil
+}
+func (c *testComponent) UpdateStatus(runtime.Object, types.ReconcileStatus, string) error {
+	return nil
+}
+
+func TestDisabledComponentIgnoresStrictOverlays(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, apiextensionsv1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	discovery := &fakediscovery.FakeDiscovery{
+		Fake:               &clienttesting.Fake{},
+		FakedServerVersion: &version.Info{GitVersion: "v1.30.0", Major: "1", Minor: "30"},
+	}
+
+	rec := NewHelmReconcilerWith(c, scheme, logr.Discard(), discovery,
+		ManageNamespace(false),
+		WithStrictOverlays(),
+		WithCRDInstaller(crd.NewInstaller(c, logr.Discard(), crd.WithRemoveOnUninstall())),
+	)
+	component := &testComponent{
+		releaseData: &ReleaseData{
+			Chart:       http.Dir("../testdata/crds-and-templates/logging-operator"),
+			Values:      map[string]interface{}{"createCustomResource": false},
+			Namespace:   "test-ns",
+			ChartName:   "logging-operator",
+			ReleaseName: "test",
+			Layers: []resources.K8SResourceOverlay{
+				{
+					GVK:       &resources.GroupVersionKind{Version: "v1", Kind: "ConfigMap"},
+					ObjectKey: types.ObjectKey{Name: "missing"},
+				},
+			},
+		},
+	}
+	parent := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "owner", Namespace: "test-ns"}}
+
+	component.enabled = true
+	_, err := rec.GetResourceBuilders(parent, component, component.releaseData, false)
+	require.Error(t, err, "unmatched layer should fail an enabled component in strict mode")
+
+	component.enabled = false
+	_, err = rec.GetResourceBuilders(parent, component, component.releaseData, false)
+	require.NoError(t, err)
+	release, err := rec.getReleaseResources(parent, component, component.releaseData, false)
+	require.NoError(t, err)
+	assert.Nil(t, release.overlayReport)
+	assert.Len(t, release.crds, 1, "crds of the disabled component should be rendered for removal")
+}
@@ -0,0 +1,214 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package templatereconciler
+
+import (
+	"emperror.dev/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/cisco-open/operator-tools/pkg/reconciler"
+	"github.com/cisco-open/operator-tools/pkg/wait"
+)
+
+func (rec *HelmReconciler) revisionHistory() *revisionHistory {
+	limit := rec.revisionHistoryLimit
+	if limit <= 0 {
+		limit = DefaultRevisionHistoryLimit
+	}
+	return &revisionHistory{
+		client: rec.client,
+		limit:  limit,
+	}
+}
+
+func (rec *HelmReconciler) revisionHistoryKey(parent reconciler.ResourceOwner, releaseData *ReleaseData) client.ObjectKey {
+	return client.ObjectKey{
+		Namespace: releaseData.Namespace,
+		Name:      revisionHistoryName(parent, releaseData),
+	}
+}
+
+// History returns the recorded revisions of the release in ascending order
+func (rec *HelmReconciler) History(object runtime.Object, component Component) ([]Revision, error) {
+	parent, ok := object.(reconciler.ResourceOwner)
+	if !ok {
+		return nil, errors.New("cannot convert object to ResourceOwner interface")
+	}
+
+	releaseData, err := component.ReleaseData(object)
+	if err != nil {
+		return nil, errors.WrapIf(err, "failed to get release data")
+	}
+
+	return rec.revisionHistory().List(rec.revisionHistoryKey(parent, releaseData))
+}
+
+// Rollback applies a previously recorded revision of the release and records it as the latest revision.
+// The release is pinned to the revision, so that Reconcile keeps it until the desired state changes or Unpin is called.
+func (rec *HelmReconciler) Rollback(object runtime.Object, component Component, revision int) (*reconcile.Result, error) {
+	parent, ok := object.(reconciler.ResourceOwner)
+	if !ok {
+		return nil, errors.New("cannot convert object to ResourceOwner interface")
+	}
+
+	releaseData, err := component.ReleaseData(object)
+	if err != nil {
+		return nil, errors.WrapIf(err, "failed to get release data")
+	}
+
+	key := rec.revisionHistoryKey(parent, releaseData)
+	revisions, err := rec.revisionHistory().List(key)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range revisions {
+		if r.Number != revision {
+			continue
+		}
+		release, err := rec.getReleaseResources(parent, component, releaseData, false)
+		if err != nil {
+			return nil, err
+		}
+		desired, err := manifestFromResourceBuilders(release.chartResourceBuilders)
+		if err != nil {
+			return nil, err
+		}
+		result, err := rec.rollback(parent, component, releaseData, r)
+		if err != nil {
+			return result, err
+		}
+		return result, rec.revisionHistory().Pin(key, r.Number, desired)
+	}
+
+	return nil, errors.Errorf("revision %d of release %s not found", revision, releaseData.ReleaseName)
+}
+
+// Unpin lets the release follow its desired state again after a Rollback
+func (rec *HelmReconciler) Unpin(object runtime.Object, component Component) error {
+	parent, ok := object.(reconciler.ResourceOwner)
+	if !ok {
+		return errors.New("cannot convert object to ResourceOwner interface")
+	}
+
+	releaseData, err := component.ReleaseData(object)
+	if err != nil {
+		return errors.WrapIf(err, "failed to get release data")
+	}
+
+	return rec.revisionHistory().Unpin(rec.revisionHistoryKey(parent, releaseData))
+}
+
+func (rec *HelmReconciler) rollback(parent reconciler.ResourceOwner, component Component, releaseData *ReleaseData, revision Revision) (*reconcile.Result, error) {
+	rec.logger.Info("rolling back", "revision", revision.Number)
+
+	return rec.applyRevision(parent, component, releaseData, revision)
+}
+
+// applyRevision reconciles the objects of a recorded revision and records it as the latest revision
+func (rec *HelmReconciler) applyRevision(parent reconciler.ResourceOwner, component Component, releaseData *ReleaseData, revision Revision) (*reconcile.Result, error) {
+	objects, err := revision.Objects(rec.objectParser)
+	if err != nil {
+		return nil, err
+	}
+
+	// the stored manifest already has the modifiers and layers applied
+	release, err := rec.resourceBuildersFromObjects(parent, true, releaseData, objects, reconciler.StatePresent, true)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := rec.nativeReconciler(component, release.resourceBuilders).Reconcile(parent)
+	if err != nil {
+		return result, errors.WrapIfWithDetails(err, "failed to roll back", "revision", revision.Number)
+	}
+
+	if _, err := rec.revisionHistory().Record(rec.revisionHistoryKey(parent, releaseData), revision.Manifest); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// pinnedRevision returns the revision the release has been rolled back to, if the desired manifest hasn't changed since
+func (rec *HelmReconciler) pinnedRevision(parent reconciler.ResourceOwner, releaseData *ReleaseData, manifest string) (*Revision, error) {
+	return rec.revisionHistory().Pinned(rec.revisionHistoryKey(parent, releaseData), manifest)
+}
+
+// checkPreviouslyFailed prevents flapping between a broken upgrade and the rollback on every reconcile
+func (rec *HelmReconciler) checkPreviouslyFailed(parent reconciler.ResourceOwner, releaseData *ReleaseData, manifest string) error {
+	if rec.rollbackBackoff == nil {
+		return nil
+	}
+
+	failed, err := rec.revisionHistory().IsFailed(rec.revisionHistoryKey(parent, releaseData), manifest)
+	if err != nil {
+		return err
+	}
+	if failed {
+		return errors.Errorf("the desired state of release %s failed readiness checks before and has been rolled back, waiting for changes", releaseData.ReleaseName)
+	}
+
+	return nil
+}
+
+func (rec *HelmReconciler) recordRevision(parent reconciler.ResourceOwner, component Component, releaseData *ReleaseData, r *reconciler.NativeReconciler, manifest string) error {
+	history := rec.revisionHistory()
+	key := rec.revisionHistoryKey(parent, releaseData)
+
+	if rec.rollbackBackoff != nil {
+		latest, err := history.Latest(key)
+		if err != nil {
+			return err
+		}
+		if latest != nil && latest.Hash != manifestHash(manifest) {
+			rcc := wait.NewResourceConditionChecks(rec.client, *rec.rollbackBackoff, rec.logger, rec.scheme)
+			presentObjects := r.GetReconciledObjectWithState(reconciler.ReconciledObjectStatePresent)
+			if err := rcc.WaitForResources("upgrade readiness", presentObjects, wait.ExistsConditionCheck, wait.ReadyReplicasConditionCheck); err != nil {
+				rec.logger.Error(err, "upgraded release failed readiness checks", "revision", latest.Number)
+				if _, rerr := rec.rollback(parent, component, releaseData, *latest); rerr != nil {
+					return errors.Combine(errors.WrapIf(err, "upgrade failed readiness checks"), rerr)
+				}
+				if merr := history.MarkFailed(key, manifest); merr != nil {
+					return errors.Combine(errors.WrapIf(err, "upgrade failed readiness checks"), merr)
+				}
+				return errors.WrapIff(err, "upgrade failed readiness checks, rolled back to revision %d", latest.Number)
+			}
+		}
+	}
+
+	revision, err := history.Record(key, manifest)
+	if err != nil {
+		return err
+	}
+	rec.logger.V(1).Info("revision recorded", "revision", revision.Number)
+
+	return nil
+}
+
+func manifestFromResourceBuilders(resourceBuilders []reconciler.ResourceBuilder) (string, error) {
+	objects := make([]runtime.Object, 0, len(resourceBuilders))
+	for _, rb := range resourceBuilders {
+		o, _, err := rb()
+		if err != nil {
+			return "", err
+		}
+		objects = append(objects, o.DeepCopyObject())
+	}
+
+	return RenderManifest(objects)
+}
@@ -0,0 +1,190 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package templatereconciler
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clienttesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/cisco-open/operator-tools/pkg/reconciler"
+	"github.com/cisco-open/operator-tools/pkg/resources"
+	"github.com/cisco-open/operator-tools/pkg/wait"
+)
+
+func newTestHelmReconciler(t *testing.T, opts ...HelmReconcilerOpt) (*HelmReconciler, client.Client) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	discovery := &fakediscovery.FakeDiscovery{
+		Fake:               &clienttesting.Fake{},
+		FakedServerVersion: &version.Info{GitVersion: "v1.30.0", Major: "1", Minor: "30"},
+	}
+	return NewHelmReconcilerWith(c, scheme, logr.Discard(), discovery, append([]HelmReconcilerOpt{ManageNamespace(false)}, opts...)...), c
+}
+
+func testParent() *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "owner", Namespace: "test-ns", UID: "owner-uid"},
+	}
+}
+
+// reconcileObjects applies the objects the way reconcile does and returns the manifest recorded for them
+func reconcileObjects(t *testing.T, rec *HelmReconciler, component Component, objects ...runtime.Object) (*reconciler.NativeReconciler, string) {
+	builders, err := reconciler.GetResourceBuildersFromObjects(objects, reconciler.StatePresent)
+	require.NoError(t, err)
+	manifest, err := manifestFromResourceBuilders(builders)
+	require.NoError(t, err)
+	r := rec.nativeReconciler(component, builders)
+	_, err = r.Reconcile(testParent())
+	require.NoError(t, err)
+	return r, manifest
+}
+
+func liveImage(t *testing.T, c client.Client) string {
+	deployment := &appsv1.Deployment{}
+	require.NoError(t, c.Get(context.Background(), client.ObjectKey{Namespace: "test-ns", Name: "test"}, deployment))
+	return deployment.Spec.Template.Spec.Containers[0].Image
+}
+
+func TestRecordRevision(t *testing.T) {
+	rec, _ := newTestHelmReconciler(t, WithRevisionHistory(2))
+	component := &testComponent{enabled: true, releaseData: &ReleaseData{Namespace: "test-ns", ReleaseName: "test"}}
+	parent := testParent()
+
+	for _, image := range []string{"test:1.0", "test:2.0", "test:2.0", "test:3.0"} {
+		r, manifest := reconcileObjects(t, rec, component, testDeployment(image, 1))
+		require.NoError(t, rec.recordRevision(parent, component, component.releaseData, r, manifest))
+	}
+
+	revisions, err := rec.History(parent, component)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, 2, revisions[0].Number)
+	assert.Equal(t, 3, revisions[1].Number)
+	assert.Contains(t, revisions[1].Manifest, "test:3.0")
+}
+
+func TestRecordRevisionRollsBackOnFailedReadiness(t *testing.T) {
+	rec, c := newTestHelmReconciler(t,
+		WithRevisionHistory(3),
+		WithRollbackOnFailedReadiness(wait.Backoff{Duration: time.Millisecond, Steps: 2}),
+	)
+	component := &testComponent{enabled: true, releaseData: &ReleaseData{Namespace: "test-ns", ReleaseName: "test"}}
+	parent := testParent()
+
+	r, stable := reconcileObjects(t, rec, component, testDeployment("test:1.0", 1))
+	require.NoError(t, rec.recordRevision(parent, component, component.releaseData, r, stable))
+	require.NoError(t, rec.checkPreviouslyFailed(parent, component.releaseData, stable))
+
+	r, broken := reconcileObjects(t, rec, component, testDeployment("test:2.0", 1))
+	require.Equal(t, "test:2.0", liveImage(t, c))
+	// the replicas of the upgraded deployment never become ready
+	live := &appsv1.Deployment{}
+	require.NoError(t, c.Get(context.Background(), client.ObjectKey{Namespace: "test-ns", Name: "test"}, live))
+	live.Status = appsv1.DeploymentStatus{Replicas: 1}
+	require.NoError(t, c.Status().Update(context.Background(), live))
+
+	err := rec.recordRevision(parent, component, component.releaseData, r, broken)
+	require.ErrorContains(t, err, "rolled back to revision 1")
+	assert.Equal(t, "test:1.0", liveImage(t, c))
+
+	revisions, err := rec.History(parent, component)
+	require.NoError(t, err)
+	require.Len(t, revisions, 1, "the failed manifest should not be recorded")
+
+	assert.ErrorContains(t, rec.checkPreviouslyFailed(parent, component.releaseData, broken), "failed readiness checks before")
+	assert.NoError(t, rec.checkPreviouslyFailed(parent, component.releaseData, stable))
+}
+
+func TestRollbackPinsRevision(t *testing.T) {
+	rec, c := newTestHelmReconciler(t, WithRevisionHistory(5))
+	version := "1"
+	component := &testComponent{
+		enabled: true,
+		releaseData: &ReleaseData{
+			Chart:       http.Dir("../testdata/templates/logging-operator"),
+			Namespace:   "test-ns",
+			ChartName:   "logging-operator",
+			ReleaseName: "test",
+			Modifiers: []resources.ObjectModifierFunc{func(o runtime.Object) (runtime.Object, error) {
+				if sa, ok := o.(*corev1.ServiceAccount); ok {
+					sa.Labels["version"] = version
+				}
+				return o, nil
+			}},
+		},
+	}
+	parent := testParent()
+	liveVersion := func() string {
+		sa := &corev1.ServiceAccount{}
+		require.NoError(t, c.Get(context.Background(), client.ObjectKey{Namespace: "test-ns", Name: "test-logging-operator"}, sa))
+		return sa.Labels["version"]
+	}
+	reconcile := func() {
+		_, err := rec.reconcile(parent, component, component.releaseData)
+		require.NoError(t, err)
+	}
+
+	reconcile()
+	version = "2"
+	reconcile()
+	require.Equal(t, "2", liveVersion())
+
+	_, err := rec.Rollback(parent, component, 1)
+	require.NoError(t, err)
+	require.Equal(t, "1", liveVersion())
+
+	// the unchanged desired state must not undo the rollback
+	reconcile()
+	assert.Equal(t, "1", liveVersion())
+
+	require.NoError(t, rec.Unpin(parent, component))
+	reconcile()
+	assert.Equal(t, "2", liveVersion())
+
+	_, err = rec.Rollback(parent, component, 1)
+	require.NoError(t, err)
+	reconcile()
+	require.Equal(t, "1", liveVersion())
+
+	// a new desired state releases the pin
+	version = "3"
+	reconcile()
+	assert.Equal(t, "3", liveVersion())
+	version = "2"
+	reconcile()
+	assert.Equal(t, "2", liveVersion())
+
+	revisions, err := rec.History(parent, component)
+	require.NoError(t, err)
+	assert.Len(t, revisions, 5)
+}
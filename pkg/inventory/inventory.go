@@ -173,7 +173,7 @@ func (c *Inventory) PrepareDesiredObjects(ns, componentName string, parent recon
 	var err error
 	var desiredObjects []runtime.Object
 	var objectsInventory core.ConfigMap
-	objectsInventoryName := fmt.Sprintf("%s-%s-%s-object-inventory", parent.GetName(), ns, componentName)
+	objectsInventoryName := objectsInventoryName(parent, ns, componentName)
 
 	// collect
 	err = c.genericClient.Get(context.TODO(), types.NamespacedName{Namespace: ns, Name: objectsInventoryName}, &objectsInventory)
@@ -224,6 +224,44 @@ func (c *Inventory) PrepareDesiredObjects(ns, componentName string, parent recon
 		"namespace", ns, "inventoryName", objectsInventoryName)
 }
 
+func objectsInventoryName(parent reconciler.ResourceOwner, ns, componentName string) string {
+	return fmt.Sprintf("%s-%s-%s-object-inventory", parent.GetName(), ns, componentName)
+}
+
+// Adopt creates the inventory of a component from objects installed by some other means (e.g. by Helm),
+// so that the ones not desired anymore get removed by the next reconcile.
+// It does nothing and returns false if the inventory already exists.
+func (c *Inventory) Adopt(ns, componentName string, parent reconciler.ResourceOwner, objects []runtime.Object) (bool, error) {
+	name := objectsInventoryName(parent, ns, componentName)
+
+	err := c.genericClient.Get(context.TODO(), types.NamespacedName{Namespace: ns, Name: name}, &core.ConfigMap{})
+	if err == nil {
+		return false, nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return false, errors.WrapIfWithDetails(err, "during object inventory fetch...", "namespace", ns, "component", componentName, "inventoryName", name)
+	}
+
+	if err := c.sanitizeDesiredObjects(objects); err != nil {
+		return false, errors.WrapIfWithDetails(err, "couldn't sanitize adopted objects", "namespace", ns, "component", componentName)
+	}
+	if err := c.ensureNamespace(ns, objects); err != nil {
+		return false, errors.WrapIfWithDetails(err, "couldn't ensure namespace meta field on adopted objects", "namespace", ns, "component", componentName)
+	}
+
+	objectsInventory, err := CreateObjectsInventory(ns, name, objects)
+	if err != nil {
+		return false, errors.WrapIfWithDetails(err, "during object inventory creation...", "namespace", ns, "inventoryName", name)
+	}
+	if err := c.genericClient.Create(context.TODO(), objectsInventory); err != nil {
+		return false, errors.WrapIfWithDetails(err, "couldn't create object inventory", "namespace", ns, "inventoryName", name)
+	}
+
+	c.log.Info("objects adopted into the inventory", "namespace", ns, "component", componentName, "count", len(objects))
+
+	return true, nil
+}
+
 // Collect `missing` resources from desired state
 func (c *Inventory) PrepareDeletableObjects() error {
 	var deleteObjects []runtime.Object
@@ -0,0 +1,184 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package merge
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+
+	"github.com/cisco-open/operator-tools/pkg/utils"
+)
+
+// Strategic merge patch directives supported in untyped overrides, e.g. to remove a default sidecar:
+//
+//	containers:
+//	- name: sidecar
+//	  $patch: delete
+const (
+	PatchDirective                   = "$patch"
+	RetainKeysDirective              = "$retainKeys"
+	DeleteFromPrimitiveListDirective = "$deleteFromPrimitiveList/"
+	setElementOrderPrefix            = "$setElementOrder/"
+
+	deletePatch  = "delete"
+	replacePatch = "replace"
+	mergePatch   = "merge"
+
+	retainKeysStrategy = "retainKeys"
+)
+
+// DirectiveError is returned when a directive of the override can't be applied to the field it targets
+type DirectiveError struct {
+	// Path of the field in the override, e.g. spec.template.spec.containers
+	Path      string
+	Directive string
+	Reason    string
+}
+
+func (e *DirectiveError) Error() string {
+	return fmt.Sprintf("invalid %s directive at %s: %s", e.Directive, e.Path, e.Reason)
+}
+
+// prepare drops the null values of the override if they shouldn't delete fields and reports whether it has directives
+func prepare(value interface{}, ignoreNulls bool) bool {
+	directives := false
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if item == nil && ignoreNulls {
+				delete(v, key)
+				continue
+			}
+			if strings.HasPrefix(key, "$") {
+				directives = true
+			}
+			if prepare(item, ignoreNulls) {
+				directives = true
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if prepare(item, ignoreNulls) {
+				directives = true
+			}
+		}
+	}
+	return directives
+}
+
+// validateDirectives checks that the directives of the patch target fields with the required patch metadata
+func validateDirectives(patch map[string]interface{}, lookup strategicpatch.LookupPatchMeta, path string) error {
+	for key, value := range patch {
+		switch {
+		case key == PatchDirective:
+			if value != deletePatch && value != replacePatch && value != mergePatch {
+				return &DirectiveError{Path: path, Directive: PatchDirective, Reason: fmt.Sprintf("unknown patch %v", value)}
+			}
+			continue
+		case key == RetainKeysDirective, strings.HasPrefix(key, setElementOrderPrefix):
+			// validated along with the field they belong to
+			continue
+		case strings.HasPrefix(key, DeleteFromPrimitiveListDirective):
+			if err := validateDeleteFromPrimitiveList(lookup, strings.TrimPrefix(key, DeleteFromPrimitiveListDirective), value, join(path, key)); err != nil {
+				return err
+			}
+			continue
+		case strings.HasPrefix(key, "$"):
+			return &DirectiveError{Path: join(path, key), Directive: key, Reason: "unknown directive"}
+		}
+
+		switch v := value.(type) {
+		case map[string]interface{}:
+			fieldLookup, patchMeta, err := lookup.LookupPatchMetadataForStruct(key)
+			if err != nil {
+				return err
+			}
+			if _, ok := v[RetainKeysDirective]; ok && !utils.Contains(patchMeta.GetPatchStrategies(), retainKeysStrategy) {
+				return &DirectiveError{Path: join(path, key), Directive: RetainKeysDirective, Reason: "the field has no retainKeys patch strategy"}
+			}
+			if err := validateDirectives(v, fieldLookup, join(path, key)); err != nil {
+				return err
+			}
+		case []interface{}:
+			if err := validateList(lookup, key, v, join(path, key)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateList(lookup strategicpatch.LookupPatchMeta, key string, items []interface{}, path string) error {
+	var itemLookup strategicpatch.LookupPatchMeta
+	var patchMeta strategicpatch.PatchMeta
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if itemLookup == nil {
+			var err error
+			if itemLookup, patchMeta, err = lookup.LookupPatchMetadataForSlice(key); err != nil {
+				return err
+			}
+		}
+		if patch, ok := m[PatchDirective]; ok && patch == deletePatch {
+			mergeKey := patchMeta.GetPatchMergeKey()
+			if !utils.Contains(patchMeta.GetPatchStrategies(), mergePatch) || mergeKey == "" {
+				return &DirectiveError{Path: path, Directive: PatchDirective, Reason: "items can only be deleted from lists with a patch merge key"}
+			}
+			if _, ok := m[mergeKey]; !ok {
+				return &DirectiveError{Path: path, Directive: PatchDirective, Reason: fmt.Sprintf("the item to delete has no merge key %s", mergeKey)}
+			}
+		}
+		if _, ok := m[RetainKeysDirective]; ok && !utils.Contains(patchMeta.GetPatchStrategies(), retainKeysStrategy) {
+			return &DirectiveError{Path: path, Directive: RetainKeysDirective, Reason: "the items have no retainKeys patch strategy"}
+		}
+		if err := validateDirectives(m, itemLookup, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateDeleteFromPrimitiveList(lookup strategicpatch.LookupPatchMeta, key string, value interface{}, path string) error {
+	_, patchMeta, err := lookup.LookupPatchMetadataForSlice(key)
+	if err != nil {
+		return err
+	}
+	if !utils.Contains(patchMeta.GetPatchStrategies(), mergePatch) {
+		return &DirectiveError{Path: path, Directive: DeleteFromPrimitiveListDirective, Reason: "the list has no merge patch strategy"}
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return &DirectiveError{Path: path, Directive: DeleteFromPrimitiveListDirective, Reason: "the value is not a list"}
+	}
+	for _, item := range items {
+		switch item.(type) {
+		case map[string]interface{}, []interface{}:
+			return &DirectiveError{Path: path, Directive: DeleteFromPrimitiveListDirective, Reason: "the list has non-primitive items"}
+		}
+	}
+	return nil
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
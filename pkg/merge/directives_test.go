@@ -0,0 +1,175 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package merge
+
+import (
+	"encoding/json"
+	"testing"
+
+	"emperror.dev/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	v12 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func defaultDaemonSet() *v1.DaemonSet {
+	return &v1.DaemonSet{
+		ObjectMeta: v12.ObjectMeta{
+			Name:       "ds",
+			Finalizers: []string{"a", "b"},
+		},
+		Spec: v1.DaemonSetSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name: "app",
+							Env:  []corev1.EnvVar{{Name: "A", Value: "1"}, {Name: "B", Value: "2"}},
+						},
+						{Name: "sidecar"},
+					},
+					Tolerations: []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}},
+					Volumes: []corev1.Volume{
+						{Name: "config", VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{}}},
+					},
+					Affinity: &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{}},
+				},
+			},
+		},
+	}
+}
+
+func TestMergeDeleteDirectives(t *testing.T) {
+	base := defaultDaemonSet()
+	overrides := json.RawMessage(`{
+		"metadata": {"$deleteFromPrimitiveList/finalizers": ["a"]},
+		"spec": {"template": {"spec": {
+			"containers": [
+				{"name": "app", "env": [{"name": "A", "$patch": "delete"}]},
+				{"name": "sidecar", "$patch": "delete"}
+			],
+			"volumes": [
+				{"name": "config", "secret": {"secretName": "config"}, "$retainKeys": ["name", "secret"]}
+			],
+			"affinity": {"$patch": "delete"}
+		}}}
+	}`)
+
+	require.NoError(t, Merge(base, overrides))
+
+	assert.Equal(t, "ds", base.Name)
+	assert.Equal(t, []string{"b"}, base.Finalizers)
+	podSpec := base.Spec.Template.Spec
+	require.Len(t, podSpec.Containers, 1)
+	assert.Equal(t, "app", podSpec.Containers[0].Name)
+	assert.Equal(t, []corev1.EnvVar{{Name: "B", Value: "2"}}, podSpec.Containers[0].Env)
+	assert.Equal(t, []corev1.Volume{
+		{Name: "config", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "config"}}},
+	}, podSpec.Volumes)
+	// $patch: delete clears the content of a map, use null with WithNullDeletes to remove the field itself
+	assert.Equal(t, &corev1.Affinity{}, podSpec.Affinity)
+	assert.Len(t, podSpec.Tolerations, 1)
+}
+
+func TestMergeNulls(t *testing.T) {
+	overrides := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"affinity":    nil,
+					"tolerations": nil,
+				},
+			},
+		},
+	}
+
+	base := defaultDaemonSet()
+	require.NoError(t, Merge(base, overrides))
+	assert.NotNil(t, base.Spec.Template.Spec.Affinity)
+	assert.Len(t, base.Spec.Template.Spec.Tolerations, 1)
+
+	require.NoError(t, Merge(base, overrides, WithNullDeletes()))
+	assert.Nil(t, base.Spec.Template.Spec.Affinity)
+	assert.Empty(t, base.Spec.Template.Spec.Tolerations)
+	assert.Len(t, base.Spec.Template.Spec.Containers, 2)
+
+	// typed overrides serialize fields without omitempty as null, those never delete anything
+	podSpec := defaultDaemonSet().Spec.Template.Spec
+	require.NoError(t, Merge(&podSpec, &corev1.PodSpec{ServiceAccountName: "sa"}, WithNullDeletes()))
+	assert.Equal(t, "sa", podSpec.ServiceAccountName)
+	assert.Len(t, podSpec.Containers, 2)
+}
+
+func TestMergeInvalidDirectives(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides string
+		path      string
+		directive string
+	}{
+		{
+			name:      "delete from a list without merge key",
+			overrides: `{"spec": {"template": {"spec": {"tolerations": [{"key": "dedicated", "$patch": "delete"}]}}}}`,
+			path:      "spec.template.spec.tolerations",
+			directive: PatchDirective,
+		},
+		{
+			name:      "delete an item without its merge key",
+			overrides: `{"spec": {"template": {"spec": {"containers": [{"image": "app", "$patch": "delete"}]}}}}`,
+			path:      "spec.template.spec.containers",
+			directive: PatchDirective,
+		},
+		{
+			name:      "delete from a primitive list without merge strategy",
+			overrides: `{"spec": {"template": {"spec": {"containers": [{"name": "app", "$deleteFromPrimitiveList/args": ["--debug"]}]}}}}`,
+			path:      "spec.template.spec.containers.$deleteFromPrimitiveList/args",
+			directive: DeleteFromPrimitiveListDirective,
+		},
+		{
+			name:      "retain keys without retainKeys strategy",
+			overrides: `{"spec": {"template": {"spec": {"affinity": {"$retainKeys": ["nodeAffinity"]}}}}}`,
+			path:      "spec.template.spec.affinity",
+			directive: RetainKeysDirective,
+		},
+		{
+			name:      "unknown patch",
+			overrides: `{"spec": {"template": {"spec": {"affinity": {"$patch": "remove"}}}}}`,
+			path:      "spec.template.spec.affinity",
+			directive: PatchDirective,
+		},
+		{
+			name:      "unknown directive",
+			overrides: `{"spec": {"$unknown": true}}`,
+			path:      "spec.$unknown",
+			directive: "$unknown",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			base := defaultDaemonSet()
+			err := Merge(base, json.RawMessage(tt.overrides))
+			require.Error(t, err)
+
+			var directiveErr *DirectiveError
+			require.True(t, errors.As(err, &directiveErr), err.Error())
+			assert.Equal(t, tt.path, directiveErr.Path)
+			assert.Equal(t, tt.directive, directiveErr.Directive)
+			assert.Equal(t, defaultDaemonSet(), base)
+		})
+	}
+}
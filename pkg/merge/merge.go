@@ -12,6 +12,11 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
+// Package merge applies overrides to Kubernetes objects as strategic merge patches.
+//
+// The $patch, $retainKeys and $deleteFromPrimitiveList directives and null deletes are only honoured for untyped
+// overrides (`[]byte`, `json.RawMessage` or `map[string]interface{}`). Typed overrides are marshalled from Go structs,
+// so they can set fields but can't remove them from the target; use an untyped override for that.
 package merge
 
 import (
@@ -19,35 +24,24 @@ import (
 	"reflect"
 
 	"emperror.dev/errors"
-	"k8s.io/apimachinery/pkg/util/strategicpatch"
 )
 
 // Merge merges `overrides` into `base` using the SMP (structural merge patch) approach.
 // - It intentionally does not remove fields present in base but missing from overrides
 // - It merges slices only if the `patchStrategy:"merge"` tag is present and the `patchMergeKey` identifies the unique field
-func Merge(base, overrides interface{}) error {
-	baseBytes, err := json.Marshal(base)
-	if err != nil {
-		return errors.Wrap(err, "failed to convert current object to byte sequence")
-	}
-
-	overrideBytes, err := json.Marshal(overrides)
-	if err != nil {
-		return errors.Wrap(err, "failed to convert current object to byte sequence")
+// - Untyped overrides may remove fields with the $patch: delete, $retainKeys and $deleteFromPrimitiveList directives,
+// or with null values when WithNullDeletes is set.
+// Unlike Override, null values are ignored by default to keep the behaviour of earlier versions.
+// See Override for the details.
+func Merge(base, overrides interface{}, opts ...OverrideOption) error {
+	options := overrideOptions{}
+	for _, opt := range opts {
+		opt(&options)
 	}
 
-	patchMeta, err := strategicpatch.NewPatchMetaFromStruct(base)
+	merged, err := apply(base, overrides, options)
 	if err != nil {
-		return errors.WrapIf(err, "failed to produce patch meta from struct")
-	}
-	patch, err := strategicpatch.CreateThreeWayMergePatch(overrideBytes, overrideBytes, baseBytes, patchMeta, true)
-	if err != nil {
-		return errors.WrapIf(err, "failed to create three way merge patch")
-	}
-
-	merged, err := strategicpatch.StrategicMergePatchUsingLookupPatchMeta(baseBytes, patch, patchMeta)
-	if err != nil {
-		return errors.WrapIf(err, "failed to apply patch")
+		return err
 	}
 
 	valueOfBase := reflect.Indirect(reflect.ValueOf(base))
@@ -60,4 +54,4 @@ func Merge(base, overrides interface{}) error {
 	}
 	valueOfBase.Set(reflect.Indirect(into))
 	return nil
-}
\ No newline at end of file
+}
@@ -20,7 +20,11 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	v1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	v12 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -458,3 +462,324 @@ func TestMergeService(t *testing.T) {
 		LoadBalancerIP: "1.2.3.4",
 	})
 }
+
+func TestMergeJob(t *testing.T) {
+	base := &batchv1.Job{
+		Spec: batchv1.JobSpec{
+			BackoffLimit: utils.IntPointer(6),
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{
+						{
+							Name:    "job",
+							Image:   "image",
+							Command: []string{"run"},
+						},
+					},
+				},
+			},
+		},
+	}
+	overrides := &typeoverride.Job{
+		ObjectMeta: typeoverride.ObjectMeta{
+			Labels: map[string]string{"a": "1"},
+		},
+		Spec: typeoverride.JobSpec{
+			BackoffLimit:            utils.IntPointer(0),
+			TTLSecondsAfterFinished: utils.IntPointer(100),
+			Template: typeoverride.PodTemplateSpec{
+				Spec: typeoverride.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  "job",
+							Image: "image-2",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	err := Merge(base, overrides)
+	require.NoError(t, err)
+
+	require.Equal(t, &batchv1.Job{
+		ObjectMeta: v12.ObjectMeta{
+			Labels: map[string]string{"a": "1"},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            utils.IntPointer(0),
+			TTLSecondsAfterFinished: utils.IntPointer(100),
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{
+						{
+							Name:    "job",
+							Image:   "image-2",
+							Command: []string{"run"},
+						},
+					},
+				},
+			},
+		},
+	}, base)
+}
+
+func TestMergeCronJob(t *testing.T) {
+	base := &batchv1.CronJob{
+		Spec: batchv1.CronJobSpec{
+			Schedule:          "0 * * * *",
+			ConcurrencyPolicy: batchv1.AllowConcurrent,
+			JobTemplate: batchv1.JobTemplateSpec{
+				Spec: batchv1.JobSpec{
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "cron",
+									Image: "image",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	overrides := &typeoverride.CronJob{
+		Spec: typeoverride.CronJobSpec{
+			Schedule: "*/5 * * * *",
+			JobTemplate: typeoverride.JobTemplateSpec{
+				ObjectMeta: typeoverride.ObjectMeta{
+					Annotations: map[string]string{"a": "1"},
+				},
+				Spec: typeoverride.JobSpec{
+					Template: typeoverride.PodTemplateSpec{
+						Spec: typeoverride.PodSpec{
+							NodeSelector: map[string]string{"node": "cron"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	err := Merge(base, overrides)
+	require.NoError(t, err)
+
+	require.Equal(t, &batchv1.CronJob{
+		Spec: batchv1.CronJobSpec{
+			Schedule:          "*/5 * * * *",
+			ConcurrencyPolicy: batchv1.AllowConcurrent,
+			JobTemplate: batchv1.JobTemplateSpec{
+				ObjectMeta: v12.ObjectMeta{
+					Annotations: map[string]string{"a": "1"},
+				},
+				Spec: batchv1.JobSpec{
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							NodeSelector: map[string]string{"node": "cron"},
+							Containers: []corev1.Container{
+								{
+									Name:  "cron",
+									Image: "image",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}, base)
+}
+
+func TestMergeHorizontalPodAutoscaler(t *testing.T) {
+	base := &autoscalingv2.HorizontalPodAutoscaler{
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       "app",
+			},
+			MinReplicas: utils.IntPointer(1),
+			MaxReplicas: 3,
+			Metrics: []autoscalingv2.MetricSpec{
+				{
+					Type: autoscalingv2.ResourceMetricSourceType,
+					Resource: &autoscalingv2.ResourceMetricSource{
+						Name: corev1.ResourceCPU,
+						Target: autoscalingv2.MetricTarget{
+							Type:               autoscalingv2.UtilizationMetricType,
+							AverageUtilization: utils.IntPointer(80),
+						},
+					},
+				},
+			},
+		},
+	}
+	overrides := &typeoverride.HorizontalPodAutoscaler{
+		Spec: typeoverride.HorizontalPodAutoscalerSpec{
+			MaxReplicas: 10,
+			Metrics: []autoscalingv2.MetricSpec{
+				{
+					Type: autoscalingv2.ResourceMetricSourceType,
+					Resource: &autoscalingv2.ResourceMetricSource{
+						Name: corev1.ResourceMemory,
+						Target: autoscalingv2.MetricTarget{
+							Type:               autoscalingv2.UtilizationMetricType,
+							AverageUtilization: utils.IntPointer(60),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	err := Merge(base, overrides)
+	require.NoError(t, err)
+
+	// the scale target is kept as the override leaves it empty
+	assert.Equal(t, autoscalingv2.CrossVersionObjectReference{
+		APIVersion: "apps/v1",
+		Kind:       "Deployment",
+		Name:       "app",
+	}, base.Spec.ScaleTargetRef)
+	assert.Equal(t, utils.IntPointer(1), base.Spec.MinReplicas)
+	assert.Equal(t, int32(10), base.Spec.MaxReplicas)
+	// metrics are atomic, the list is replaced as a whole
+	assert.Equal(t, overrides.Spec.Metrics, base.Spec.Metrics)
+}
+
+func TestMergePodDisruptionBudget(t *testing.T) {
+	minAvailable := intstr.FromInt(1)
+	maxUnavailable := intstr.FromString("50%")
+	base := &policyv1.PodDisruptionBudget{
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MinAvailable: &minAvailable,
+			Selector: &v12.LabelSelector{
+				MatchLabels: map[string]string{"app": "a", "tier": "backend"},
+			},
+		},
+	}
+	overrides := &typeoverride.PodDisruptionBudget{
+		Spec: typeoverride.PodDisruptionBudgetSpec{
+			MaxUnavailable: &maxUnavailable,
+			Selector: &v12.LabelSelector{
+				MatchLabels: map[string]string{"app": "b"},
+			},
+		},
+	}
+
+	err := Merge(base, overrides)
+	require.NoError(t, err)
+
+	assert.Equal(t, &minAvailable, base.Spec.MinAvailable)
+	assert.Equal(t, &maxUnavailable, base.Spec.MaxUnavailable)
+	// the selector has a replace patch strategy
+	assert.Equal(t, &v12.LabelSelector{
+		MatchLabels: map[string]string{"app": "b"},
+	}, base.Spec.Selector)
+}
+
+func TestMergeIngress(t *testing.T) {
+	pathType := networkingv1.PathTypePrefix
+	base := &networkingv1.Ingress{
+		ObjectMeta: v12.ObjectMeta{
+			Annotations: map[string]string{"a": "1"},
+		},
+		Spec: networkingv1.IngressSpec{
+			IngressClassName: utils.StringPointer("nginx"),
+			Rules: []networkingv1.IngressRule{
+				{
+					Host: "a.example.com",
+					IngressRuleValue: networkingv1.IngressRuleValue{
+						HTTP: &networkingv1.HTTPIngressRuleValue{
+							Paths: []networkingv1.HTTPIngressPath{
+								{
+									Path:     "/",
+									PathType: &pathType,
+									Backend: networkingv1.IngressBackend{
+										Service: &networkingv1.IngressServiceBackend{
+											Name: "app",
+											Port: networkingv1.ServiceBackendPort{Number: 80},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	overrides := &typeoverride.Ingress{
+		ObjectMeta: typeoverride.ObjectMeta{
+			Annotations: map[string]string{"b": "2"},
+		},
+		Spec: networkingv1.IngressSpec{
+			TLS: []networkingv1.IngressTLS{
+				{
+					Hosts:      []string{"a.example.com"},
+					SecretName: "tls",
+				},
+			},
+		},
+	}
+
+	err := Merge(base, overrides)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, base.Annotations)
+	assert.Equal(t, utils.StringPointer("nginx"), base.Spec.IngressClassName)
+	assert.Len(t, base.Spec.Rules, 1)
+	assert.Equal(t, overrides.Spec.TLS, base.Spec.TLS)
+}
+
+func TestMergeNetworkPolicy(t *testing.T) {
+	base := &networkingv1.NetworkPolicy{
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: v12.LabelSelector{
+				MatchLabels: map[string]string{"app": "a"},
+			},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{
+				{
+					From: []networkingv1.NetworkPolicyPeer{
+						{
+							PodSelector: &v12.LabelSelector{
+								MatchLabels: map[string]string{"app": "b"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	overrides := &typeoverride.NetworkPolicy{
+		Spec: typeoverride.NetworkPolicySpec{
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+			Egress: []networkingv1.NetworkPolicyEgressRule{
+				{
+					To: []networkingv1.NetworkPolicyPeer{
+						{
+							IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.0/8"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	err := Merge(base, overrides)
+	require.NoError(t, err)
+
+	// the pod selector and the ingress rules are kept as the override leaves them empty
+	assert.Equal(t, v12.LabelSelector{
+		MatchLabels: map[string]string{"app": "a"},
+	}, base.Spec.PodSelector)
+	assert.Len(t, base.Spec.Ingress, 1)
+	assert.Equal(t, overrides.Spec.Egress, base.Spec.Egress)
+	assert.Equal(t, []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress}, base.Spec.PolicyTypes)
+}
@@ -0,0 +1,771 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package merge
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"emperror.dev/errors"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+
+	"github.com/cisco-open/operator-tools/pkg/utils"
+)
+
+// Struct tags of override types that refine how a field is applied to the target, e.g.
+// `overrideStrategy:"merge" overrideMergeKey:"key"` or `overridePath:"imagePullPolicy"`
+const (
+	// OverrideStrategyTag overrides the patch strategy the target type declares for the field
+	OverrideStrategyTag = "overrideStrategy"
+	// OverrideMergeKeyTag overrides the patch merge key the target type declares for the field
+	OverrideMergeKeyTag = "overrideMergeKey"
+	// OverridePathTag is the JSON name of the field in the target if it differs from the name in the override.
+	// Inlined fields are moved under the given key of the target, e.g. annotations and labels under metadata.
+	OverridePathTag = "overridePath"
+)
+
+// OverrideStrategy of a list or map field of an override type
+type OverrideStrategy string
+
+const (
+	// OverrideStrategyReplace replaces the value of the target
+	OverrideStrategyReplace OverrideStrategy = "replace"
+	// OverrideStrategyMerge merges the items of a list with the same merge key and adds the rest, or sets the keys of a map
+	OverrideStrategyMerge OverrideStrategy = "merge"
+	// OverrideStrategyAppend adds the items of the override that are missing from the list of the target
+	OverrideStrategyAppend OverrideStrategy = "append"
+	// OverrideStrategyReplaceByKey replaces the items of the target having the merge key of any item of the override
+	// with the items of the override with that key, and adds the rest. Unlike merge, it doesn't mix the fields of the items
+	// and more than one item may have the same key, e.g. tolerations of a key with different effects.
+	OverrideStrategyReplaceByKey OverrideStrategy = "replaceByKey"
+)
+
+// OverrideOption customizes how Override applies the override
+type OverrideOption func(*overrideOptions)
+
+type overrideOptions struct {
+	targetOrder      bool
+	nullDeletes      bool
+	missingMergeKeys bool
+}
+
+// WithNullDeletes removes the fields of the target that are null in an untyped override.
+// It is the default of Override, Merge ignores null values unless it is set.
+func WithNullDeletes() OverrideOption {
+	return func(o *overrideOptions) {
+		o.nullDeletes = true
+	}
+}
+
+// WithMissingMergeKeys matches the list items missing their merge key with each other, as if the key was empty,
+// instead of failing to merge the list, e.g. tolerations without a key or containers without a name
+func WithMissingMergeKeys() OverrideOption {
+	return func(o *overrideOptions) {
+		o.missingMergeKeys = true
+	}
+}
+
+// WithTargetOrder keeps the order of the list items of the target and appends the items missing from the target,
+// instead of ordering the merged lists the way the override does
+func WithTargetOrder() OverrideOption {
+	return func(o *overrideOptions) {
+		o.targetOrder = true
+	}
+}
+
+// Override returns a copy of the target with the override applied as a strategic merge patch
+// using the patch metadata of the target type, refined by the override tags of the override type.
+// - Fields that are empty in the override are left untouched, null values in untyped overrides
+// (`[]byte`, `json.RawMessage` or `map[string]interface{}`) remove the field from the target,
+// unlike in Merge, which ignores them unless WithNullDeletes is set.
+// - It merges lists only if the target or the override type declares a merge strategy and key for them.
+// - Untyped overrides may contain the $patch, $retainKeys and $deleteFromPrimitiveList directives,
+// such overrides are validated and applied to the target as they are, see DirectiveError.
+func Override[T, O any](target T, override O, opts ...OverrideOption) (T, error) {
+	options := overrideOptions{nullDeletes: true}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	var result T
+	merged, err := apply(target, override, options)
+	if err != nil {
+		return result, err
+	}
+	if err := json.Unmarshal(merged, &result); err != nil {
+		return result, errors.WrapIf(err, "failed to convert patched object")
+	}
+	return result, nil
+}
+
+func apply(target, override interface{}, options overrideOptions) ([]byte, error) {
+	targetBytes, err := json.Marshal(target)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to convert current object to byte sequence")
+	}
+	patchMeta, err := strategicpatch.NewPatchMetaFromStruct(target)
+	if err != nil {
+		return nil, errors.WrapIf(err, "failed to produce patch meta from struct")
+	}
+	return applyWithPatchMeta(targetBytes, override, patchMeta, options)
+}
+
+// applyWithPatchMeta applies the override to the JSON of the target using the given patch metadata of the target
+func applyWithPatchMeta(targetBytes []byte, override interface{}, patchMeta strategicpatch.LookupPatchMeta, options overrideOptions) ([]byte, error) {
+	overrideBytes, untyped, err := patchOf(override)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(overrideBytes, &fields); err != nil {
+		return nil, errors.Wrap(err, "failed to convert override object")
+	}
+	if fields == nil {
+		return targetBytes, nil
+	}
+	// null values of typed overrides come from fields without omitempty, they never mean deletion
+	directives := prepare(fields, !untyped || !options.nullDeletes)
+	patchMeta = withOverlay(patchMeta, override)
+	if overlay, ok := patchMeta.(overlayPatchMeta); ok {
+		var targetFields map[string]interface{}
+		if err := json.Unmarshal(targetBytes, &targetFields); err != nil {
+			return nil, errors.Wrap(err, "failed to convert current object")
+		}
+		replaceItemsByKey(fields, targetFields, overlay)
+	}
+	if !options.missingMergeKeys {
+		return patchTarget(targetBytes, fields, directives, patchMeta, options)
+	}
+
+	setMissingMergeKeys(fields, patchMeta)
+	var targetFields map[string]interface{}
+	if err := json.Unmarshal(targetBytes, &targetFields); err != nil {
+		return nil, errors.Wrap(err, "failed to convert current object")
+	}
+	setMissingMergeKeys(targetFields, patchMeta)
+	if targetBytes, err = json.Marshal(targetFields); err != nil {
+		return nil, errors.Wrap(err, "failed to convert current object to byte sequence")
+	}
+	merged, err := patchTarget(targetBytes, fields, directives, patchMeta, options)
+	if err != nil {
+		return nil, err
+	}
+	var mergedFields map[string]interface{}
+	if err := json.Unmarshal(merged, &mergedFields); err != nil {
+		return nil, errors.Wrap(err, "failed to convert patched object")
+	}
+	removeEmptyMergeKeys(mergedFields, patchMeta)
+	return json.Marshal(mergedFields)
+}
+
+// patchTarget applies the prepared fields of the override to the JSON of the target
+func patchTarget(targetBytes []byte, fields map[string]interface{}, directives bool, patchMeta strategicpatch.LookupPatchMeta, options overrideOptions) ([]byte, error) {
+	overrideBytes, err := json.Marshal(fields)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to convert override object to byte sequence")
+	}
+	if directives {
+		if err := validateDirectives(fields, patchMeta, ""); err != nil {
+			return nil, err
+		}
+		merged, err := strategicpatch.StrategicMergePatchUsingLookupPatchMeta(targetBytes, overrideBytes, patchMeta)
+		if err != nil {
+			return nil, errors.WrapIf(err, "failed to apply patch")
+		}
+		return merged, nil
+	}
+
+	patch, err := strategicpatch.CreateThreeWayMergePatch(overrideBytes, overrideBytes, targetBytes, patchMeta, true)
+	if err != nil {
+		return nil, errors.WrapIf(err, "failed to create three way merge patch")
+	}
+	if options.targetOrder {
+		if patch, err = withTargetOrder(patch, targetBytes); err != nil {
+			return nil, err
+		}
+	}
+
+	merged, err := strategicpatch.StrategicMergePatchUsingLookupPatchMeta(targetBytes, patch, patchMeta)
+	if err != nil {
+		return nil, errors.WrapIf(err, "failed to apply patch")
+	}
+	return merged, nil
+}
+
+// replaceItemsByKey resolves the lists of the override with the replaceByKey strategy against the target,
+// so that the lists can be replaced by the patch as a whole
+func replaceItemsByKey(fields, target map[string]interface{}, lookup strategicpatch.LookupPatchMeta) {
+	for key, value := range fields {
+		if strings.HasPrefix(key, "$") {
+			continue
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			t, ok := target[key].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if fieldLookup, _, err := lookup.LookupPatchMetadataForStruct(key); err == nil && fieldLookup != nil {
+				replaceItemsByKey(v, t, fieldLookup)
+			}
+		case []interface{}:
+			itemLookup, patchMeta, err := lookup.LookupPatchMetadataForSlice(key)
+			if err != nil {
+				continue
+			}
+			mergeKey := patchMeta.GetPatchMergeKey()
+			items, _ := target[key].([]interface{})
+			if o, ok := lookup.(overlayPatchMeta); ok && o.overlay.fields[key] != nil && o.overlay.fields[key].strategy == OverrideStrategyReplaceByKey {
+				if mergeKey != "" {
+					fields[key] = itemsReplacedByKey(items, v, mergeKey)
+				}
+				continue
+			}
+			if mergeKey == "" || itemLookup == nil || !utils.Contains(patchMeta.GetPatchStrategies(), mergePatch) {
+				continue
+			}
+			for _, item := range v {
+				m, ok := item.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				for _, t := range items {
+					if targetItem, ok := t.(map[string]interface{}); ok && reflect.DeepEqual(targetItem[mergeKey], m[mergeKey]) {
+						replaceItemsByKey(m, targetItem, itemLookup)
+						break
+					}
+				}
+			}
+		}
+	}
+}
+
+// itemsReplacedByKey returns the items of the target with the items of the override in place of the ones with the same key,
+// followed by the items of the override with keys missing from the target. Items missing the key match each other.
+func itemsReplacedByKey(target, override []interface{}, mergeKey string) []interface{} {
+	keyOf := func(item interface{}) interface{} {
+		if m, ok := item.(map[string]interface{}); ok {
+			return m[mergeKey]
+		}
+		return nil
+	}
+	withKey := func(key interface{}) []interface{} {
+		var result []interface{}
+		for _, item := range override {
+			if reflect.DeepEqual(keyOf(item), key) {
+				result = append(result, item)
+			}
+		}
+		return result
+	}
+
+	result := make([]interface{}, 0, len(target)+len(override))
+	var replaced []interface{}
+	for _, item := range target {
+		key := keyOf(item)
+		items := withKey(key)
+		if len(items) == 0 {
+			result = append(result, item)
+			continue
+		}
+		if !containsItem(replaced, key) {
+			result = append(result, items...)
+			replaced = append(replaced, key)
+		}
+	}
+	for _, item := range override {
+		if !containsItem(replaced, keyOf(item)) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// setMissingMergeKeys sets the merge key of the list items missing it to an empty string, which matches other empty keys
+func setMissingMergeKeys(fields map[string]interface{}, lookup strategicpatch.LookupPatchMeta) {
+	visitMergeKeys(fields, lookup, func(item map[string]interface{}, mergeKey string) {
+		if _, ok := item[mergeKey]; !ok {
+			item[mergeKey] = ""
+		}
+	})
+}
+
+// removeEmptyMergeKeys removes the empty merge keys set by setMissingMergeKeys from the merged object
+func removeEmptyMergeKeys(fields map[string]interface{}, lookup strategicpatch.LookupPatchMeta) {
+	visitMergeKeys(fields, lookup, func(item map[string]interface{}, mergeKey string) {
+		if item[mergeKey] == "" {
+			delete(item, mergeKey)
+		}
+	})
+}
+
+// visitMergeKeys calls the function with the items of the lists merged by a merge key and the key, fields missing
+// from the patch metadata are skipped
+func visitMergeKeys(fields map[string]interface{}, lookup strategicpatch.LookupPatchMeta, fn func(item map[string]interface{}, mergeKey string)) {
+	for key, value := range fields {
+		if strings.HasPrefix(key, "$") {
+			continue
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if fieldLookup, _, err := lookup.LookupPatchMetadataForStruct(key); err == nil && fieldLookup != nil {
+				visitMergeKeys(v, fieldLookup, fn)
+			}
+		case []interface{}:
+			itemLookup, patchMeta, err := lookup.LookupPatchMetadataForSlice(key)
+			if err != nil {
+				continue
+			}
+			mergeKey := patchMeta.GetPatchMergeKey()
+			merged := mergeKey != "" && utils.Contains(patchMeta.GetPatchStrategies(), mergePatch)
+			for _, item := range v {
+				m, ok := item.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if merged {
+					fn(m, mergeKey)
+				}
+				if itemLookup != nil {
+					visitMergeKeys(m, itemLookup, fn)
+				}
+			}
+		}
+	}
+}
+
+// withTargetOrder rewrites the $setElementOrder directives of the patch to keep the order of the items of the target
+// and to append the items missing from the target
+func withTargetOrder(patch, target []byte) ([]byte, error) {
+	var patchFields, targetFields map[string]interface{}
+	if err := json.Unmarshal(patch, &patchFields); err != nil {
+		return nil, errors.Wrap(err, "failed to parse patch")
+	}
+	if err := json.Unmarshal(target, &targetFields); err != nil {
+		return nil, errors.Wrap(err, "failed to parse current object")
+	}
+	targetOrder(patchFields, targetFields)
+	return json.Marshal(patchFields)
+}
+
+func targetOrder(patch, target map[string]interface{}) {
+	for key, value := range patch {
+		if strings.HasPrefix(key, setElementOrderPrefix) {
+			order, _ := value.([]interface{})
+			name := strings.TrimPrefix(key, setElementOrderPrefix)
+			items, _ := target[name].([]interface{})
+			order = orderOf(items, order)
+			patch[key] = order
+			// the items of the patch have to follow the element order
+			if list, ok := patch[name].([]interface{}); ok {
+				sortByOrder(list, order)
+			}
+			continue
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if t, ok := target[key].(map[string]interface{}); ok {
+				targetOrder(v, t)
+			}
+		case []interface{}:
+			order, _ := patch[setElementOrderPrefix+key].([]interface{})
+			mergeKey := orderMergeKey(order)
+			if mergeKey == "" {
+				continue
+			}
+			items, _ := target[key].([]interface{})
+			for _, item := range v {
+				patchItem, ok := item.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				for _, t := range items {
+					if targetItem, ok := t.(map[string]interface{}); ok && reflect.DeepEqual(targetItem[mergeKey], patchItem[mergeKey]) {
+						targetOrder(patchItem, targetItem)
+						break
+					}
+				}
+			}
+		}
+	}
+}
+
+// orderOf returns the element order of the items of the target followed by the items of the order missing from the target
+func orderOf(items, order []interface{}) []interface{} {
+	mergeKey := orderMergeKey(order)
+	result := make([]interface{}, 0, len(items)+len(order))
+	for _, item := range items {
+		if mergeKey == "" {
+			result = append(result, item)
+			continue
+		}
+		if m, ok := item.(map[string]interface{}); ok {
+			if value, ok := m[mergeKey]; ok {
+				result = append(result, map[string]interface{}{mergeKey: value})
+			}
+		}
+	}
+	for _, o := range order {
+		if !containsItem(result, o) {
+			result = append(result, o)
+		}
+	}
+	return result
+}
+
+// sortByOrder sorts the items of a patch list by their position in the element order
+func sortByOrder(list, order []interface{}) {
+	mergeKey := orderMergeKey(order)
+	position := func(item interface{}) int {
+		if m, ok := item.(map[string]interface{}); ok && mergeKey != "" {
+			item = map[string]interface{}{mergeKey: m[mergeKey]}
+		}
+		for i, o := range order {
+			if reflect.DeepEqual(o, item) {
+				return i
+			}
+		}
+		return len(order)
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return position(list[i]) < position(list[j])
+	})
+}
+
+// orderMergeKey returns the merge key used by the items of a $setElementOrder directive, or empty for primitive lists
+func orderMergeKey(order []interface{}) string {
+	for _, o := range order {
+		if m, ok := o.(map[string]interface{}); ok {
+			for k := range m {
+				return k
+			}
+		}
+	}
+	return ""
+}
+
+func containsItem(items []interface{}, item interface{}) bool {
+	for _, i := range items {
+		if reflect.DeepEqual(i, item) {
+			return true
+		}
+	}
+	return false
+}
+
+// patchOf converts the override to the JSON representation of the target and reports whether the override is untyped
+func patchOf(override interface{}) ([]byte, bool, error) {
+	switch o := override.(type) {
+	case json.RawMessage:
+		return o, true, nil
+	case []byte:
+		return o, true, nil
+	}
+
+	overrideBytes, err := json.Marshal(override)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to convert override object to byte sequence")
+	}
+	if _, ok := override.(map[string]interface{}); ok {
+		return overrideBytes, true, nil
+	}
+	t := reflect.TypeOf(override)
+	if t == nil || !hasOverridePath(t) {
+		return overrideBytes, false, nil
+	}
+
+	var fields interface{}
+	if err := json.Unmarshal(overrideBytes, &fields); err != nil {
+		return nil, false, errors.Wrap(err, "failed to convert override object")
+	}
+	relocate(t, fields)
+	overrideBytes, err = json.Marshal(fields)
+	return overrideBytes, false, err
+}
+
+// withOverlay refines the patch metadata of the target with the override tags of the override type
+func withOverlay(patchMeta strategicpatch.LookupPatchMeta, override interface{}) strategicpatch.LookupPatchMeta {
+	t := reflect.TypeOf(override)
+	if t == nil {
+		return patchMeta
+	}
+	if o := overlayOf(t); o != nil {
+		return overlayPatchMeta{LookupPatchMeta: patchMeta, overlay: o}
+	}
+	return patchMeta
+}
+
+// overlay holds the patch metadata declared by the override tags, keyed by the JSON name of the field in the target
+type overlay struct {
+	strategy OverrideStrategy
+	mergeKey string
+	fields   map[string]*overlay
+}
+
+var overlays sync.Map
+
+func overlayOf(t reflect.Type) *overlay {
+	if cached, ok := overlays.Load(t); ok {
+		return cached.(*overlay)
+	}
+	o := typeOverlay(t, map[reflect.Type]bool{})
+	overlays.Store(t, o)
+	return o
+}
+
+func typeOverlay(t reflect.Type, visiting map[reflect.Type]bool) *overlay {
+	t = structType(t)
+	if t == nil || visiting[t] {
+		return nil
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	fields := make(map[string]*overlay)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, inline, ok := jsonField(f)
+		if !ok {
+			continue
+		}
+		child := typeOverlay(f.Type, visiting)
+		path := f.Tag.Get(OverridePathTag)
+		if inline && path == "" {
+			if child != nil {
+				for k, v := range child.fields {
+					fields[k] = v
+				}
+			}
+			continue
+		}
+		if path == "" {
+			path = name
+		}
+		strategy := OverrideStrategy(f.Tag.Get(OverrideStrategyTag))
+		if child == nil && strategy == "" {
+			continue
+		}
+		node := &overlay{strategy: strategy, mergeKey: f.Tag.Get(OverrideMergeKeyTag)}
+		if child != nil {
+			node.fields = child.fields
+		}
+		fields[path] = node
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return &overlay{fields: fields}
+}
+
+// overlayPatchMeta refines the patch metadata of the target type with the override tags
+type overlayPatchMeta struct {
+	strategicpatch.LookupPatchMeta
+	overlay *overlay
+}
+
+func (m overlayPatchMeta) LookupPatchMetadataForStruct(key string) (strategicpatch.LookupPatchMeta, strategicpatch.PatchMeta, error) {
+	lookup, patchMeta, err := m.LookupPatchMeta.LookupPatchMetadataForStruct(key)
+	if err != nil {
+		return lookup, patchMeta, err
+	}
+	return m.refine(key, lookup, patchMeta)
+}
+
+func (m overlayPatchMeta) LookupPatchMetadataForSlice(key string) (strategicpatch.LookupPatchMeta, strategicpatch.PatchMeta, error) {
+	lookup, patchMeta, err := m.LookupPatchMeta.LookupPatchMetadataForSlice(key)
+	if err != nil {
+		return lookup, patchMeta, err
+	}
+	return m.refine(key, lookup, patchMeta)
+}
+
+func (m overlayPatchMeta) refine(key string, lookup strategicpatch.LookupPatchMeta, patchMeta strategicpatch.PatchMeta) (strategicpatch.LookupPatchMeta, strategicpatch.PatchMeta, error) {
+	node := m.overlay.fields[key]
+	if node == nil {
+		return lookup, patchMeta, nil
+	}
+	switch node.strategy {
+	case "":
+	case OverrideStrategyReplace, OverrideStrategyReplaceByKey:
+		// lists to be replaced by key are resolved against the target beforehand, see replaceItemsByKey
+		patchMeta.SetPatchStrategies([]string{string(OverrideStrategyReplace)})
+	case OverrideStrategyMerge, OverrideStrategyAppend:
+		// keep additional strategies of the target like retainKeys
+		if !utils.Contains(patchMeta.GetPatchStrategies(), mergePatch) {
+			patchMeta.SetPatchStrategies([]string{string(OverrideStrategyMerge)})
+		}
+	default:
+		return nil, patchMeta, errors.Errorf("unknown override strategy %q of field %s", node.strategy, key)
+	}
+	if node.mergeKey != "" {
+		patchMeta.SetPatchMergeKey(node.mergeKey)
+	}
+	if node.fields != nil {
+		lookup = overlayPatchMeta{LookupPatchMeta: lookup, overlay: node}
+	}
+	return lookup, patchMeta, nil
+}
+
+var overridePaths sync.Map
+
+// hasOverridePath reports whether the JSON representation of the type needs to be relocated to match the target
+func hasOverridePath(t reflect.Type) bool {
+	if cached, ok := overridePaths.Load(t); ok {
+		return cached.(bool)
+	}
+	has := typeHasOverridePath(t, map[reflect.Type]bool{})
+	overridePaths.Store(t, has)
+	return has
+}
+
+func typeHasOverridePath(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	t = structType(t)
+	if t == nil || visiting[t] {
+		return false
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if _, _, ok := jsonField(f); !ok {
+			continue
+		}
+		if f.Tag.Get(OverridePathTag) != "" || typeHasOverridePath(f.Type, visiting) {
+			return true
+		}
+	}
+	return false
+}
+
+// relocate moves the fields of the JSON value of the given type to their override path
+func relocate(t reflect.Type, value interface{}) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch v := value.(type) {
+	case []interface{}:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for _, item := range v {
+				relocate(t.Elem(), item)
+			}
+		}
+	case map[string]interface{}:
+		switch t.Kind() {
+		case reflect.Map:
+			for _, item := range v {
+				relocate(t.Elem(), item)
+			}
+		case reflect.Struct:
+			relocateFields(t, v)
+		}
+	}
+}
+
+func relocateFields(t reflect.Type, fields map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, inline, ok := jsonField(f)
+		if !ok {
+			continue
+		}
+		path := f.Tag.Get(OverridePathTag)
+		if inline {
+			if path == "" {
+				relocate(f.Type, fields)
+				continue
+			}
+			moved, _ := fields[path].(map[string]interface{})
+			for _, key := range jsonNames(f.Type) {
+				if v, ok := fields[key]; ok {
+					if moved == nil {
+						moved = make(map[string]interface{})
+					}
+					moved[key] = v
+					delete(fields, key)
+				}
+			}
+			if moved != nil {
+				relocate(f.Type, moved)
+				fields[path] = moved
+			}
+			continue
+		}
+		v, ok := fields[name]
+		if !ok {
+			continue
+		}
+		relocate(f.Type, v)
+		if path != "" {
+			delete(fields, name)
+			fields[path] = v
+		}
+	}
+}
+
+// jsonNames returns the JSON names of the fields of a struct type including the inlined ones
+func jsonNames(t reflect.Type) []string {
+	t = structType(t)
+	if t == nil {
+		return nil
+	}
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		name, inline, ok := jsonField(t.Field(i))
+		switch {
+		case !ok:
+		case inline:
+			names = append(names, jsonNames(t.Field(i).Type)...)
+		default:
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// jsonField returns the JSON name of a struct field and whether encoding/json inlines it
+func jsonField(f reflect.StructField) (name string, inline bool, ok bool) {
+	if !f.IsExported() && !f.Anonymous {
+		return "", false, false
+	}
+	name = strings.Split(f.Tag.Get("json"), ",")[0]
+	if name == "-" {
+		return "", false, false
+	}
+	if name == "" {
+		if f.Anonymous && structType(f.Type) != nil {
+			return "", true, true
+		}
+		name = f.Name
+	}
+	return name, false, f.IsExported()
+}
+
+// structType returns the struct type a pointer, slice, array or map type refers to, or nil
+func structType(t reflect.Type) reflect.Type {
+	for {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+		case reflect.Struct:
+			return t
+		default:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package merge
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	v12 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/cisco-open/operator-tools/pkg/utils"
+)
+
+type testMeta struct {
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+type testContainer struct {
+	Name       string            `json:"name,omitempty"`
+	PullPolicy corev1.PullPolicy `json:"pullPolicy,omitempty" overridePath:"imagePullPolicy"`
+}
+
+type testPodSpec struct {
+	Containers  []testContainer     `json:"containers,omitempty"`
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty" overrideStrategy:"merge" overrideMergeKey:"key"`
+	Args        []string            `json:"args,omitempty" overrideStrategy:"append"`
+}
+
+type testPod struct {
+	v12.ObjectMeta `json:"metadata,omitempty"`
+	Spec           testPodSpecTarget `json:"spec,omitempty"`
+}
+
+type testPodSpecTarget struct {
+	Containers  []corev1.Container  `json:"containers,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	Args        []string            `json:"args,omitempty"`
+}
+
+func TestOverride(t *testing.T) {
+	target := corev1.PodSpec{
+		Containers: []corev1.Container{{Name: "a", Image: "image-a"}},
+	}
+	result, err := Override(target, &corev1.PodSpec{
+		Containers: []corev1.Container{{Name: "a", Image: "image-a-2"}, {Name: "b", Image: "image-b"}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []corev1.Container{{Name: "a", Image: "image-a-2"}, {Name: "b", Image: "image-b"}}, result.Containers)
+	// the target is not modified
+	assert.Equal(t, "image-a", target.Containers[0].Image)
+
+	result, err = Override(target, (*corev1.PodSpec)(nil))
+	require.NoError(t, err)
+	assert.Equal(t, target, result)
+}
+
+func TestOverrideNullClearsField(t *testing.T) {
+	target := corev1.PodSpec{
+		ServiceAccountName: "sa",
+		NodeSelector:       map[string]string{"a": "1", "b": "2"},
+		Affinity:           &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{}},
+	}
+
+	result, err := Override(target, json.RawMessage(`{"affinity": null, "nodeSelector": {"a": null}}`))
+	require.NoError(t, err)
+	assert.Nil(t, result.Affinity)
+	assert.Equal(t, map[string]string{"b": "2"}, result.NodeSelector)
+	assert.Equal(t, "sa", result.ServiceAccountName)
+
+	result, err = Override(target, map[string]interface{}{"serviceAccountName": nil})
+	require.NoError(t, err)
+	assert.Empty(t, result.ServiceAccountName)
+	assert.NotNil(t, result.Affinity)
+}
+
+func TestOverrideTags(t *testing.T) {
+	target := testPod{
+		ObjectMeta: v12.ObjectMeta{Labels: map[string]string{"a": "1"}},
+		Spec: testPodSpecTarget{
+			Containers: []corev1.Container{{Name: "app", Image: "app-image"}},
+			Tolerations: []corev1.Toleration{
+				{Key: "dedicated", Operator: corev1.TolerationOpExists},
+				{Key: "other", Operator: corev1.TolerationOpExists},
+			},
+			Args: []string{"--verbose"},
+		},
+	}
+	override := struct {
+		Spec testPodSpec `json:"spec,omitempty"`
+	}{
+		Spec: testPodSpec{
+			Containers:  []testContainer{{Name: "app", PullPolicy: corev1.PullAlways}},
+			Tolerations: []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "logging"}},
+			Args:        []string{"--debug", "--verbose"},
+		},
+	}
+
+	result, err := Override(target, override)
+	require.NoError(t, err)
+	assert.Equal(t, []corev1.Container{{Name: "app", Image: "app-image", ImagePullPolicy: corev1.PullAlways}}, result.Spec.Containers)
+	assert.Equal(t, []corev1.Toleration{
+		{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "logging"},
+		{Key: "other", Operator: corev1.TolerationOpExists},
+	}, result.Spec.Tolerations)
+	assert.Equal(t, []string{"--debug", "--verbose"}, result.Spec.Args)
+
+	result, err = Override(target, override, WithTargetOrder())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"--verbose", "--debug"}, result.Spec.Args)
+}
+
+func TestOverrideInlinePath(t *testing.T) {
+	type metaBase struct {
+		*testMeta `json:",inline" overridePath:"metadata"`
+		Replicas  *int32 `json:"replicas,omitempty"`
+	}
+	type target struct {
+		v12.ObjectMeta `json:"metadata,omitempty"`
+		Replicas       *int32 `json:"replicas,omitempty"`
+	}
+
+	result, err := Override(target{ObjectMeta: v12.ObjectMeta{Name: "name", Labels: map[string]string{"a": "1"}}}, metaBase{
+		testMeta: &testMeta{Labels: map[string]string{"b": "2"}},
+		Replicas: utils.IntPointer(2),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "name", result.Name)
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, result.Labels)
+	assert.Equal(t, utils.IntPointer(2), result.Replicas)
+}
+
+func TestOverrideWithTargetOrder(t *testing.T) {
+	target := corev1.PodSpec{
+		Containers: []corev1.Container{
+			{Name: "app", Env: []corev1.EnvVar{{Name: "A", Value: "1"}, {Name: "B", Value: "2"}}},
+			{Name: "other"},
+		},
+	}
+	override := corev1.PodSpec{
+		Containers: []corev1.Container{
+			{Name: "sidecar"},
+			{Name: "app", Env: []corev1.EnvVar{{Name: "C", Value: "3"}, {Name: "B", Value: "override"}}},
+		},
+	}
+
+	result, err := Override(target, override, WithTargetOrder())
+	require.NoError(t, err)
+	require.Len(t, result.Containers, 3)
+	assert.Equal(t, "app", result.Containers[0].Name)
+	assert.Equal(t, []corev1.EnvVar{{Name: "A", Value: "1"}, {Name: "B", Value: "override"}, {Name: "C", Value: "3"}}, result.Containers[0].Env)
+	assert.Equal(t, "other", result.Containers[1].Name)
+	assert.Equal(t, "sidecar", result.Containers[2].Name)
+}
+
+func TestOverrideWithMissingMergeKeys(t *testing.T) {
+	target := corev1.PodSpec{
+		Containers: []corev1.Container{{Name: "app", Image: "app"}, {Image: "unnamed"}},
+	}
+	override := json.RawMessage(`{"containers": [{"image": "unnamed-2"}, {"name": "app", "env": [{"value": "1"}]}]}`)
+
+	_, err := Override(target, override)
+	require.Error(t, err)
+
+	result, err := Override(target, override, WithMissingMergeKeys(), WithTargetOrder())
+	require.NoError(t, err)
+	assert.Equal(t, []corev1.Container{
+		{Name: "app", Image: "app", Env: []corev1.EnvVar{{Value: "1"}}},
+		{Image: "unnamed-2"},
+	}, result.Containers)
+}
+
+func TestOverrideReplaceByKey(t *testing.T) {
+	type podSpec struct {
+		Tolerations []corev1.Toleration `json:"tolerations,omitempty" overrideStrategy:"replaceByKey" overrideMergeKey:"key"`
+	}
+	type pod struct {
+		Spec podSpec `json:"spec,omitempty"`
+	}
+	target := corev1.Pod{Spec: corev1.PodSpec{Tolerations: []corev1.Toleration{
+		{Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+		{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "logging"},
+		{Key: "other", Operator: corev1.TolerationOpExists},
+	}}}
+
+	result, err := Override(target, pod{Spec: podSpec{Tolerations: []corev1.Toleration{
+		{Key: "new", Operator: corev1.TolerationOpExists},
+		{Key: "dedicated", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+		{Key: "dedicated", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute},
+		{Operator: corev1.TolerationOpExists},
+	}}}, WithTargetOrder())
+	require.NoError(t, err)
+	assert.Equal(t, []corev1.Toleration{
+		{Operator: corev1.TolerationOpExists},
+		{Key: "dedicated", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+		{Key: "dedicated", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute},
+		{Key: "other", Operator: corev1.TolerationOpExists},
+		{Key: "new", Operator: corev1.TolerationOpExists},
+	}, result.Spec.Tolerations)
+}
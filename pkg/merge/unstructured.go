@@ -0,0 +1,282 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package merge
+
+import (
+	"encoding/json"
+	"strings"
+
+	"emperror.dev/errors"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/openapi"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// OpenAPI extensions the patch metadata is read from
+const (
+	extensionPatchStrategy = "x-kubernetes-patch-strategy"
+	extensionPatchMergeKey = "x-kubernetes-patch-merge-key"
+	extensionListType      = "x-kubernetes-list-type"
+	extensionListMapKeys   = "x-kubernetes-list-map-keys"
+	extensionMapType       = "x-kubernetes-map-type"
+	extensionGVK           = "x-kubernetes-group-version-kind"
+	extensionPreserve      = "x-kubernetes-preserve-unknown-fields"
+
+	schemaRefPrefix = "#/components/schemas/"
+)
+
+// MergeUnstructured merges `overrides` into the unstructured `base` the same way Merge does for typed objects,
+// using the patch metadata of the schema of the object, see NewSchemaPatchMetaFromCRD and NewSchemaPatchMetaFromOpenAPIV3.
+// The metadata of the object is merged according to the ObjectMeta type, regardless of the schema.
+func MergeUnstructured(base *unstructured.Unstructured, overrides interface{}, patchMeta strategicpatch.LookupPatchMeta, opts ...OverrideOption) error {
+	options := overrideOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if u, ok := overrides.(*unstructured.Unstructured); ok {
+		overrides = u.Object
+	}
+	baseBytes, err := json.Marshal(base.Object)
+	if err != nil {
+		return errors.Wrap(err, "failed to convert current object to byte sequence")
+	}
+	merged, err := applyWithPatchMeta(baseBytes, overrides, objectPatchMeta{LookupPatchMeta: patchMeta}, options)
+	if err != nil {
+		return err
+	}
+
+	object := make(map[string]interface{})
+	if err := json.Unmarshal(merged, &object); err != nil {
+		return errors.WrapIf(err, "failed to convert patched object")
+	}
+	base.Object = object
+	return nil
+}
+
+// objectPatchMeta uses the patch metadata of ObjectMeta for the metadata of the object
+type objectPatchMeta struct {
+	strategicpatch.LookupPatchMeta
+}
+
+func (m objectPatchMeta) LookupPatchMetadataForStruct(key string) (strategicpatch.LookupPatchMeta, strategicpatch.PatchMeta, error) {
+	if key == "metadata" {
+		objectMeta, err := strategicpatch.NewPatchMetaFromStruct(metav1.ObjectMeta{})
+		return objectMeta, strategicpatch.PatchMeta{}, err
+	}
+	return m.LookupPatchMeta.LookupPatchMetadataForStruct(key)
+}
+
+// SchemaPatchMeta looks up patch metadata in an OpenAPI v3 schema.
+// The patch strategy of a field is read from the x-kubernetes-patch-strategy and x-kubernetes-patch-merge-key extensions
+// of built-in types, or derived from the x-kubernetes-list-type, x-kubernetes-list-map-keys and x-kubernetes-map-type
+// extensions of custom resources:
+// - map lists with a single key are merged by that key, lists of multiple keys are replaced as SMP supports a single merge key
+// - set lists are merged, atomic and untyped lists are replaced
+// - atomic maps and structs are replaced, the rest is merged
+// Fields of schemaless objects and objects preserving unknown fields are merged with the default strategies.
+type SchemaPatchMeta struct {
+	Schema *spec.Schema
+	// SchemaList resolves the references of the schema by name
+	SchemaList map[string]*spec.Schema
+}
+
+var _ strategicpatch.LookupPatchMeta = SchemaPatchMeta{}
+
+// NewSchemaPatchMetaFromCRD returns the patch metadata of the given version of a custom resource
+func NewSchemaPatchMetaFromCRD(crd *apiextensionsv1.CustomResourceDefinition, version string) (SchemaPatchMeta, error) {
+	for _, v := range crd.Spec.Versions {
+		if v.Name != version {
+			continue
+		}
+		if v.Schema == nil || v.Schema.OpenAPIV3Schema == nil {
+			return SchemaPatchMeta{}, errors.Errorf("version %s of %s has no schema", version, crd.Name)
+		}
+		// JSONSchemaProps serializes to the same OpenAPI v3 schema, including the x-kubernetes extensions
+		schemaBytes, err := json.Marshal(v.Schema.OpenAPIV3Schema)
+		if err != nil {
+			return SchemaPatchMeta{}, errors.WrapIf(err, "failed to convert schema")
+		}
+		s := &spec.Schema{}
+		if err := json.Unmarshal(schemaBytes, s); err != nil {
+			return SchemaPatchMeta{}, errors.WrapIf(err, "failed to parse schema")
+		}
+		return SchemaPatchMeta{Schema: s}, nil
+	}
+	return SchemaPatchMeta{}, errors.Errorf("version %s not found in %s", version, crd.Name)
+}
+
+// NewSchemaPatchMetaFromOpenAPIV3 fetches the OpenAPI v3 document of the group version of the kind,
+// e.g. from the client returned by the OpenAPIV3 method of the discovery client
+func NewSchemaPatchMetaFromOpenAPIV3(client openapi.Client, gvk schema.GroupVersionKind) (SchemaPatchMeta, error) {
+	paths, err := client.Paths()
+	if err != nil {
+		return SchemaPatchMeta{}, errors.WrapIf(err, "failed to list OpenAPI v3 paths")
+	}
+	path := "apis/" + gvk.GroupVersion().String()
+	if gvk.Group == "" {
+		path = "api/" + gvk.Version
+	}
+	gv, ok := paths[path]
+	if !ok {
+		return SchemaPatchMeta{}, errors.Errorf("no OpenAPI v3 document for %s", gvk.GroupVersion())
+	}
+	doc, err := gv.Schema(runtime.ContentTypeJSON)
+	if err != nil {
+		return SchemaPatchMeta{}, errors.WrapIff(err, "failed to fetch OpenAPI v3 document for %s", gvk.GroupVersion())
+	}
+	return NewSchemaPatchMetaFromOpenAPIV3Document(doc, gvk)
+}
+
+// NewSchemaPatchMetaFromOpenAPIV3Document returns the patch metadata of the kind from an OpenAPI v3 document in JSON
+func NewSchemaPatchMetaFromOpenAPIV3Document(doc []byte, gvk schema.GroupVersionKind) (SchemaPatchMeta, error) {
+	var openAPI struct {
+		Components struct {
+			Schemas map[string]*spec.Schema `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(doc, &openAPI); err != nil {
+		return SchemaPatchMeta{}, errors.WrapIf(err, "failed to parse OpenAPI v3 document")
+	}
+	for _, s := range openAPI.Components.Schemas {
+		if hasGVK(s, gvk) {
+			return SchemaPatchMeta{Schema: s, SchemaList: openAPI.Components.Schemas}, nil
+		}
+	}
+	return SchemaPatchMeta{}, errors.Errorf("no schema for %s in the OpenAPI v3 document", gvk)
+}
+
+func hasGVK(s *spec.Schema, gvk schema.GroupVersionKind) bool {
+	var gvks []schema.GroupVersionKind
+	if err := s.Extensions.GetObject(extensionGVK, &gvks); err != nil {
+		return false
+	}
+	for _, g := range gvks {
+		if g == gvk {
+			return true
+		}
+	}
+	return false
+}
+
+func (s SchemaPatchMeta) LookupPatchMetadataForStruct(key string) (strategicpatch.LookupPatchMeta, strategicpatch.PatchMeta, error) {
+	field, err := s.field(key)
+	if err != nil {
+		return nil, strategicpatch.PatchMeta{}, err
+	}
+	patchMeta := schemaPatchMeta(field)
+	field, err = s.resolve(field)
+	return SchemaPatchMeta{Schema: field, SchemaList: s.SchemaList}, patchMeta, err
+}
+
+func (s SchemaPatchMeta) LookupPatchMetadataForSlice(key string) (strategicpatch.LookupPatchMeta, strategicpatch.PatchMeta, error) {
+	field, err := s.field(key)
+	if err != nil {
+		return nil, strategicpatch.PatchMeta{}, err
+	}
+	patchMeta := schemaPatchMeta(field)
+	if field, err = s.resolve(field); err != nil {
+		return nil, patchMeta, err
+	}
+	var items *spec.Schema
+	if field != nil && field.Items != nil {
+		items, err = s.resolve(field.Items.Schema)
+	}
+	return SchemaPatchMeta{Schema: items, SchemaList: s.SchemaList}, patchMeta, err
+}
+
+func (s SchemaPatchMeta) Name() string {
+	if s.Schema != nil && len(s.Schema.Type) > 0 {
+		return strings.Join(s.Schema.Type, "")
+	}
+	return "Struct"
+}
+
+// field returns the unresolved schema of a field, or nil if the object is schemaless
+func (s SchemaPatchMeta) field(key string) (*spec.Schema, error) {
+	if s.Schema == nil {
+		return nil, nil
+	}
+	if field, ok := s.Schema.Properties[key]; ok {
+		return &field, nil
+	}
+	if additional := s.Schema.AdditionalProperties; additional != nil {
+		if additional.Schema != nil {
+			return additional.Schema, nil
+		}
+		if additional.Allows {
+			return nil, nil
+		}
+	}
+	if preserve, _ := s.Schema.Extensions.GetBool(extensionPreserve); preserve || len(s.Schema.Properties) == 0 {
+		return nil, nil
+	}
+	return nil, errors.Errorf("unable to find api field %q", key)
+}
+
+// resolve follows the references of a schema
+func (s SchemaPatchMeta) resolve(schema *spec.Schema) (*spec.Schema, error) {
+	for schema != nil {
+		if len(schema.AllOf) > 0 {
+			schema = &schema.AllOf[0]
+			continue
+		}
+		ref := schema.Ref.String()
+		if ref == "" {
+			return schema, nil
+		}
+		resolved, ok := s.SchemaList[strings.TrimPrefix(ref, schemaRefPrefix)]
+		if !ok {
+			return nil, errors.Errorf("unable to resolve %s in OpenAPI v3", ref)
+		}
+		schema = resolved
+	}
+	return nil, nil
+}
+
+// schemaPatchMeta returns the patch metadata declared by the extensions of a field
+func schemaPatchMeta(field *spec.Schema) strategicpatch.PatchMeta {
+	patchMeta := strategicpatch.PatchMeta{}
+	if field == nil {
+		return patchMeta
+	}
+	if strategy, ok := field.Extensions.GetString(extensionPatchStrategy); ok {
+		patchMeta.SetPatchStrategies(strings.Split(strategy, ","))
+		if mergeKey, ok := field.Extensions.GetString(extensionPatchMergeKey); ok {
+			patchMeta.SetPatchMergeKey(mergeKey)
+		}
+		return patchMeta
+	}
+
+	listType, _ := field.Extensions.GetString(extensionListType)
+	switch listType {
+	case "map":
+		if keys, ok := field.Extensions.GetStringSlice(extensionListMapKeys); ok && len(keys) == 1 {
+			patchMeta.SetPatchStrategies([]string{mergePatch})
+			patchMeta.SetPatchMergeKey(keys[0])
+		}
+	case "set":
+		patchMeta.SetPatchStrategies([]string{mergePatch})
+	}
+	if mapType, _ := field.Extensions.GetString(extensionMapType); mapType == "atomic" {
+		patchMeta.SetPatchStrategies([]string{replacePatch})
+	}
+	return patchMeta
+}
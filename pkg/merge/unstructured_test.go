@@ -0,0 +1,201 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package merge
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/openapi/openapitest"
+)
+
+const testCRDSchema = `{
+	"type": "object",
+	"properties": {
+		"apiVersion": {"type": "string"},
+		"kind": {"type": "string"},
+		"metadata": {"type": "object"},
+		"spec": {
+			"type": "object",
+			"properties": {
+				"workers": {
+					"type": "array",
+					"x-kubernetes-list-type": "map",
+					"x-kubernetes-list-map-keys": ["name"],
+					"items": {"type": "object", "properties": {"name": {"type": "string"}, "image": {"type": "string"}, "replicas": {"type": "integer"}}}
+				},
+				"ports": {
+					"type": "array",
+					"x-kubernetes-list-type": "map",
+					"x-kubernetes-list-map-keys": ["port", "protocol"],
+					"items": {"type": "object", "properties": {"port": {"type": "integer"}, "protocol": {"type": "string"}}}
+				},
+				"tags": {
+					"type": "array",
+					"x-kubernetes-list-type": "set",
+					"items": {"type": "string"}
+				},
+				"args": {
+					"type": "array",
+					"items": {"type": "string"}
+				},
+				"selector": {
+					"type": "object",
+					"x-kubernetes-map-type": "atomic",
+					"additionalProperties": {"type": "string"}
+				},
+				"config": {
+					"type": "object",
+					"x-kubernetes-preserve-unknown-fields": true
+				}
+			}
+		}
+	}
+}`
+
+func testCRD(t *testing.T) *apiextensionsv1.CustomResourceDefinition {
+	props := &apiextensionsv1.JSONSchemaProps{}
+	require.NoError(t, json.Unmarshal([]byte(testCRDSchema), props))
+	return &apiextensionsv1.CustomResourceDefinition{
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{Name: "v1", Schema: &apiextensionsv1.CustomResourceValidation{OpenAPIV3Schema: props}},
+			},
+		},
+	}
+}
+
+func testCustomResource() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Cluster",
+		"metadata": map[string]interface{}{
+			"name":       "cluster",
+			"labels":     map[string]interface{}{"a": "1"},
+			"finalizers": []interface{}{"a"},
+		},
+		"spec": map[string]interface{}{
+			"workers": []interface{}{
+				map[string]interface{}{"name": "default", "image": "worker", "replicas": int64(1)},
+				map[string]interface{}{"name": "gpu", "image": "worker-gpu"},
+			},
+			"ports": []interface{}{
+				map[string]interface{}{"port": int64(80), "protocol": "TCP"},
+				map[string]interface{}{"port": int64(443), "protocol": "TCP"},
+			},
+			"tags":     []interface{}{"a"},
+			"args":     []interface{}{"--verbose"},
+			"selector": map[string]interface{}{"app": "cluster", "tier": "backend"},
+			"config":   map[string]interface{}{"log": map[string]interface{}{"level": "info", "format": "json"}},
+		},
+	}}
+}
+
+func TestMergeUnstructuredCRD(t *testing.T) {
+	patchMeta, err := NewSchemaPatchMetaFromCRD(testCRD(t), "v1")
+	require.NoError(t, err)
+
+	base := testCustomResource()
+	require.NoError(t, MergeUnstructured(base, map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels":     map[string]interface{}{"b": "2"},
+			"finalizers": []interface{}{"b"},
+		},
+		"spec": map[string]interface{}{
+			"workers": []interface{}{
+				map[string]interface{}{"name": "default", "replicas": int64(3)},
+				map[string]interface{}{"name": "gpu", "$patch": "delete"},
+			},
+			"ports":    []interface{}{map[string]interface{}{"port": int64(8080), "protocol": "TCP"}},
+			"tags":     []interface{}{"b"},
+			"args":     []interface{}{"--debug"},
+			"selector": map[string]interface{}{"app": "other"},
+			"config":   map[string]interface{}{"log": map[string]interface{}{"level": "debug"}},
+		},
+	}, patchMeta))
+
+	assert.Equal(t, "cluster", base.GetName())
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, base.GetLabels())
+	assert.ElementsMatch(t, []string{"a", "b"}, base.GetFinalizers())
+
+	spec := base.Object["spec"].(map[string]interface{})
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"name": "default", "image": "worker", "replicas": float64(3)},
+	}, spec["workers"])
+	// lists with multiple keys and atomic lists are replaced
+	assert.Equal(t, []interface{}{map[string]interface{}{"port": float64(8080), "protocol": "TCP"}}, spec["ports"])
+	assert.Equal(t, []interface{}{"--debug"}, spec["args"])
+	assert.ElementsMatch(t, []interface{}{"a", "b"}, spec["tags"])
+	assert.Equal(t, map[string]interface{}{"app": "other"}, spec["selector"])
+	assert.Equal(t, map[string]interface{}{"log": map[string]interface{}{"level": "debug", "format": "json"}}, spec["config"])
+}
+
+func TestMergeUnstructuredUnstructuredOverrides(t *testing.T) {
+	patchMeta, err := NewSchemaPatchMetaFromCRD(testCRD(t), "v1")
+	require.NoError(t, err)
+
+	base := testCustomResource()
+	require.NoError(t, MergeUnstructured(base, &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"workers": []interface{}{map[string]interface{}{"name": "spot", "image": "worker"}},
+		},
+	}}, patchMeta))
+
+	workers, _, err := unstructured.NestedSlice(base.Object, "spec", "workers")
+	require.NoError(t, err)
+	assert.Len(t, workers, 3)
+
+	_, err = NewSchemaPatchMetaFromCRD(testCRD(t), "v2")
+	assert.Error(t, err)
+}
+
+func TestMergeUnstructuredOpenAPIV3(t *testing.T) {
+	patchMeta, err := NewSchemaPatchMetaFromOpenAPIV3(openapitest.NewEmbeddedFileClient(), schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"})
+	require.NoError(t, err)
+
+	base := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "app"},
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"name": "app", "image": "app"},
+						map[string]interface{}{"name": "sidecar", "image": "sidecar"},
+					},
+				},
+			},
+		},
+	}}
+	require.NoError(t, MergeUnstructured(base, json.RawMessage(`{"spec": {"template": {"spec": {"containers": [
+		{"name": "app", "imagePullPolicy": "Always"},
+		{"name": "sidecar", "$patch": "delete"}
+	]}}}}`), patchMeta))
+
+	containers, _, err := unstructured.NestedSlice(base.Object, "spec", "template", "spec", "containers")
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"name": "app", "image": "app", "imagePullPolicy": "Always"},
+	}, containers)
+
+	_, err = NewSchemaPatchMetaFromOpenAPIV3(openapitest.NewEmbeddedFileClient(), schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Cluster"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,110 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconciler
+
+import (
+	"context"
+
+	"emperror.dev/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/cisco-open/operator-tools/pkg/resources"
+)
+
+// DefaultCABundleSecretKey is the key of the CA certificate in Secrets of type kubernetes.io/tls
+const DefaultCABundleSecretKey = "ca.crt"
+
+// CABundleInjection is a desired state that injects the CA bundle stored in a Secret into webhook configurations,
+// CRD conversion webhooks and APIServices right before they are created or updated
+type CABundleInjection struct {
+	// State is the wrapped desired state, StatePresent if not set
+	State  DesiredState
+	Client client.Reader
+	Secret client.ObjectKey
+	// Key of the CA bundle in the Secret, DefaultCABundleSecretKey if not set
+	Key string
+}
+
+func NewCABundleInjection(c client.Reader, secret client.ObjectKey, state DesiredState) CABundleInjection {
+	return CABundleInjection{
+		State:  state,
+		Client: c,
+		Secret: secret,
+	}
+}
+
+func (s CABundleInjection) GetDesiredState() DesiredState {
+	if s.State == nil {
+		return StatePresent
+	}
+	if ds, ok := s.State.(DesiredStateWithGetter); ok {
+		return ds.GetDesiredState()
+	}
+	return s.State
+}
+
+func (s CABundleInjection) BeforeCreate(desired runtime.Object) error {
+	if s.State != nil {
+		if err := s.State.BeforeCreate(desired); err != nil {
+			return err
+		}
+	}
+	return s.inject(desired)
+}
+
+func (s CABundleInjection) BeforeUpdate(current, desired runtime.Object) error {
+	if s.State != nil {
+		if err := s.State.BeforeUpdate(current, desired); err != nil {
+			return err
+		}
+	}
+	return s.inject(desired)
+}
+
+func (s CABundleInjection) BeforeDelete(current runtime.Object) error {
+	if s.State != nil {
+		return s.State.BeforeDelete(current)
+	}
+	return nil
+}
+
+func (s CABundleInjection) inject(desired runtime.Object) error {
+	caBundle, err := s.caBundle()
+	if err != nil {
+		return err
+	}
+	_, err = resources.CABundleModifier(caBundle)(desired)
+	return err
+}
+
+func (s CABundleInjection) caBundle() ([]byte, error) {
+	key := s.Key
+	if key == "" {
+		key = DefaultCABundleSecretKey
+	}
+
+	secret := &corev1.Secret{}
+	if err := s.Client.Get(context.TODO(), s.Secret, secret); err != nil {
+		return nil, errors.WrapIfWithDetails(err, "could not get CA bundle secret", "secret", s.Secret)
+	}
+	caBundle, ok := secret.Data[key]
+	if !ok || len(caBundle) == 0 {
+		return nil, errors.NewWithDetails("CA bundle is missing from secret", "secret", s.Secret, "key", key)
+	}
+
+	return caBundle, nil
+}
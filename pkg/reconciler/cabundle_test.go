@@ -0,0 +1,242 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconciler_test
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	admissionregistrationv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/cisco-open/operator-tools/pkg/reconciler"
+)
+
+// caBundleKind builds an object of a kind with a CA bundle and reads the CA bundle back
+type caBundleKind struct {
+	build    func(caBundle []byte, annotations map[string]string) runtime.Object
+	caBundle func(o runtime.Object) []byte
+}
+
+func caBundleKinds() map[string]caBundleKind {
+	meta := func(annotations map[string]string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Name: "test", Annotations: annotations}
+	}
+	return map[string]caBundleKind{
+		"v1 mutating webhook configuration": {
+			build: func(caBundle []byte, annotations map[string]string) runtime.Object {
+				return &admissionregistrationv1.MutatingWebhookConfiguration{ObjectMeta: meta(annotations), Webhooks: []admissionregistrationv1.MutatingWebhook{
+					{Name: "webhook", ClientConfig: admissionregistrationv1.WebhookClientConfig{CABundle: caBundle}},
+				}}
+			},
+			caBundle: func(o runtime.Object) []byte {
+				return o.(*admissionregistrationv1.MutatingWebhookConfiguration).Webhooks[0].ClientConfig.CABundle
+			},
+		},
+		"v1 validating webhook configuration": {
+			build: func(caBundle []byte, annotations map[string]string) runtime.Object {
+				return &admissionregistrationv1.ValidatingWebhookConfiguration{ObjectMeta: meta(annotations), Webhooks: []admissionregistrationv1.ValidatingWebhook{
+					{Name: "webhook", ClientConfig: admissionregistrationv1.WebhookClientConfig{CABundle: caBundle}},
+				}}
+			},
+			caBundle: func(o runtime.Object) []byte {
+				return o.(*admissionregistrationv1.ValidatingWebhookConfiguration).Webhooks[0].ClientConfig.CABundle
+			},
+		},
+		"v1beta1 mutating webhook configuration": {
+			build: func(caBundle []byte, annotations map[string]string) runtime.Object {
+				return &admissionregistrationv1beta1.MutatingWebhookConfiguration{ObjectMeta: meta(annotations), Webhooks: []admissionregistrationv1beta1.MutatingWebhook{
+					{Name: "webhook", ClientConfig: admissionregistrationv1beta1.WebhookClientConfig{CABundle: caBundle}},
+				}}
+			},
+			caBundle: func(o runtime.Object) []byte {
+				return o.(*admissionregistrationv1beta1.MutatingWebhookConfiguration).Webhooks[0].ClientConfig.CABundle
+			},
+		},
+		"v1beta1 validating webhook configuration": {
+			build: func(caBundle []byte, annotations map[string]string) runtime.Object {
+				return &admissionregistrationv1beta1.ValidatingWebhookConfiguration{ObjectMeta: meta(annotations), Webhooks: []admissionregistrationv1beta1.ValidatingWebhook{
+					{Name: "webhook", ClientConfig: admissionregistrationv1beta1.WebhookClientConfig{CABundle: caBundle}},
+				}}
+			},
+			caBundle: func(o runtime.Object) []byte {
+				return o.(*admissionregistrationv1beta1.ValidatingWebhookConfiguration).Webhooks[0].ClientConfig.CABundle
+			},
+		},
+		"v1 CRD conversion webhook": {
+			build: func(caBundle []byte, annotations map[string]string) runtime.Object {
+				return &apiextensionsv1.CustomResourceDefinition{ObjectMeta: meta(annotations), Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+					Conversion: &apiextensionsv1.CustomResourceConversion{
+						Strategy: apiextensionsv1.WebhookConverter,
+						Webhook: &apiextensionsv1.WebhookConversion{
+							ClientConfig: &apiextensionsv1.WebhookClientConfig{CABundle: caBundle},
+						},
+					},
+				}}
+			},
+			caBundle: func(o runtime.Object) []byte {
+				return o.(*apiextensionsv1.CustomResourceDefinition).Spec.Conversion.Webhook.ClientConfig.CABundle
+			},
+		},
+		"v1beta1 CRD conversion webhook": {
+			build: func(caBundle []byte, annotations map[string]string) runtime.Object {
+				return &apiextensionsv1beta1.CustomResourceDefinition{ObjectMeta: meta(annotations), Spec: apiextensionsv1beta1.CustomResourceDefinitionSpec{
+					Conversion: &apiextensionsv1beta1.CustomResourceConversion{
+						Strategy:            apiextensionsv1beta1.WebhookConverter,
+						WebhookClientConfig: &apiextensionsv1beta1.WebhookClientConfig{CABundle: caBundle},
+					},
+				}}
+			},
+			caBundle: func(o runtime.Object) []byte {
+				return o.(*apiextensionsv1beta1.CustomResourceDefinition).Spec.Conversion.WebhookClientConfig.CABundle
+			},
+		},
+		"unstructured APIService": {
+			build: func(caBundle []byte, annotations map[string]string) runtime.Object {
+				u := &unstructured.Unstructured{Object: map[string]interface{}{
+					"apiVersion": "apiregistration.k8s.io/v1",
+					"kind":       "APIService",
+					"spec":       map[string]interface{}{"service": map[string]interface{}{"name": "api"}},
+				}}
+				u.SetName("test")
+				u.SetAnnotations(annotations)
+				if len(caBundle) > 0 {
+					_ = unstructured.SetNestedField(u.Object, base64.StdEncoding.EncodeToString(caBundle), "spec", "caBundle")
+				}
+				return u
+			},
+			caBundle: func(o runtime.Object) []byte {
+				encoded, _, _ := unstructured.NestedString(o.(*unstructured.Unstructured).Object, "spec", "caBundle")
+				caBundle, _ := base64.StdEncoding.DecodeString(encoded)
+				if len(caBundle) == 0 {
+					return nil
+				}
+				return caBundle
+			},
+		},
+	}
+}
+
+func TestKeepCABundleModifier(t *testing.T) {
+	live, desired := []byte("live"), []byte("desired")
+	external := map[string]string{"cert-manager.io/inject-ca-from": "default/webhook"}
+	tests := map[string]struct {
+		current     []byte
+		desired     []byte
+		annotations map[string]string
+		want        []byte
+	}{
+		"externally injected bundle is kept":      {current: live, desired: desired, annotations: external, want: live},
+		"empty desired bundle keeps the live one": {current: live, want: live},
+		"desired bundle wins":                     {current: live, desired: desired, want: desired},
+		"no live bundle":                          {desired: desired, annotations: external, want: desired},
+	}
+
+	for kindName, kind := range caBundleKinds() {
+		for name, tt := range tests {
+			kind, tt := kind, tt
+			t.Run(kindName+"/"+name, func(t *testing.T) {
+				desiredObject := kind.build(tt.desired, tt.annotations)
+				require.NoError(t, reconciler.KeepCABundleModifier(kind.build(tt.current, nil), desiredObject))
+				assert.Equal(t, tt.want, kind.caBundle(desiredObject))
+			})
+		}
+	}
+}
+
+func TestKeepCABundleModifierByWebhookName(t *testing.T) {
+	current := &admissionregistrationv1.ValidatingWebhookConfiguration{Webhooks: []admissionregistrationv1.ValidatingWebhook{
+		{Name: "a", ClientConfig: admissionregistrationv1.WebhookClientConfig{CABundle: []byte("live-a")}},
+	}}
+	desired := &admissionregistrationv1.ValidatingWebhookConfiguration{Webhooks: []admissionregistrationv1.ValidatingWebhook{
+		{Name: "b"},
+		{Name: "a"},
+	}}
+
+	require.NoError(t, reconciler.KeepCABundleModifier(current, desired))
+	assert.Empty(t, desired.Webhooks[0].ClientConfig.CABundle)
+	assert.Equal(t, []byte("live-a"), desired.Webhooks[1].ClientConfig.CABundle)
+
+	// other kinds are left alone
+	service := &corev1.Service{Spec: corev1.ServiceSpec{ClusterIP: "10.0.0.1"}}
+	require.NoError(t, reconciler.KeepCABundleModifier(&corev1.Service{}, service))
+	assert.Equal(t, "10.0.0.1", service.Spec.ClusterIP)
+}
+
+func TestCABundleInjection(t *testing.T) {
+	secretKey := client.ObjectKey{Namespace: "default", Name: "webhook-cert"}
+	c := fake.NewClientBuilder().WithObjects(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: secretKey.Namespace, Name: secretKey.Name},
+			Data:       map[string][]byte{"ca.crt": []byte("ca"), "custom.crt": []byte("custom")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: secretKey.Namespace, Name: "empty"},
+			Data:       map[string][]byte{"tls.crt": []byte("cert")},
+		},
+	).Build()
+
+	for kindName, kind := range caBundleKinds() {
+		kind := kind
+		t.Run(kindName, func(t *testing.T) {
+			injection := reconciler.NewCABundleInjection(c, secretKey, nil)
+			assert.Equal(t, reconciler.StatePresent, injection.GetDesiredState())
+
+			desired := kind.build(nil, nil)
+			require.NoError(t, injection.BeforeCreate(desired))
+			assert.Equal(t, []byte("ca"), kind.caBundle(desired))
+
+			injection.Key = "custom.crt"
+			desired = kind.build([]byte("stale"), nil)
+			require.NoError(t, injection.BeforeUpdate(kind.build([]byte("ca"), nil), desired))
+			assert.Equal(t, []byte("custom"), kind.caBundle(desired))
+		})
+	}
+
+	t.Run("missing secret", func(t *testing.T) {
+		injection := reconciler.NewCABundleInjection(c, client.ObjectKey{Namespace: "default", Name: "missing"}, reconciler.StatePresent)
+		assert.Error(t, injection.BeforeCreate(caBundleKinds()["v1 mutating webhook configuration"].build(nil, nil)))
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		injection := reconciler.NewCABundleInjection(c, client.ObjectKey{Namespace: "default", Name: "empty"}, reconciler.StatePresent)
+		assert.Error(t, injection.BeforeUpdate(nil, caBundleKinds()["v1 mutating webhook configuration"].build(nil, nil)))
+	})
+
+	t.Run("wrapped state", func(t *testing.T) {
+		called := false
+		state := reconciler.DynamicDesiredState{
+			DesiredState: reconciler.StateAbsent,
+			BeforeCreateFunc: func(desired runtime.Object) error {
+				called = true
+				return nil
+			},
+		}
+		injection := reconciler.NewCABundleInjection(c, secretKey, state)
+		assert.Equal(t, reconciler.StateAbsent, injection.GetDesiredState())
+		require.NoError(t, injection.BeforeCreate(caBundleKinds()["v1 mutating webhook configuration"].build(nil, nil)))
+		assert.True(t, called)
+	})
+}
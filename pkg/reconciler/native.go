@@ -87,6 +87,7 @@ func GetResourceBuildersFromObjects(objects []runtime.Object, state DesiredState
 					ServiceIPModifier,
 					KeepLabelsAndAnnotationsModifer,
 					KeepServiceAccountTokenReferences,
+					KeepCABundleModifier,
 				} {
 					err := f(current, desired)
 					if err != nil {
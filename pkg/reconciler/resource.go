@@ -38,6 +38,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"github.com/cisco-open/operator-tools/pkg/crd"
 	"github.com/cisco-open/operator-tools/pkg/types"
 	"github.com/cisco-open/operator-tools/pkg/utils"
 )
@@ -693,16 +694,8 @@ func crdReady(crd *v1beta1.CustomResourceDefinition) bool {
 	return false
 }
 
-func crdReadyV1(crd *v1.CustomResourceDefinition) bool {
-	for _, cond := range crd.Status.Conditions {
-		switch cond.Type {
-		case v1.Established:
-			if cond.Status == v1.ConditionTrue {
-				return true
-			}
-		}
-	}
-	return false
+func crdReadyV1(c *v1.CustomResourceDefinition) bool {
+	return crd.IsEstablished(c)
 }
 
 func (r *GenericResourceReconciler) resourceDetails(desired runtime.Object) ([]interface{}, schema.GroupVersionKind, error) {
@@ -19,6 +19,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 
+	"github.com/cisco-open/operator-tools/pkg/resources"
 	"github.com/cisco-open/operator-tools/pkg/types"
 )
 
@@ -62,3 +63,9 @@ func KeepServiceAccountTokenReferences(current, desired runtime.Object) error {
 
 	return nil
 }
+
+// KeepCABundleModifier keeps the live CA bundles of webhook configurations, CRD conversion webhooks and APIServices
+// when the desired object has none or when the CA bundle is injected externally, see resources.KeepCABundles
+func KeepCABundleModifier(current, desired runtime.Object) error {
+	return resources.KeepCABundles(current, desired)
+}
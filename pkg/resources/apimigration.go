@@ -0,0 +1,410 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"strings"
+
+	"emperror.dev/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/cisco-open/operator-tools/pkg/utils"
+)
+
+// APIMigration rewrites objects of a deprecated API version to a newer version
+type APIMigration struct {
+	From schema.GroupVersionKind
+	// To lists the replacement versions from the newest to the oldest, the newest one served by the cluster is used.
+	// Objects of APIs without replacement are removed once the cluster stops serving them.
+	To []schema.GroupVersion
+	// Convert adapts the unstructured content of the object to the schema of the target version, if they differ
+	Convert func(content map[string]interface{}, to schema.GroupVersion) error
+}
+
+// DefaultAPIMigrations cover the APIs removed from Kubernetes between 1.22 and 1.29
+var DefaultAPIMigrations = []APIMigration{
+	{
+		From:    schema.GroupVersionKind{Group: "autoscaling", Version: "v2beta1", Kind: "HorizontalPodAutoscaler"},
+		To:      []schema.GroupVersion{{Group: "autoscaling", Version: "v2"}, {Group: "autoscaling", Version: "v2beta2"}},
+		Convert: convertHPAFromV2beta1,
+	},
+	{
+		From: schema.GroupVersionKind{Group: "autoscaling", Version: "v2beta2", Kind: "HorizontalPodAutoscaler"},
+		To:   []schema.GroupVersion{{Group: "autoscaling", Version: "v2"}},
+	},
+	{
+		From:    schema.GroupVersionKind{Group: "policy", Version: "v1beta1", Kind: "PodDisruptionBudget"},
+		To:      []schema.GroupVersion{{Group: "policy", Version: "v1"}},
+		Convert: convertPDBFromV1beta1,
+	},
+	{
+		From:    schema.GroupVersionKind{Group: "extensions", Version: "v1beta1", Kind: "Ingress"},
+		To:      []schema.GroupVersion{{Group: "networking.k8s.io", Version: "v1"}, {Group: "networking.k8s.io", Version: "v1beta1"}},
+		Convert: convertIngressFromV1beta1,
+	},
+	{
+		From:    schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1beta1", Kind: "Ingress"},
+		To:      []schema.GroupVersion{{Group: "networking.k8s.io", Version: "v1"}},
+		Convert: convertIngressFromV1beta1,
+	},
+	{
+		From: schema.GroupVersionKind{Group: "batch", Version: "v1beta1", Kind: "CronJob"},
+		To:   []schema.GroupVersion{{Group: "batch", Version: "v1"}},
+	},
+	{
+		From: schema.GroupVersionKind{Group: "policy", Version: "v1beta1", Kind: "PodSecurityPolicy"},
+	},
+	{
+		From: schema.GroupVersionKind{Group: "extensions", Version: "v1beta1", Kind: "PodSecurityPolicy"},
+	},
+	{
+		From: schema.GroupVersionKind{Group: "flowcontrol.apiserver.k8s.io", Version: "v1beta1", Kind: "FlowSchema"},
+		To:   flowControlVersions("v1", "v1beta3", "v1beta2"),
+	},
+	{
+		From: schema.GroupVersionKind{Group: "flowcontrol.apiserver.k8s.io", Version: "v1beta2", Kind: "FlowSchema"},
+		To:   flowControlVersions("v1", "v1beta3"),
+	},
+	{
+		From: schema.GroupVersionKind{Group: "flowcontrol.apiserver.k8s.io", Version: "v1beta3", Kind: "FlowSchema"},
+		To:   flowControlVersions("v1"),
+	},
+	{
+		From:    schema.GroupVersionKind{Group: "flowcontrol.apiserver.k8s.io", Version: "v1beta1", Kind: "PriorityLevelConfiguration"},
+		To:      flowControlVersions("v1", "v1beta3", "v1beta2"),
+		Convert: convertPriorityLevelConfiguration,
+	},
+	{
+		From:    schema.GroupVersionKind{Group: "flowcontrol.apiserver.k8s.io", Version: "v1beta2", Kind: "PriorityLevelConfiguration"},
+		To:      flowControlVersions("v1", "v1beta3"),
+		Convert: convertPriorityLevelConfiguration,
+	},
+	{
+		From: schema.GroupVersionKind{Group: "flowcontrol.apiserver.k8s.io", Version: "v1beta3", Kind: "PriorityLevelConfiguration"},
+		To:   flowControlVersions("v1"),
+	},
+}
+
+func flowControlVersions(versions ...string) []schema.GroupVersion {
+	gvs := make([]schema.GroupVersion, 0, len(versions))
+	for _, v := range versions {
+		gvs = append(gvs, schema.GroupVersion{Group: "flowcontrol.apiserver.k8s.io", Version: v})
+	}
+	return gvs
+}
+
+// APIMigrationReport lists the objects rewritten or removed by API migrations.
+// It can be embedded into the status of CRDs.
+// +kubebuilder:object:generate=true
+type APIMigrationReport struct {
+	Rewrites []APIRewrite `json:"rewrites,omitempty"`
+}
+
+// APIRewrite describes the migration of a single object
+type APIRewrite struct {
+	// Object in `Kind.group:namespace/name` format, using the original API group
+	Object string `json:"object"`
+	From   string `json:"from"`
+	// To is empty if the object has been removed
+	To      string `json:"to,omitempty"`
+	Removed bool   `json:"removed,omitempty"`
+}
+
+// APIMigrator rewrites objects of deprecated APIs to the newest API version served by the cluster
+type APIMigrator struct {
+	served     []string
+	parser     *ObjectParser
+	migrations map[schema.GroupVersionKind]APIMigration
+}
+
+// NewAPIMigrator creates a migrator for the API versions served by the cluster, e.g. the APIVersions of Helm capabilities.
+// Objects are not migrated if the served versions are unknown.
+// DefaultAPIMigrations are used if no migrations are given.
+func NewAPIMigrator(served []string, parser *ObjectParser, migrations ...APIMigration) *APIMigrator {
+	if len(migrations) == 0 {
+		migrations = DefaultAPIMigrations
+	}
+
+	m := &APIMigrator{
+		served:     served,
+		parser:     parser,
+		migrations: make(map[schema.GroupVersionKind]APIMigration, len(migrations)),
+	}
+	for _, migration := range migrations {
+		m.migrations[migration.From] = migration
+	}
+
+	return m
+}
+
+// Migrate returns the object rewritten to the target API version along with the description of the rewrite,
+// or a nil object if the API has been removed without replacement. Objects that need no migration are returned as is.
+func (m *APIMigrator) Migrate(o runtime.Object) (runtime.Object, *APIRewrite, error) {
+	gvk := o.GetObjectKind().GroupVersionKind()
+	migration, ok := m.migrations[gvk]
+	if !ok {
+		return o, nil, nil
+	}
+
+	to, ok := m.target(migration)
+	if !ok {
+		if len(migration.To) == 0 && len(m.served) > 0 && !m.isServed(gvk.GroupVersion()) {
+			return nil, &APIRewrite{Object: objectRef(o), From: gvk.GroupVersion().String(), Removed: true}, nil
+		}
+		return o, nil, nil
+	}
+	if to == gvk.GroupVersion() {
+		return o, nil, nil
+	}
+
+	content, err := toUnstructuredContent(o)
+	if err != nil {
+		return nil, nil, err
+	}
+	if migration.Convert != nil {
+		if err := migration.Convert(content, to); err != nil {
+			return nil, nil, errors.WrapIfWithDetails(err, "could not convert object", "object", objectRef(o), "from", gvk.GroupVersion().String(), "to", to.String())
+		}
+	}
+	u := &unstructured.Unstructured{Object: content}
+	u.SetAPIVersion(to.String())
+
+	migrated, err := m.parser.fromUnstructuredContent(u.Object)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return migrated, &APIRewrite{Object: objectRef(o), From: gvk.GroupVersion().String(), To: to.String()}, nil
+}
+
+// MigrateObjects migrates all the objects, leaving out the ones of removed APIs
+func (m *APIMigrator) MigrateObjects(objects []runtime.Object) ([]runtime.Object, *APIMigrationReport, error) {
+	report := &APIMigrationReport{}
+	migrated := make([]runtime.Object, 0, len(objects))
+	for _, o := range objects {
+		o, rewrite, err := m.Migrate(o)
+		if err != nil {
+			return nil, nil, err
+		}
+		if rewrite != nil {
+			report.Rewrites = append(report.Rewrites, *rewrite)
+		}
+		if o != nil {
+			migrated = append(migrated, o)
+		}
+	}
+
+	return migrated, report, nil
+}
+
+// APIMigrationModifier migrates objects one by one, the returned report is filled as the modifier is called.
+// Objects of removed APIs can not be left out by a modifier, they result in an error instead.
+func APIMigrationModifier(served []string, parser *ObjectParser, migrations ...APIMigration) (ObjectModifierFunc, *APIMigrationReport) {
+	m := NewAPIMigrator(served, parser, migrations...)
+	report := &APIMigrationReport{}
+
+	return func(o runtime.Object) (runtime.Object, error) {
+		migrated, rewrite, err := m.Migrate(o)
+		if err != nil {
+			return o, err
+		}
+		if rewrite == nil {
+			return migrated, nil
+		}
+		report.Rewrites = append(report.Rewrites, *rewrite)
+		if rewrite.Removed {
+			return o, errors.Errorf("%s is no longer served by the cluster", rewrite.Object)
+		}
+		return migrated, nil
+	}, report
+}
+
+func (m *APIMigrator) target(migration APIMigration) (schema.GroupVersion, bool) {
+	// objects are left as they are if the served versions are unknown, the replacements may not be served by the cluster yet
+	if len(migration.To) == 0 || len(m.served) == 0 {
+		return schema.GroupVersion{}, false
+	}
+	for _, gv := range migration.To {
+		if m.isServed(gv) {
+			return gv, true
+		}
+	}
+	return schema.GroupVersion{}, false
+}
+
+func (m *APIMigrator) isServed(gv schema.GroupVersion) bool {
+	return utils.Contains(m.served, gv.String())
+}
+
+// convertHPAFromV2beta1 converts the metric specs to the target based format of autoscaling/v2beta2 and autoscaling/v2
+func convertHPAFromV2beta1(content map[string]interface{}, _ schema.GroupVersion) error {
+	metrics, ok, err := unstructured.NestedSlice(content, "spec", "metrics")
+	if err != nil || !ok {
+		return err
+	}
+
+	for _, metric := range metrics {
+		metric, ok := metric.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		metricType, _ := metric["type"].(string)
+		if metricType == "" {
+			continue
+		}
+		// the source of the metric is stored under the type name, e.g. containerResource for ContainerResource
+		source, ok := metric[strings.ToLower(metricType[:1])+metricType[1:]].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		target := map[string]interface{}{}
+		moveField(source, "targetAverageUtilization", target, "averageUtilization")
+		moveField(source, "targetAverageValue", target, "averageValue")
+		moveField(source, "averageValue", target, "averageValue")
+		moveField(source, "targetValue", target, "value")
+		switch {
+		case target["averageUtilization"] != nil:
+			target["type"] = "Utilization"
+		case target["averageValue"] != nil:
+			target["type"] = "AverageValue"
+		default:
+			target["type"] = "Value"
+		}
+
+		switch metricType {
+		case "Object", "Pods", "External":
+			identifier := map[string]interface{}{}
+			moveField(source, "metricName", identifier, "name")
+			moveField(source, "selector", identifier, "selector")
+			moveField(source, "metricSelector", identifier, "selector")
+			source["metric"] = identifier
+			moveField(source, "target", source, "describedObject")
+		}
+		source["target"] = target
+	}
+
+	return unstructured.SetNestedSlice(content, metrics, "spec", "metrics")
+}
+
+// convertPDBFromV1beta1 keeps the meaning of empty selectors, which match no pods in policy/v1beta1 but all pods in policy/v1,
+// using the same selector the API server uses for the conversion
+func convertPDBFromV1beta1(content map[string]interface{}, _ schema.GroupVersion) error {
+	selector, ok, err := unstructured.NestedMap(content, "spec", "selector")
+	if err != nil || !ok || len(selector) > 0 {
+		return err
+	}
+
+	return unstructured.SetNestedSlice(content, []interface{}{
+		map[string]interface{}{
+			"key":      "pdb.kubernetes.io/deprecated-v1beta1-empty-selector-match",
+			"operator": "Exists",
+		},
+	}, "spec", "selector", "matchExpressions")
+}
+
+// convertIngressFromV1beta1 converts service backends and sets the path type required by networking.k8s.io/v1
+func convertIngressFromV1beta1(content map[string]interface{}, to schema.GroupVersion) error {
+	if to.Version != "v1" {
+		return nil
+	}
+
+	spec, ok, err := unstructured.NestedMap(content, "spec")
+	if err != nil || !ok {
+		return err
+	}
+
+	moveField(spec, "backend", spec, "defaultBackend")
+	if backend, ok := spec["defaultBackend"].(map[string]interface{}); ok {
+		convertIngressBackend(backend)
+	}
+
+	rules, _ := spec["rules"].([]interface{})
+	for _, rule := range rules {
+		rule, ok := rule.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		paths, _, _ := unstructured.NestedSlice(rule, "http", "paths")
+		for _, path := range paths {
+			path, ok := path.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if _, ok := path["pathType"]; !ok {
+				path["pathType"] = "ImplementationSpecific"
+			}
+			if backend, ok := path["backend"].(map[string]interface{}); ok {
+				convertIngressBackend(backend)
+			}
+		}
+		if len(paths) > 0 {
+			if err := unstructured.SetNestedSlice(rule, paths, "http", "paths"); err != nil {
+				return err
+			}
+		}
+	}
+
+	return unstructured.SetNestedMap(content, spec, "spec")
+}
+
+func convertIngressBackend(backend map[string]interface{}) {
+	name, hasName := backend["serviceName"]
+	port, hasPort := backend["servicePort"]
+	if !hasName && !hasPort {
+		return
+	}
+	delete(backend, "serviceName")
+	delete(backend, "servicePort")
+
+	service := map[string]interface{}{"name": name}
+	switch port := port.(type) {
+	case string:
+		service["port"] = map[string]interface{}{"name": port}
+	case nil:
+	default:
+		service["port"] = map[string]interface{}{"number": port}
+	}
+	backend["service"] = service
+}
+
+// convertPriorityLevelConfiguration renames assuredConcurrencyShares to nominalConcurrencyShares as of flowcontrol v1beta3
+func convertPriorityLevelConfiguration(content map[string]interface{}, to schema.GroupVersion) error {
+	if to.Version == "v1beta2" {
+		return nil
+	}
+
+	limited, ok, err := unstructured.NestedMap(content, "spec", "limited")
+	if err != nil || !ok {
+		return err
+	}
+	moveField(limited, "assuredConcurrencyShares", limited, "nominalConcurrencyShares")
+
+	return unstructured.SetNestedMap(content, limited, "spec", "limited")
+}
+
+// moveField moves a field between maps unless the destination is already set
+func moveField(from map[string]interface{}, fromKey string, to map[string]interface{}, toKey string) {
+	value, ok := from[fromKey]
+	if !ok {
+		return
+	}
+	delete(from, fromKey)
+	if _, ok := to[toKey]; !ok {
+		to[toKey] = value
+	}
+}
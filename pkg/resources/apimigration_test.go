@@ -0,0 +1,239 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	flowcontrolv1 "k8s.io/api/flowcontrol/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+
+	"github.com/cisco-open/operator-tools/pkg/utils"
+)
+
+const deprecatedManifest = `
+apiVersion: autoscaling/v2beta1
+kind: HorizontalPodAutoscaler
+metadata:
+  name: hpa
+spec:
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: app
+  maxReplicas: 5
+  metrics:
+  - type: Resource
+    resource:
+      name: cpu
+      targetAverageUtilization: 80
+  - type: Pods
+    pods:
+      metricName: requests
+      targetAverageValue: "10"
+  - type: Object
+    object:
+      target:
+        apiVersion: networking.k8s.io/v1
+        kind: Ingress
+        name: main
+      metricName: hits
+      targetValue: "100"
+---
+apiVersion: policy/v1beta1
+kind: PodDisruptionBudget
+metadata:
+  name: pdb
+spec:
+  minAvailable: 1
+  selector: {}
+---
+apiVersion: extensions/v1beta1
+kind: Ingress
+metadata:
+  name: ingress
+spec:
+  backend:
+    serviceName: default
+    servicePort: 80
+  rules:
+  - host: example.com
+    http:
+      paths:
+      - path: /
+        backend:
+          serviceName: app
+          servicePort: http
+---
+apiVersion: batch/v1beta1
+kind: CronJob
+metadata:
+  name: cronjob
+spec:
+  schedule: "* * * * *"
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: job
+            image: busybox
+---
+apiVersion: policy/v1beta1
+kind: PodSecurityPolicy
+metadata:
+  name: psp
+---
+apiVersion: flowcontrol.apiserver.k8s.io/v1beta2
+kind: PriorityLevelConfiguration
+metadata:
+  name: plc
+spec:
+  type: Limited
+  limited:
+    assuredConcurrencyShares: 10
+    limitResponse:
+      type: Reject
+`
+
+func TestAPIMigrator(t *testing.T) {
+	parser := NewObjectParser(clientgoscheme.Scheme)
+	objects, err := parser.ParseYAMLManifest(deprecatedManifest)
+	require.NoError(t, err)
+
+	served := []string{"v1", "autoscaling/v2", "policy/v1", "networking.k8s.io/v1", "batch/v1", "flowcontrol.apiserver.k8s.io/v1"}
+	migrated, report, err := NewAPIMigrator(served, parser).MigrateObjects(objects)
+	require.NoError(t, err)
+	require.Len(t, migrated, 5)
+
+	assert.Equal(t, []APIRewrite{
+		{Object: "HorizontalPodAutoscaler.autoscaling:hpa", From: "autoscaling/v2beta1", To: "autoscaling/v2"},
+		{Object: "PodDisruptionBudget.policy:pdb", From: "policy/v1beta1", To: "policy/v1"},
+		{Object: "Ingress.extensions:ingress", From: "extensions/v1beta1", To: "networking.k8s.io/v1"},
+		{Object: "CronJob.batch:cronjob", From: "batch/v1beta1", To: "batch/v1"},
+		{Object: "PodSecurityPolicy.policy:psp", From: "policy/v1beta1", Removed: true},
+		{Object: "PriorityLevelConfiguration.flowcontrol.apiserver.k8s.io:plc", From: "flowcontrol.apiserver.k8s.io/v1beta2", To: "flowcontrol.apiserver.k8s.io/v1"},
+	}, report.Rewrites)
+
+	hpa := migrated[0].(*autoscalingv2.HorizontalPodAutoscaler)
+	assert.Equal(t, []autoscalingv2.MetricSpec{
+		{
+			Type: autoscalingv2.ResourceMetricSourceType,
+			Resource: &autoscalingv2.ResourceMetricSource{
+				Name:   "cpu",
+				Target: autoscalingv2.MetricTarget{Type: autoscalingv2.UtilizationMetricType, AverageUtilization: utils.IntPointer(80)},
+			},
+		},
+		{
+			Type: autoscalingv2.PodsMetricSourceType,
+			Pods: &autoscalingv2.PodsMetricSource{
+				Metric: autoscalingv2.MetricIdentifier{Name: "requests"},
+				Target: autoscalingv2.MetricTarget{Type: autoscalingv2.AverageValueMetricType, AverageValue: quantity("10")},
+			},
+		},
+		{
+			Type: autoscalingv2.ObjectMetricSourceType,
+			Object: &autoscalingv2.ObjectMetricSource{
+				DescribedObject: autoscalingv2.CrossVersionObjectReference{APIVersion: "networking.k8s.io/v1", Kind: "Ingress", Name: "main"},
+				Metric:          autoscalingv2.MetricIdentifier{Name: "hits"},
+				Target:          autoscalingv2.MetricTarget{Type: autoscalingv2.ValueMetricType, Value: quantity("100")},
+			},
+		},
+	}, hpa.Spec.Metrics)
+
+	pdb := migrated[1].(*policyv1.PodDisruptionBudget)
+	assert.Equal(t, &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+		{Key: "pdb.kubernetes.io/deprecated-v1beta1-empty-selector-match", Operator: metav1.LabelSelectorOpExists},
+	}}, pdb.Spec.Selector)
+
+	ingress := migrated[2].(*networkingv1.Ingress)
+	assert.Equal(t, &networkingv1.IngressBackend{
+		Service: &networkingv1.IngressServiceBackend{Name: "default", Port: networkingv1.ServiceBackendPort{Number: 80}},
+	}, ingress.Spec.DefaultBackend)
+	path := ingress.Spec.Rules[0].HTTP.Paths[0]
+	assert.Equal(t, networkingv1.PathTypeImplementationSpecific, *path.PathType)
+	assert.Equal(t, &networkingv1.IngressServiceBackend{Name: "app", Port: networkingv1.ServiceBackendPort{Name: "http"}}, path.Backend.Service)
+
+	assert.IsType(t, &batchv1.CronJob{}, migrated[3])
+
+	plc := migrated[4].(*flowcontrolv1.PriorityLevelConfiguration)
+	assert.Equal(t, utils.IntPointer(10), plc.Spec.Limited.NominalConcurrencyShares)
+}
+
+func TestConvertIngressFromV1beta1MalformedRules(t *testing.T) {
+	content := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"rules": []interface{}{
+				nil,
+				"invalid",
+				map[string]interface{}{"http": map[string]interface{}{"paths": []interface{}{
+					map[string]interface{}{"backend": map[string]interface{}{"serviceName": "app", "servicePort": int64(80)}},
+				}}},
+			},
+		},
+	}
+
+	require.NoError(t, convertIngressFromV1beta1(content, networkingv1.SchemeGroupVersion))
+	rules := content["spec"].(map[string]interface{})["rules"].([]interface{})
+	assert.Equal(t, []interface{}{nil, "invalid"}, rules[:2])
+	assert.Equal(t, map[string]interface{}{"http": map[string]interface{}{"paths": []interface{}{
+		map[string]interface{}{
+			"pathType": "ImplementationSpecific",
+			"backend":  map[string]interface{}{"service": map[string]interface{}{"name": "app", "port": map[string]interface{}{"number": int64(80)}}},
+		},
+	}}}, rules[2])
+}
+
+func quantity(s string) *resource.Quantity {
+	q := resource.MustParse(s)
+	// populates the cached string representation, like unmarshaling does
+	_ = q.String()
+	return &q
+}
+
+func TestAPIMigratorServedVersions(t *testing.T) {
+	parser := NewObjectParser(clientgoscheme.Scheme)
+	objects, err := parser.ParseYAMLManifest(deprecatedManifest)
+	require.NoError(t, err)
+
+	// an old cluster still serving the deprecated versions only gets the rewrites it supports
+	served := []string{"v1", "autoscaling/v2beta1", "autoscaling/v2beta2", "policy/v1beta1", "extensions/v1beta1", "networking.k8s.io/v1beta1", "batch/v1beta1", "flowcontrol.apiserver.k8s.io/v1beta2"}
+	migrated, report, err := NewAPIMigrator(served, parser).MigrateObjects(objects)
+	require.NoError(t, err)
+	assert.Len(t, migrated, 6)
+	assert.Equal(t, []APIRewrite{
+		{Object: "HorizontalPodAutoscaler.autoscaling:hpa", From: "autoscaling/v2beta1", To: "autoscaling/v2beta2"},
+		{Object: "Ingress.extensions:ingress", From: "extensions/v1beta1", To: "networking.k8s.io/v1beta1"},
+	}, report.Rewrites)
+
+	// the replacements may not be served by clusters of unknown versions
+	migrated, report, err = NewAPIMigrator(nil, parser).MigrateObjects(objects)
+	require.NoError(t, err)
+	assert.Equal(t, objects, migrated)
+	assert.Empty(t, report.Rewrites)
+
+	modifier, modifierReport := APIMigrationModifier([]string{"v1", "policy/v1"}, parser)
+	_, err = modifier(objects[4])
+	assert.EqualError(t, err, "PodSecurityPolicy.policy:psp is no longer served by the cluster")
+	assert.Len(t, modifierReport.Rewrites, 1)
+}
@@ -0,0 +1,170 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"encoding/base64"
+
+	"emperror.dev/errors"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	admissionregistrationv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/cisco-open/operator-tools/pkg/utils"
+)
+
+// Annotations used by the cert-manager CA injector, objects having any of them get their CA bundle injected externally
+var ExternalCAInjectionAnnotations = []string{
+	"cert-manager.io/inject-ca-from",
+	"cert-manager.io/inject-ca-from-secret",
+	"cert-manager.io/inject-apiserver-ca",
+}
+
+// CABundleModifier sets the CA bundle of every webhook of webhook configurations, of CRD conversion webhooks and of APIServices
+func CABundleModifier(caBundle []byte) ObjectModifierFunc {
+	return func(o runtime.Object) (runtime.Object, error) {
+		return o, VisitCABundles(o, func(string, []byte) []byte {
+			return caBundle
+		})
+	}
+}
+
+// KeepCABundles keeps the live CA bundles of webhook configurations, CRD conversion webhooks and APIServices
+// when the desired object has none or when the CA bundle is injected externally, e.g. by the cert-manager CA injector
+func KeepCABundles(current, desired runtime.Object) error {
+	caBundles := make(map[string][]byte)
+	if err := VisitCABundles(current, func(key string, caBundle []byte) []byte {
+		if len(caBundle) > 0 {
+			caBundles[key] = caBundle
+		}
+		return caBundle
+	}); err != nil {
+		return err
+	}
+	if len(caBundles) == 0 {
+		return nil
+	}
+
+	external := false
+	if desiredMetaObject, ok := desired.(metav1.Object); ok {
+		for annotation := range desiredMetaObject.GetAnnotations() {
+			if utils.Contains(ExternalCAInjectionAnnotations, annotation) {
+				external = true
+			}
+		}
+	}
+
+	return VisitCABundles(desired, func(key string, caBundle []byte) []byte {
+		if current, ok := caBundles[key]; ok && (external || len(caBundle) == 0) {
+			return current
+		}
+		return caBundle
+	})
+}
+
+// VisitCABundles calls the function with the CA bundle fields of webhook configurations, CRD conversion webhooks and APIServices
+// and replaces them with the returned values. The key is the webhook name for webhook configurations and empty otherwise.
+// APIServices are only supported as unstructured objects.
+func VisitCABundles(o runtime.Object, fn func(key string, caBundle []byte) []byte) error {
+	switch obj := o.(type) {
+	case *admissionregistrationv1.MutatingWebhookConfiguration:
+		for i := range obj.Webhooks {
+			obj.Webhooks[i].ClientConfig.CABundle = fn(obj.Webhooks[i].Name, obj.Webhooks[i].ClientConfig.CABundle)
+		}
+	case *admissionregistrationv1.ValidatingWebhookConfiguration:
+		for i := range obj.Webhooks {
+			obj.Webhooks[i].ClientConfig.CABundle = fn(obj.Webhooks[i].Name, obj.Webhooks[i].ClientConfig.CABundle)
+		}
+	case *admissionregistrationv1beta1.MutatingWebhookConfiguration:
+		for i := range obj.Webhooks {
+			obj.Webhooks[i].ClientConfig.CABundle = fn(obj.Webhooks[i].Name, obj.Webhooks[i].ClientConfig.CABundle)
+		}
+	case *admissionregistrationv1beta1.ValidatingWebhookConfiguration:
+		for i := range obj.Webhooks {
+			obj.Webhooks[i].ClientConfig.CABundle = fn(obj.Webhooks[i].Name, obj.Webhooks[i].ClientConfig.CABundle)
+		}
+	case *apiextensionsv1.CustomResourceDefinition:
+		if c := obj.Spec.Conversion; c != nil && c.Webhook != nil && c.Webhook.ClientConfig != nil {
+			c.Webhook.ClientConfig.CABundle = fn("", c.Webhook.ClientConfig.CABundle)
+		}
+	case *apiextensionsv1beta1.CustomResourceDefinition:
+		if c := obj.Spec.Conversion; c != nil && c.WebhookClientConfig != nil {
+			c.WebhookClientConfig.CABundle = fn("", c.WebhookClientConfig.CABundle)
+		}
+	case *unstructured.Unstructured:
+		return visitUnstructuredCABundles(obj, fn)
+	}
+
+	return nil
+}
+
+func visitUnstructuredCABundles(u *unstructured.Unstructured, fn func(key string, caBundle []byte) []byte) error {
+	gvk := u.GroupVersionKind()
+	switch {
+	case gvk.Group == admissionregistrationv1.GroupName && (gvk.Kind == "MutatingWebhookConfiguration" || gvk.Kind == "ValidatingWebhookConfiguration"):
+		webhooks, ok, err := unstructured.NestedSlice(u.Object, "webhooks")
+		if err != nil || !ok {
+			return err
+		}
+		for _, webhook := range webhooks {
+			webhook, ok := webhook.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _, _ := unstructured.NestedString(webhook, "name")
+			if err := visitUnstructuredCABundle(webhook, name, fn, "clientConfig", "caBundle"); err != nil {
+				return errors.WrapIfWithDetails(err, "invalid webhook CA bundle", "name", u.GetName(), "webhook", name)
+			}
+		}
+		return unstructured.SetNestedSlice(u.Object, webhooks, "webhooks")
+	case gvk.Group == apiextensionsv1.GroupName && gvk.Kind == "CustomResourceDefinition":
+		path := []string{"spec", "conversion", "webhook", "clientConfig"}
+		if gvk.Version == apiextensionsv1beta1.SchemeGroupVersion.Version {
+			path = []string{"spec", "conversion", "webhookClientConfig"}
+		}
+		if _, ok, _ := unstructured.NestedMap(u.Object, path...); !ok {
+			return nil
+		}
+		return errors.WrapIfWithDetails(visitUnstructuredCABundle(u.Object, "", fn, append(path, "caBundle")...),
+			"invalid conversion webhook CA bundle", "name", u.GetName())
+	case gvk.Group == "apiregistration.k8s.io" && gvk.Kind == "APIService":
+		return errors.WrapIfWithDetails(visitUnstructuredCABundle(u.Object, "", fn, "spec", "caBundle"),
+			"invalid APIService CA bundle", "name", u.GetName())
+	}
+
+	return nil
+}
+
+func visitUnstructuredCABundle(content map[string]interface{}, key string, fn func(key string, caBundle []byte) []byte, path ...string) error {
+	encoded, _, err := unstructured.NestedString(content, path...)
+	if err != nil {
+		return err
+	}
+	caBundle, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return err
+	}
+
+	caBundle = fn(key, caBundle)
+	if len(caBundle) == 0 {
+		unstructured.RemoveNestedField(content, path...)
+		return nil
+	}
+	return unstructured.SetNestedField(content, base64.StdEncoding.EncodeToString(caBundle), path...)
+}
@@ -0,0 +1,109 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestCABundleModifier(t *testing.T) {
+	caBundle := []byte("-----BEGIN CERTIFICATE-----")
+	encoded := base64.StdEncoding.EncodeToString(caBundle)
+
+	t.Run("webhook configuration", func(t *testing.T) {
+		obj := &admissionregistrationv1.ValidatingWebhookConfiguration{
+			Webhooks: []admissionregistrationv1.ValidatingWebhook{{Name: "a"}, {Name: "b"}},
+		}
+		_, err := CABundleModifier(caBundle)(obj)
+		require.NoError(t, err)
+		for _, wh := range obj.Webhooks {
+			assert.Equal(t, caBundle, wh.ClientConfig.CABundle)
+		}
+	})
+
+	t.Run("CRD without conversion webhook", func(t *testing.T) {
+		obj := &apiextensionsv1.CustomResourceDefinition{}
+		_, err := CABundleModifier(caBundle)(obj)
+		require.NoError(t, err)
+		assert.Nil(t, obj.Spec.Conversion)
+	})
+
+	t.Run("CRD with conversion webhook", func(t *testing.T) {
+		obj := &apiextensionsv1.CustomResourceDefinition{
+			Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+				Conversion: &apiextensionsv1.CustomResourceConversion{
+					Strategy: apiextensionsv1.WebhookConverter,
+					Webhook:  &apiextensionsv1.WebhookConversion{ClientConfig: &apiextensionsv1.WebhookClientConfig{}},
+				},
+			},
+		}
+		_, err := CABundleModifier(caBundle)(obj)
+		require.NoError(t, err)
+		assert.Equal(t, caBundle, obj.Spec.Conversion.Webhook.ClientConfig.CABundle)
+	})
+
+	t.Run("unstructured", func(t *testing.T) {
+		webhooks := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "admissionregistration.k8s.io/v1",
+			"kind":       "MutatingWebhookConfiguration",
+			"webhooks": []interface{}{
+				map[string]interface{}{"name": "a", "clientConfig": map[string]interface{}{}},
+			},
+		}}
+		crd := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "apiextensions.k8s.io/v1",
+			"kind":       "CustomResourceDefinition",
+			"spec": map[string]interface{}{
+				"conversion": map[string]interface{}{
+					"strategy": "Webhook",
+					"webhook":  map[string]interface{}{"clientConfig": map[string]interface{}{}},
+				},
+			},
+		}}
+		apiService := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "apiregistration.k8s.io/v1",
+			"kind":       "APIService",
+			"spec":       map[string]interface{}{"caBundle": "aW52YWxpZA=="},
+		}}
+
+		for _, obj := range []*unstructured.Unstructured{webhooks, crd, apiService} {
+			_, err := CABundleModifier(caBundle)(obj)
+			require.NoError(t, err)
+		}
+
+		assert.Equal(t, encoded, webhooks.Object["webhooks"].([]interface{})[0].(map[string]interface{})["clientConfig"].(map[string]interface{})["caBundle"])
+		value, _, _ := unstructured.NestedString(crd.Object, "spec", "conversion", "webhook", "clientConfig", "caBundle")
+		assert.Equal(t, encoded, value)
+		value, _, _ = unstructured.NestedString(apiService.Object, "spec", "caBundle")
+		assert.Equal(t, encoded, value)
+	})
+
+	t.Run("invalid unstructured CA bundle", func(t *testing.T) {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "apiregistration.k8s.io/v1",
+			"kind":       "APIService",
+			"spec":       map[string]interface{}{"caBundle": "not base64!"},
+		}}
+		_, err := CABundleModifier(caBundle)(obj)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,230 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"emperror.dev/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+// ManifestDocument is a single document of a multi-document YAML manifest
+type ManifestDocument struct {
+	// Index of the document in the manifest, starting from 0
+	Index int
+	// Line of the manifest the document starts at, starting from 1
+	Line int
+	Data []byte
+}
+
+// ManifestReader splits a multi-document YAML manifest into documents without loading the whole manifest into memory.
+// It is built on the YAML reader of apimachinery: documents are separated by `---` lines, optionally followed
+// by a comment, and line endings are normalized to `\n`. Empty documents are skipped.
+type ManifestReader struct {
+	lines  *manifestLines
+	reader *utilyaml.YAMLReader
+}
+
+func NewManifestReader(r io.Reader) *ManifestReader {
+	lines := &manifestLines{reader: bufio.NewReader(r)}
+	return &ManifestReader{lines: lines, reader: utilyaml.NewYAMLReader(bufio.NewReader(lines))}
+}
+
+// Read returns the next document of the manifest or io.EOF if there are no more documents
+func (r *ManifestReader) Read() (*ManifestDocument, error) {
+	data, err := r.reader.Read()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, errors.WrapIff(err, "could not read manifest at line %d", r.lines.count)
+	}
+
+	// the YAML reader has consumed the lines of the document and the separator ending it, if any
+	doc := &ManifestDocument{Index: r.lines.separators, Line: r.lines.count - bytes.Count(data, []byte("\n")) + 1, Data: data}
+	if r.lines.separator {
+		doc.Index--
+		doc.Line--
+	}
+	return doc, nil
+}
+
+// manifestLines hands out the manifest at most one line at a time, so that the lines read from it are the lines
+// the YAML reader has consumed, which is what the document index and line numbers are counted from
+type manifestLines struct {
+	reader  *bufio.Reader
+	pending []byte
+	// count of the lines read, separators among them and whether the last one was a separator
+	count      int
+	separators int
+	separator  bool
+}
+
+func (l *manifestLines) Read(p []byte) (int, error) {
+	if len(l.pending) == 0 {
+		// ReadBytes does not limit the length of lines, unlike bufio.Scanner
+		line, err := l.reader.ReadBytes('\n')
+		if len(line) == 0 {
+			return 0, err
+		}
+		l.count++
+		l.separator = isYAMLSeparator(strings.TrimRight(string(line), "\r\n"))
+		if l.separator {
+			l.separators++
+		}
+		l.pending = line
+	}
+	n := copy(p, l.pending)
+	l.pending = l.pending[n:]
+	return n, nil
+}
+
+// isYAMLSeparator accepts document separators followed by whitespace or a comment, e.g. `--- # Source: templates/service.yaml`
+func isYAMLSeparator(line string) bool {
+	if !strings.HasPrefix(line, YAMLSeparator) {
+		return false
+	}
+	rest := strings.TrimSpace(line[len(YAMLSeparator):])
+	return rest == "" || strings.HasPrefix(rest, "#")
+}
+
+// ManifestDocumentError is a document of a manifest that could not be parsed
+type ManifestDocumentError struct {
+	Index int
+	Line  int
+	Err   error
+}
+
+func (e *ManifestDocumentError) Error() string {
+	return fmt.Sprintf("document #%d at line %d: %s", e.Index, e.Line, e.Err)
+}
+
+func (e *ManifestDocumentError) Unwrap() error {
+	return e.Err
+}
+
+// ManifestError aggregates the errors of all the documents of a manifest that could not be parsed
+type ManifestError struct {
+	Documents []*ManifestDocumentError
+}
+
+func (e *ManifestError) Error() string {
+	msgs := make([]string, 0, len(e.Documents))
+	for _, d := range e.Documents {
+		msgs = append(msgs, d.Error())
+	}
+	return fmt.Sprintf("failed to parse %d manifest document(s): %s", len(e.Documents), strings.Join(msgs, "; "))
+}
+
+// Unwrap makes the document errors available through errors.GetErrors and errors.As
+func (e *ManifestError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Documents))
+	for _, d := range e.Documents {
+		errs = append(errs, d)
+	}
+	return errs
+}
+
+// DecodeYAMLManifest parses the documents of a YAML or JSON manifest one at a time and calls the function with every resulting object.
+// Items of `List` kinds are returned as separate objects, empty and comment-only documents are skipped.
+// Documents that fail to parse are only logged, or collected into a ManifestError if the parser is strict.
+// Errors returned by the function stop the parsing immediately.
+func (p *ObjectParser) DecodeYAMLManifest(r io.Reader, fn func(o runtime.Object) error, modifiers ...YAMLModifierFuncs) error {
+	reader := NewManifestReader(r)
+	manifestErr := &ManifestError{}
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		objects, err := p.parseManifestDocument(doc.Data, modifiers...)
+		if err != nil {
+			docErr := &ManifestDocumentError{Index: doc.Index, Line: doc.Line, Err: err}
+			if !p.strict {
+				log.Error(docErr, "failed to parse YAML to a k8s object")
+				continue
+			}
+			manifestErr.Documents = append(manifestErr.Documents, docErr)
+			continue
+		}
+
+		for _, o := range objects {
+			if err := fn(o); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(manifestErr.Documents) > 0 {
+		return manifestErr
+	}
+	return nil
+}
+
+func (p *ObjectParser) parseManifestDocument(data []byte, modifiers ...YAMLModifierFuncs) ([]runtime.Object, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+
+	// JSON documents are valid YAML as well
+	jsonData, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, errors.WrapIf(err, "invalid YAML")
+	}
+
+	var content map[string]interface{}
+	if err := json.Unmarshal(jsonData, &content); err != nil {
+		return nil, errors.WrapIf(err, "document is not an object")
+	}
+	if content == nil {
+		return nil, nil
+	}
+
+	items, isList := content["items"].([]interface{})
+	if kind, _ := content["kind"].(string); !isList || !strings.HasSuffix(kind, "List") {
+		o, err := p.ParseYAMLToK8sObject(data, modifiers...)
+		if err != nil {
+			return nil, err
+		}
+		return []runtime.Object{o}, nil
+	}
+
+	objects := make([]runtime.Object, 0, len(items))
+	for i, item := range items {
+		itemData, err := yaml.Marshal(item)
+		if err != nil {
+			return nil, errors.WrapIff(err, "invalid list item #%d", i)
+		}
+		o, err := p.ParseYAMLToK8sObject(itemData, modifiers...)
+		if err != nil {
+			return nil, errors.WrapIff(err, "invalid list item #%d", i)
+		}
+		objects = append(objects, o)
+	}
+
+	return objects, nil
+}
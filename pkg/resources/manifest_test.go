@@ -0,0 +1,118 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"strings"
+	"testing"
+
+	"emperror.dev/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+)
+
+func TestParseYAMLManifest(t *testing.T) {
+	huge := strings.Repeat("x", 1024*1024)
+	manifest := strings.Join([]string{
+		"# Source: chart/templates/a.yaml",
+		"apiVersion: v1",
+		"kind: ConfigMap",
+		"metadata:",
+		"  name: a",
+		"--- # Source: chart/templates/b.yaml",
+		"apiVersion: v1\r",
+		"kind: ConfigMap\r",
+		"metadata:\r",
+		"  name: b\r",
+		"data:\r",
+		"  huge: " + huge + "\r",
+		"---",
+		"# only a comment",
+		"---",
+		`{"apiVersion": "v1", "kind": "Service", "metadata": {"name": "c"}}`,
+		"---",
+		"apiVersion: v1",
+		"kind: List",
+		"items:",
+		"- apiVersion: v1",
+		"  kind: ServiceAccount",
+		"  metadata:",
+		"    name: d",
+		"- apiVersion: example.com/v1",
+		"  kind: Custom",
+		"  metadata:",
+		"    name: e",
+		"---",
+		"---",
+	}, "\n")
+
+	objects, err := NewObjectParser(clientgoscheme.Scheme).ParseYAMLManifest(manifest)
+	require.NoError(t, err)
+	require.Len(t, objects, 5)
+
+	assert.Equal(t, "a", objects[0].(*corev1.ConfigMap).Name)
+	assert.Equal(t, huge, objects[1].(*corev1.ConfigMap).Data["huge"])
+	assert.Equal(t, "c", objects[2].(*corev1.Service).Name)
+	assert.Equal(t, "d", objects[3].(*corev1.ServiceAccount).Name)
+	assert.Equal(t, "e", objects[4].(*unstructured.Unstructured).GetName())
+}
+
+func TestParseYAMLManifestErrors(t *testing.T) {
+	manifest := strings.Join([]string{
+		"apiVersion: v1",
+		"kind: ConfigMap",
+		"metadata:",
+		"  name: a",
+		"---",
+		"apiVersion: v1",
+		"kind: ConfigMap",
+		"  metadata: [",
+		"---",
+		"apiVersion: v1",
+		"kind: ConfigMap",
+		"metadata:",
+		"  name: b",
+		"---",
+		"- not an object",
+	}, "\n")
+
+	objects, err := NewObjectParser(clientgoscheme.Scheme, WithStrictParsing()).ParseYAMLManifest(manifest)
+	require.Error(t, err)
+	assert.Len(t, objects, 2)
+
+	var manifestErr *ManifestError
+	require.True(t, errors.As(err, &manifestErr))
+	require.Len(t, manifestErr.Documents, 2)
+	assert.Equal(t, 1, manifestErr.Documents[0].Index)
+	assert.Equal(t, 6, manifestErr.Documents[0].Line)
+	assert.Equal(t, 3, manifestErr.Documents[1].Index)
+	assert.Equal(t, 15, manifestErr.Documents[1].Line)
+	assert.Len(t, errors.GetErrors(err), 2)
+
+	objects, err = NewObjectParser(clientgoscheme.Scheme).ParseYAMLManifest(manifest)
+	require.NoError(t, err)
+	assert.Len(t, objects, 2)
+}
+
+func TestManifestReaderInvalidSeparator(t *testing.T) {
+	reader := NewManifestReader(strings.NewReader("a: 1\n---b: 2\n"))
+
+	_, err := reader.Read()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 2")
+}
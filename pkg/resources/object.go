@@ -15,7 +15,6 @@
 package resources
 
 import (
-	"bufio"
 	"bytes"
 	"strings"
 
@@ -24,7 +23,6 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/runtime/serializer/json"
 	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
 
 	"github.com/cisco-open/operator-tools/pkg/logger"
@@ -76,69 +74,71 @@ func isValid(o runtime.Object) bool {
 	return false
 }
 
+// ParsingMode controls whether the ObjectParser produces typed or unstructured objects
+type ParsingMode int
+
+const (
+	// TypedWithUnstructuredFallback parses kinds known by the scheme into their Go types and everything else into unstructured objects
+	TypedWithUnstructuredFallback ParsingMode = iota
+	// TypedOnly parses kinds known by the scheme into their Go types and fails on everything else
+	TypedOnly
+	// UnstructuredOnly parses every object into an unstructured object regardless of the scheme
+	UnstructuredOnly
+)
+
 type ObjectParser struct {
 	scheme *runtime.Scheme
+	mode   ParsingMode
+	strict bool
 }
 
-func NewObjectParser(scheme *runtime.Scheme) *ObjectParser {
-	return &ObjectParser{
-		scheme: scheme,
+type ObjectParserOption func(*ObjectParser)
+
+// WithStrictParsing makes the parser report the manifest documents that fail to parse instead of only logging them
+func WithStrictParsing() ObjectParserOption {
+	return func(p *ObjectParser) {
+		p.strict = true
 	}
 }
 
-func (p *ObjectParser) ParseYAMLManifest(manifest string, modifiers ...YAMLModifierFuncs) ([]runtime.Object, error) {
-	var b bytes.Buffer
-
-	var yamls []string
-	scanner := bufio.NewScanner(strings.NewReader(manifest))
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.TrimSpace(line) == YAMLSeparator {
-			yamls = append(yamls, b.String())
-			b.Reset()
-		} else {
-			if _, err := b.WriteString(line); err != nil {
-				return nil, err
-			}
-			if _, err := b.WriteString("\n"); err != nil {
-				return nil, err
-			}
-		}
+// WithParsingMode sets whether the parser produces typed or unstructured objects, TypedWithUnstructuredFallback by default
+func WithParsingMode(mode ParsingMode) ObjectParserOption {
+	return func(p *ObjectParser) {
+		p.mode = mode
 	}
-	yamls = append(yamls, b.String())
+}
 
-	var objects []runtime.Object
+func NewObjectParser(scheme *runtime.Scheme, opts ...ObjectParserOption) *ObjectParser {
+	p := &ObjectParser{
+		scheme: scheme,
+	}
 
-	for _, yaml := range yamls {
-		yaml = p.removeNonYAMLLines(yaml)
-		if yaml == "" {
-			continue
-		}
-		o, err := p.ParseYAMLToK8sObject([]byte(yaml), modifiers...)
-		if err != nil {
-			log.Error(err, "failed to parse YAML to a k8s object")
-			continue
-		}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
 
+// ParseYAMLManifest parses all the objects of a multi-document manifest, see DecodeYAMLManifest.
+// The objects parsed successfully are returned along with the ManifestError of the failed documents in strict mode.
+// Documents that fail to parse are only logged, unless the parser is created with WithStrictParsing.
+func (p *ObjectParser) ParseYAMLManifest(manifest string, modifiers ...YAMLModifierFuncs) ([]runtime.Object, error) {
+	var objects []runtime.Object
+	err := p.DecodeYAMLManifest(strings.NewReader(manifest), func(o runtime.Object) error {
 		objects = append(objects, o)
-	}
+		return nil
+	}, modifiers...)
 
-	return objects, nil
+	return objects, err
 }
 
+// ParseYAMLToK8sObject parses a single YAML or JSON document into a typed or an unstructured object depending on the parsing mode
 func (p *ObjectParser) ParseYAMLToK8sObject(yaml []byte, yamlModifiers ...YAMLModifierFuncs) (runtime.Object, error) {
 	for _, modifierFunc := range yamlModifiers {
 		yaml = modifierFunc(yaml)
 	}
 
-	if p.scheme != nil {
-		s := json.NewYAMLSerializer(json.DefaultMetaFactory, p.scheme, p.scheme)
-		o, _, err := s.Decode(yaml, nil, nil)
-		if err == nil {
-			return o, nil
-		}
-	}
-
 	r := bytes.NewReader(yaml)
 	decoder := k8syaml.NewYAMLOrJSONDecoder(r, 1024)
 
@@ -147,19 +147,78 @@ func (p *ObjectParser) ParseYAMLToK8sObject(yaml []byte, yamlModifiers ...YAMLMo
 	if err != nil {
 		return nil, errors.WrapIf(err, "error decoding object as unstructured")
 	}
-	return out, nil
+
+	return p.fromUnstructuredContent(out.Object)
 }
 
-func (p *ObjectParser) removeNonYAMLLines(yms string) string {
-	out := ""
-	for _, s := range strings.Split(yms, "\n") {
-		if strings.HasPrefix(s, "#") {
-			continue
+// ToUnstructured converts an object to an unstructured object, looking up its kind from the scheme if it is not set
+func (p *ObjectParser) ToUnstructured(o runtime.Object) (*unstructured.Unstructured, error) {
+	content, err := toUnstructuredContent(o)
+	if err != nil {
+		return nil, err
+	}
+	u := &unstructured.Unstructured{Object: content}
+
+	if u.GetKind() == "" && p.scheme != nil {
+		gvks, _, err := p.scheme.ObjectKinds(o)
+		if err != nil {
+			return nil, errors.WrapIf(err, "could not determine the kind of the object")
 		}
-		out += s + "\n"
+		u.SetGroupVersionKind(gvks[0])
+	}
+
+	return u, nil
+}
+
+// ToTyped converts an unstructured object to the Go type registered in the scheme for its kind
+func (p *ObjectParser) ToTyped(u *unstructured.Unstructured) (runtime.Object, error) {
+	if p.scheme == nil {
+		return nil, errors.NewWithDetails("no scheme to convert unstructured object with", "kind", u.GetKind(), "name", u.GetName())
+	}
+
+	o, err := p.scheme.New(u.GroupVersionKind())
+	if err != nil {
+		return nil, errors.WrapIfWithDetails(err, "unknown kind", "apiVersion", u.GetAPIVersion(), "kind", u.GetKind(), "name", u.GetName())
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, o); err != nil {
+		return nil, errors.WrapIfWithDetails(err, "could not convert unstructured object", "kind", u.GetKind(), "name", u.GetName())
+	}
+	o.GetObjectKind().SetGroupVersionKind(u.GroupVersionKind())
+
+	return o, nil
+}
+
+// Convert returns the object in the form produced by the parsing mode of the parser,
+// so that objects coming from different sources can be processed uniformly
+func (p *ObjectParser) Convert(o runtime.Object) (runtime.Object, error) {
+	u, ok := o.(*unstructured.Unstructured)
+	switch {
+	case p.mode == UnstructuredOnly && ok:
+		return o, nil
+	case p.mode == UnstructuredOnly:
+		return p.ToUnstructured(o)
+	case ok:
+		converted, err := p.fromUnstructuredContent(u.Object)
+		if _, unchanged := converted.(*unstructured.Unstructured); unchanged {
+			return o, err
+		}
+		return converted, err
+	}
+	return o, nil
+}
+
+// fromUnstructuredContent converts unstructured content to a typed or an unstructured object depending on the parsing mode
+func (p *ObjectParser) fromUnstructuredContent(content map[string]interface{}) (runtime.Object, error) {
+	u := &unstructured.Unstructured{Object: content}
+	if p.mode == UnstructuredOnly {
+		return u, nil
+	}
+
+	if p.mode == TypedWithUnstructuredFallback && (p.scheme == nil || !p.scheme.Recognizes(u.GroupVersionKind())) {
+		return u, nil
 	}
 
-	return strings.TrimSpace(out)
+	return p.ToTyped(u)
 }
 
 // IsObjectBeingDeleted returns true, if the given object is being deleted with finalizers still
@@ -0,0 +1,86 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+)
+
+func TestObjectParserModes(t *testing.T) {
+	known := []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: known\ndata:\n  a: b\n")
+	unknown := []byte("apiVersion: example.com/v1\nkind: Custom\nmetadata:\n  name: unknown\n")
+	invalid := []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: invalid\ndata:\n  a: [b]\n")
+
+	tests := map[ParsingMode]struct {
+		known, unknown, invalid interface{}
+	}{
+		TypedWithUnstructuredFallback: {known: &corev1.ConfigMap{}, unknown: &unstructured.Unstructured{}, invalid: nil},
+		TypedOnly:                     {known: &corev1.ConfigMap{}, unknown: nil, invalid: nil},
+		UnstructuredOnly:              {known: &unstructured.Unstructured{}, unknown: &unstructured.Unstructured{}, invalid: &unstructured.Unstructured{}},
+	}
+
+	for mode, tt := range tests {
+		parser := NewObjectParser(clientgoscheme.Scheme, WithParsingMode(mode))
+		for doc, want := range map[string]interface{}{string(known): tt.known, string(unknown): tt.unknown, string(invalid): tt.invalid} {
+			o, err := parser.ParseYAMLToK8sObject([]byte(doc))
+			if want == nil {
+				assert.Error(t, err, "mode %d: %s", mode, doc)
+				continue
+			}
+			require.NoError(t, err, "mode %d: %s", mode, doc)
+			assert.IsType(t, want, o, "mode %d: %s", mode, doc)
+			assert.NotEmpty(t, o.GetObjectKind().GroupVersionKind().Kind)
+		}
+	}
+}
+
+func TestObjectParserConversion(t *testing.T) {
+	parser := NewObjectParser(clientgoscheme.Scheme)
+
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test"}, Data: map[string]string{"a": "b"}}
+	u, err := parser.ToUnstructured(cm)
+	require.NoError(t, err)
+	assert.Equal(t, "ConfigMap", u.GetKind())
+	assert.Equal(t, "v1", u.GetAPIVersion())
+
+	o, err := parser.ToTyped(u)
+	require.NoError(t, err)
+	require.IsType(t, &corev1.ConfigMap{}, o)
+	assert.Equal(t, cm.Data, o.(*corev1.ConfigMap).Data)
+
+	converted, err := parser.Convert(u)
+	require.NoError(t, err)
+	assert.IsType(t, &corev1.ConfigMap{}, converted)
+
+	converted, err = NewObjectParser(clientgoscheme.Scheme, WithParsingMode(UnstructuredOnly)).Convert(cm)
+	require.NoError(t, err)
+	assert.IsType(t, &unstructured.Unstructured{}, converted)
+
+	custom := &unstructured.Unstructured{}
+	custom.SetAPIVersion("example.com/v1")
+	custom.SetKind("Custom")
+	_, err = parser.ToTyped(custom)
+	assert.Error(t, err)
+	converted, err = parser.Convert(custom)
+	require.NoError(t, err)
+	assert.Same(t, custom, converted.(*unstructured.Unstructured))
+}
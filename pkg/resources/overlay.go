@@ -15,13 +15,15 @@
 package resources
 
 import (
+	"path"
+	"regexp"
+
 	"emperror.dev/errors"
-	ypatch "github.com/cppforlife/go-patch/patch"
-	"gopkg.in/yaml.v2"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
-	k8syaml "sigs.k8s.io/yaml"
 
 	"github.com/cisco-open/operator-tools/pkg/types"
 	"github.com/cisco-open/operator-tools/pkg/utils"
@@ -35,25 +37,50 @@ type GroupVersionKind struct {
 
 // +kubebuilder:object:generate=true
 type K8SResourceOverlay struct {
-	GVK       *GroupVersionKind         `json:"groupVersionKind,omitempty"`
-	ObjectKey types.ObjectKey           `json:"objectKey,omitempty"`
+	GVK       *GroupVersionKind `json:"groupVersionKind,omitempty"`
+	ObjectKey types.ObjectKey   `json:"objectKey,omitempty"`
+	// Kinds restricts the overlay to objects of any of the listed kinds
+	Kinds []string `json:"kinds,omitempty"`
+	// LabelSelector restricts the overlay to objects with matching labels
+	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty"`
+	// AnnotationSelector restricts the overlay to objects with matching annotations, using label selector semantics
+	AnnotationSelector *metav1.LabelSelector `json:"annotationSelector,omitempty"`
+	// NamePattern restricts the overlay to objects with names matching the glob pattern, e.g. `*-controller`
+	NamePattern string `json:"namePattern,omitempty"`
+	// NameRegex restricts the overlay to objects with names matching the regular expression
+	NameRegex string                    `json:"nameRegex,omitempty"`
 	Patches   []K8SResourceOverlayPatch `json:"patches,omitempty"`
 }
 
 // +kubebuilder:object:generate=true
+// +kubebuilder:validation:Enum=replace;remove;add;move;copy;test;strategic-merge;merge
 type OverlayPatchType string
 
 const (
+	// ReplaceOverlayPatchType and DeleteOverlayPatchType are go-patch operations,
+	// their paths support selecting list items by field value, e.g. /spec/containers/name=app/image
 	ReplaceOverlayPatchType OverlayPatchType = "replace"
 	DeleteOverlayPatchType  OverlayPatchType = "remove"
+	// RFC 6902 JSON patch operations
+	AddOverlayPatchType  OverlayPatchType = "add"
+	MoveOverlayPatchType OverlayPatchType = "move"
+	CopyOverlayPatchType OverlayPatchType = "copy"
+	TestOverlayPatchType OverlayPatchType = "test"
+	// StrategicMergeOverlayPatchType merges the value using the patch metadata of the object's Go type
+	// and falls back to a JSON merge patch for unstructured objects
+	StrategicMergeOverlayPatchType OverlayPatchType = "strategic-merge"
+	// MergeOverlayPatchType is an RFC 7386 JSON merge patch
+	MergeOverlayPatchType OverlayPatchType = "merge"
 )
 
 // +kubebuilder:object:generate=true
 type K8SResourceOverlayPatch struct {
-	Type       OverlayPatchType `json:"type,omitempty"`
-	Path       *string          `json:"path,omitempty"`
-	Value      *string          `json:"value,omitempty"`
-	ParseValue bool             `json:"parseValue,omitempty"`
+	Type OverlayPatchType `json:"type,omitempty"`
+	Path *string          `json:"path,omitempty"`
+	// From is the source path of move and copy operations
+	From       *string `json:"from,omitempty"`
+	Value      *string `json:"value,omitempty"`
+	ParseValue bool    `json:"parseValue,omitempty"`
 }
 
 func PatchYAMLModifier(overlay K8SResourceOverlay, parser *ObjectParser) (ObjectModifierFunc, error) {
@@ -63,86 +90,184 @@ func PatchYAMLModifier(overlay K8SResourceOverlay, parser *ObjectParser) (Object
 		}, nil
 	}
 
-	var opsDefinitions []ypatch.OpDefinition
-	for _, patch := range overlay.Patches {
-		var value interface{}
-		if patch.ParseValue {
-			err := yaml.Unmarshal([]byte(utils.PointerToString(patch.Value)), &value)
-			if err != nil {
-				return nil, errors.WrapIf(err, "could not unmarshal value")
-			}
-		} else {
-			value = interface{}(patch.Value)
-		}
-
-		op := ypatch.OpDefinition{
-			Type: string(patch.Type),
-			Path: patch.Path,
-		}
-		if patch.Type == ReplaceOverlayPatchType {
-			op.Value = &value
-		}
-		opsDefinitions = append(opsDefinitions, op)
+	c, err := compileOverlay(overlay)
+	if err != nil {
+		return nil, err
 	}
 
 	return func(o runtime.Object) (runtime.Object, error) {
-		var ok bool
-		var meta metav1.Object
-
-		if overlay.GVK != nil {
-			gvk := o.GetObjectKind().GroupVersionKind()
-			if overlay.GVK.Group != "" && overlay.GVK.Group != gvk.Group {
-				return o, nil
-			}
-			if overlay.GVK.Version != "" && overlay.GVK.Version != gvk.Version {
-				return o, nil
-			}
-			if overlay.GVK.Kind != "" && overlay.GVK.Kind != gvk.Kind {
-				return o, nil
-			}
-		}
-
-		if meta, ok = o.(metav1.Object); !ok {
+		if !c.target.matches(o) {
 			return o, nil
 		}
 
-		if (overlay.ObjectKey.Name != "" && meta.GetName() != overlay.ObjectKey.Name) || (overlay.ObjectKey.Namespace != "" && meta.GetNamespace() != overlay.ObjectKey.Namespace) {
-			return o, nil
+		content, err := toUnstructuredContent(o)
+		if err != nil {
+			return o, err
 		}
 
-		y, err := k8syaml.Marshal(o)
+		content, err = c.apply(content, o)
 		if err != nil {
-			return o, errors.WrapIf(err, "could not marshal runtime object")
+			return o, err
 		}
 
-		ops, err := ypatch.NewOpsFromDefinitions(opsDefinitions)
+		return parser.fromUnstructuredContent(content)
+	}, nil
+}
+
+// compiledOverlay is an overlay with its target and patches validated and prepared for application
+type compiledOverlay struct {
+	overlay K8SResourceOverlay
+	target  *overlayTarget
+	patches []overlayPatchFunc
+}
+
+func compileOverlay(overlay K8SResourceOverlay) (*compiledOverlay, error) {
+	patches := make([]overlayPatchFunc, 0, len(overlay.Patches))
+	for i, patch := range overlay.Patches {
+		patchFunc, err := newOverlayPatchFunc(patch)
 		if err != nil {
-			return o, errors.WrapIf(err, "could not init patch ops from definitions")
+			return nil, errors.WrapIff(err, "invalid overlay patch #%d (%s)", i, patch.Type)
 		}
+		patches = append(patches, patchFunc)
+	}
 
-		var in interface{}
-		err = yaml.Unmarshal(y, &in)
-		if err != nil {
-			return o, errors.WrapIf(err, "could not unmarshal resource yaml")
+	target, err := newOverlayTarget(overlay)
+	if err != nil {
+		return nil, err
+	}
+
+	return &compiledOverlay{
+		overlay: overlay,
+		target:  target,
+		patches: patches,
+	}, nil
+}
+
+// overlayPatchError identifies the patch of an overlay that could not be applied
+type overlayPatchError struct {
+	patch int
+	err   error
+}
+
+func (e *overlayPatchError) Error() string {
+	return e.err.Error()
+}
+
+func (e *overlayPatchError) Unwrap() error {
+	return e.err
+}
+
+// apply runs the patches of the overlay on a copy of the unstructured content of an object,
+// so that the content is left intact if any of the patches fail
+func (c *compiledOverlay) apply(content map[string]interface{}, original runtime.Object) (map[string]interface{}, error) {
+	if len(c.patches) == 0 {
+		return content, nil
+	}
+
+	patched := runtime.DeepCopyJSON(content)
+	for i, patch := range c.patches {
+		var err error
+		if patched, err = patch(patched, original); err != nil {
+			return content, &overlayPatchError{
+				patch: i,
+				err:   errors.WrapIff(err, "could not apply overlay patch #%d (%s)", i, c.overlay.Patches[i].Type),
+			}
 		}
+	}
 
-		res, err := ops.Apply(in)
-		if err != nil {
-			return o, errors.WrapIf(err, "could not apply patch ops")
+	return patched, nil
+}
+
+// toUnstructuredContent returns the content of the object as a new unstructured map
+func toUnstructuredContent(o runtime.Object) (map[string]interface{}, error) {
+	if u, ok := o.(*unstructured.Unstructured); ok {
+		return runtime.DeepCopyJSON(u.Object), nil
+	}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(o)
+	if err != nil {
+		return nil, errors.WrapIf(err, "could not convert object to unstructured")
+	}
+	return content, nil
+}
+
+// overlayTarget decides whether an overlay applies to an object
+type overlayTarget struct {
+	overlay            K8SResourceOverlay
+	labelSelector      labels.Selector
+	annotationSelector labels.Selector
+	nameRegex          *regexp.Regexp
+}
+
+func newOverlayTarget(overlay K8SResourceOverlay) (*overlayTarget, error) {
+	t := &overlayTarget{overlay: overlay}
+
+	var err error
+	if overlay.LabelSelector != nil {
+		if t.labelSelector, err = metav1.LabelSelectorAsSelector(overlay.LabelSelector); err != nil {
+			return nil, errors.WrapIf(err, "invalid label selector")
+		}
+	}
+	if overlay.AnnotationSelector != nil {
+		if t.annotationSelector, err = metav1.LabelSelectorAsSelector(overlay.AnnotationSelector); err != nil {
+			return nil, errors.WrapIf(err, "invalid annotation selector")
+		}
+	}
+	if overlay.NamePattern != "" {
+		if _, err := path.Match(overlay.NamePattern, ""); err != nil {
+			return nil, errors.WrapIff(err, "invalid name pattern %q", overlay.NamePattern)
+		}
+	}
+	if overlay.NameRegex != "" {
+		if t.nameRegex, err = regexp.Compile(overlay.NameRegex); err != nil {
+			return nil, errors.WrapIff(err, "invalid name regex %q", overlay.NameRegex)
 		}
+	}
 
-		y, err = yaml.Marshal(res)
-		if err != nil {
-			return o, errors.WrapIf(err, "could not marshal patched object to yaml")
+	return t, nil
+}
+
+func (t *overlayTarget) matches(o runtime.Object) bool {
+	gvk := o.GetObjectKind().GroupVersionKind()
+	if t.overlay.GVK != nil {
+		if t.overlay.GVK.Group != "" && t.overlay.GVK.Group != gvk.Group {
+			return false
+		}
+		if t.overlay.GVK.Version != "" && t.overlay.GVK.Version != gvk.Version {
+			return false
 		}
+		if t.overlay.GVK.Kind != "" && t.overlay.GVK.Kind != gvk.Kind {
+			return false
+		}
+	}
+	if len(t.overlay.Kinds) > 0 && !utils.Contains(t.overlay.Kinds, gvk.Kind) {
+		return false
+	}
 
-		o, err = parser.ParseYAMLToK8sObject(y)
-		if err != nil {
-			return o, errors.WrapIf(err, "could not parse runtime object from yaml")
+	meta, ok := o.(metav1.Object)
+	if !ok {
+		return false
+	}
+
+	if (t.overlay.ObjectKey.Name != "" && meta.GetName() != t.overlay.ObjectKey.Name) || (t.overlay.ObjectKey.Namespace != "" && meta.GetNamespace() != t.overlay.ObjectKey.Namespace) {
+		return false
+	}
+	if t.overlay.NamePattern != "" {
+		if matched, _ := path.Match(t.overlay.NamePattern, meta.GetName()); !matched {
+			return false
 		}
+	}
+	if t.nameRegex != nil && !t.nameRegex.MatchString(meta.GetName()) {
+		return false
+	}
+	if t.labelSelector != nil && !t.labelSelector.Matches(labels.Set(meta.GetLabels())) {
+		return false
+	}
+	if t.annotationSelector != nil && !t.annotationSelector.Matches(labels.Set(meta.GetAnnotations())) {
+		return false
+	}
 
-		return o, nil
-	}, nil
+	return true
 }
 
 func ConvertGVK(gvk schema.GroupVersionKind) GroupVersionKind {
@@ -0,0 +1,119 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"fmt"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+
+	"github.com/cisco-open/operator-tools/pkg/utils"
+)
+
+func benchmarkObjects(n int) []runtime.Object {
+	objects := make([]runtime.Object, 0, n)
+	for i := 0; i < n; i++ {
+		objects = append(objects, &appsv1.Deployment{
+			TypeMeta:   metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("test-%d", i), Namespace: "test-ns", Labels: map[string]string{"app": "test"}},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: utils.IntPointer(1),
+				Template: v1.PodTemplateSpec{
+					Spec: v1.PodSpec{
+						Containers: []v1.Container{
+							{Name: "app", Image: "app:1.0", Args: []string{"--verbose"}},
+							{Name: "sidecar", Image: "sidecar:1.0"},
+						},
+					},
+				},
+			},
+		})
+	}
+	return objects
+}
+
+func benchmarkOverlays() []K8SResourceOverlay {
+	return []K8SResourceOverlay{
+		{
+			Kinds: []string{"Deployment"},
+			Patches: []K8SResourceOverlayPatch{
+				{Type: ReplaceOverlayPatchType, Path: utils.StringPointer("/spec/replicas"), Value: utils.StringPointer("3"), ParseValue: true},
+			},
+		},
+		{
+			LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}},
+			Patches: []K8SResourceOverlayPatch{
+				{Type: StrategicMergeOverlayPatchType, Value: utils.StringPointer("spec: {template: {spec: {containers: [{name: sidecar, image: 'sidecar:2.0'}]}}}")},
+			},
+		},
+		{
+			NamePattern: "test-*",
+			Patches: []K8SResourceOverlayPatch{
+				{Type: AddOverlayPatchType, Path: utils.StringPointer("/metadata/annotations"), Value: utils.StringPointer("{patched: 'true'}"), ParseValue: true},
+				{Type: MergeOverlayPatchType, Value: utils.StringPointer("metadata: {labels: {tier: backend}}")},
+			},
+		},
+	}
+}
+
+func BenchmarkPatchYAMLModifier(b *testing.B) {
+	parser := NewObjectParser(clientgoscheme.Scheme)
+	objects := benchmarkObjects(100)
+
+	var modifiers []ObjectModifierFunc
+	for _, overlay := range benchmarkOverlays() {
+		modifier, err := PatchYAMLModifier(overlay, parser)
+		if err != nil {
+			b.Fatal(err)
+		}
+		modifiers = append(modifiers, modifier)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, o := range objects {
+			var err error
+			for _, modifier := range modifiers {
+				if o, err = modifier(o); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkOverlayModifier(b *testing.B) {
+	parser := NewObjectParser(clientgoscheme.Scheme)
+	objects := benchmarkObjects(100)
+
+	modifier, _, err := OverlayModifier(benchmarkOverlays(), parser)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, o := range objects {
+			if _, err := modifier(o); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
@@ -19,6 +19,8 @@ import (
 
 	"github.com/go-test/deep"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	v12 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -203,3 +205,317 @@ func TestPatchYAMLModifier(t *testing.T) {
 		})
 	}
 }
+
+func TestPatchYAMLModifierPatchTypes(t *testing.T) {
+	baseObject := func() *appsv1.Deployment {
+		return &appsv1.Deployment{
+			TypeMeta:   v12.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+			ObjectMeta: v12.ObjectMeta{Name: "test", Namespace: "test-ns", Labels: map[string]string{"app": "test"}},
+			Spec: appsv1.DeploymentSpec{
+				Template: v1.PodTemplateSpec{
+					Spec: v1.PodSpec{
+						Containers: []v1.Container{
+							{Name: "app", Image: "app:1.0"},
+							{Name: "sidecar", Image: "sidecar:1.0"},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	parser := NewObjectParser(clientgoscheme.Scheme)
+	tests := map[string]struct {
+		patches []K8SResourceOverlayPatch
+		want    func(d *appsv1.Deployment)
+	}{
+		"strategic merge patch merges containers by name": {
+			patches: []K8SResourceOverlayPatch{
+				{
+					Type:  StrategicMergeOverlayPatchType,
+					Value: utils.StringPointer("spec:\n  template:\n    spec:\n      containers:\n      - name: sidecar\n        image: sidecar:2.0\n"),
+				},
+			},
+			want: func(d *appsv1.Deployment) {
+				d.Spec.Template.Spec.Containers[1].Image = "sidecar:2.0"
+			},
+		},
+		"merge patch replaces lists": {
+			patches: []K8SResourceOverlayPatch{
+				{
+					Type:  MergeOverlayPatchType,
+					Value: utils.StringPointer("metadata:\n  labels:\n    app: null\n    tier: backend\n"),
+				},
+			},
+			want: func(d *appsv1.Deployment) {
+				d.Labels = map[string]string{"tier": "backend"}
+			},
+		},
+		"json patch operations": {
+			patches: []K8SResourceOverlayPatch{
+				{
+					Type:  TestOverlayPatchType,
+					Path:  utils.StringPointer("/spec/template/spec/containers/0/name"),
+					Value: utils.StringPointer("app"),
+				},
+				{
+					Type:       AddOverlayPatchType,
+					Path:       utils.StringPointer("/spec/template/spec/containers/0/args"),
+					Value:      utils.StringPointer("[--verbose]"),
+					ParseValue: true,
+				},
+				{
+					Type: CopyOverlayPatchType,
+					From: utils.StringPointer("/spec/template/spec/containers/0/args"),
+					Path: utils.StringPointer("/spec/template/spec/containers/1/args"),
+				},
+				{
+					Type: MoveOverlayPatchType,
+					From: utils.StringPointer("/metadata/labels/app"),
+					Path: utils.StringPointer("/metadata/labels/name"),
+				},
+				{
+					Type:  ReplaceOverlayPatchType,
+					Path:  utils.StringPointer("/spec/template/spec/containers/name=app/image"),
+					Value: utils.StringPointer("app:2.0"),
+				},
+			},
+			want: func(d *appsv1.Deployment) {
+				d.Labels = map[string]string{"name": "test"}
+				d.Spec.Template.Spec.Containers[0].Args = []string{"--verbose"}
+				d.Spec.Template.Spec.Containers[0].Image = "app:2.0"
+				d.Spec.Template.Spec.Containers[1].Args = []string{"--verbose"}
+			},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			modifier, err := PatchYAMLModifier(K8SResourceOverlay{Patches: tt.patches}, parser)
+			require.NoError(t, err)
+
+			patched, err := modifier(baseObject())
+			require.NoError(t, err)
+
+			want := baseObject()
+			tt.want(want)
+			if diff := deep.Equal(patched, want); diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+
+	t.Run("failed test operation", func(t *testing.T) {
+		modifier, err := PatchYAMLModifier(K8SResourceOverlay{Patches: []K8SResourceOverlayPatch{
+			{
+				Type:  TestOverlayPatchType,
+				Path:  utils.StringPointer("/metadata/name"),
+				Value: utils.StringPointer("other"),
+			},
+		}}, parser)
+		require.NoError(t, err)
+
+		_, err = modifier(baseObject())
+		assert.ErrorContains(t, err, "could not apply overlay patch #0 (test)")
+	})
+}
+
+// TestJSONPatchConformance covers cases of RFC 6902 Appendix A and the restrictions added on top of the json-patch library
+func TestJSONPatchConformance(t *testing.T) {
+	tests := map[string]struct {
+		doc   string
+		patch K8SResourceOverlayPatch
+		want  string
+		err   string
+	}{
+		"A.1 adding an object member": {
+			doc:   `{"foo": "bar"}`,
+			patch: K8SResourceOverlayPatch{Type: AddOverlayPatchType, Path: utils.StringPointer("/baz"), Value: utils.StringPointer("qux")},
+			want:  `{"baz": "qux", "foo": "bar"}`,
+		},
+		"A.2 adding an array element": {
+			doc:   `{"foo": ["bar", "baz"]}`,
+			patch: K8SResourceOverlayPatch{Type: AddOverlayPatchType, Path: utils.StringPointer("/foo/1"), Value: utils.StringPointer("qux")},
+			want:  `{"foo": ["bar", "qux", "baz"]}`,
+		},
+		"A.7 moving an array element": {
+			doc:   `{"foo": ["all", "grass", "cows", "eat"]}`,
+			patch: K8SResourceOverlayPatch{Type: MoveOverlayPatchType, From: utils.StringPointer("/foo/1"), Path: utils.StringPointer("/foo/3")},
+			want:  `{"foo": ["all", "cows", "eat", "grass"]}`,
+		},
+		"A.10 adding a nested member object": {
+			doc:   `{"foo": "bar"}`,
+			patch: K8SResourceOverlayPatch{Type: AddOverlayPatchType, Path: utils.StringPointer("/child"), Value: utils.StringPointer(`{"grandchild": {}}`), ParseValue: true},
+			want:  `{"foo": "bar", "child": {"grandchild": {}}}`,
+		},
+		"A.12 adding to a nonexistent target": {
+			doc:   `{"foo": "bar"}`,
+			patch: K8SResourceOverlayPatch{Type: AddOverlayPatchType, Path: utils.StringPointer("/baz/bat"), Value: utils.StringPointer("qux")},
+			err:   "missing",
+		},
+		"A.14 escape ordering": {
+			doc:   `{"/": 9, "~1": 10}`,
+			patch: K8SResourceOverlayPatch{Type: TestOverlayPatchType, Path: utils.StringPointer("/~01"), Value: utils.StringPointer("10"), ParseValue: true},
+			want:  `{"/": 9, "~1": 10}`,
+		},
+		"A.15 comparing strings and numbers": {
+			doc:   `{"/": 9, "~1": 10}`,
+			patch: K8SResourceOverlayPatch{Type: TestOverlayPatchType, Path: utils.StringPointer("/~01"), Value: utils.StringPointer(`"10"`), ParseValue: true},
+			err:   "testing value",
+		},
+		"A.16 adding an array value": {
+			doc:   `{"foo": ["bar"]}`,
+			patch: K8SResourceOverlayPatch{Type: AddOverlayPatchType, Path: utils.StringPointer("/foo/-"), Value: utils.StringPointer(`["abc", "def"]`), ParseValue: true},
+			want:  `{"foo": ["bar", ["abc", "def"]]}`,
+		},
+		"negative array indices are not supported": {
+			doc:   `{"foo": ["bar", "baz"]}`,
+			patch: K8SResourceOverlayPatch{Type: CopyOverlayPatchType, From: utils.StringPointer("/foo/-1"), Path: utils.StringPointer("/qux")},
+			err:   "index",
+		},
+		"integers stay integers": {
+			doc:   `{"spec": {"replicas": 1}}`,
+			patch: K8SResourceOverlayPatch{Type: CopyOverlayPatchType, From: utils.StringPointer("/spec/replicas"), Path: utils.StringPointer("/spec/minReplicas")},
+			want:  `{"spec": {"replicas": 1, "minReplicas": 1}}`,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			patch, err := newOverlayPatchFunc(tt.patch)
+			require.NoError(t, err)
+
+			content, err := parseJSONValue(tt.doc)
+			require.NoError(t, err)
+			patched, err := patch(content.(map[string]interface{}), nil)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+
+			want, err := parseJSONValue(tt.want)
+			require.NoError(t, err)
+			assert.Equal(t, want, patched)
+		})
+	}
+
+	_, err := newOverlayPatchFunc(K8SResourceOverlayPatch{Type: MoveOverlayPatchType, From: utils.StringPointer("/a"), Path: utils.StringPointer("/a/b")})
+	assert.ErrorContains(t, err, "a location cannot be moved into one of its children")
+}
+
+func TestPatchYAMLModifierValidation(t *testing.T) {
+	tests := map[string]struct {
+		patch K8SResourceOverlayPatch
+		err   string
+	}{
+		"unknown type": {
+			patch: K8SResourceOverlayPatch{Type: "rename"},
+			err:   `invalid overlay patch #1 (rename): unsupported patch type "rename"`,
+		},
+		"missing from": {
+			patch: K8SResourceOverlayPatch{Type: MoveOverlayPatchType, Path: utils.StringPointer("/spec")},
+			err:   "invalid overlay patch #1 (move): from is required",
+		},
+		"invalid pointer": {
+			patch: K8SResourceOverlayPatch{Type: AddOverlayPatchType, Path: utils.StringPointer("spec")},
+			err:   `invalid overlay patch #1 (add): path "spec" is not a JSON pointer`,
+		},
+		"merge patch with path": {
+			patch: K8SResourceOverlayPatch{Type: MergeOverlayPatchType, Path: utils.StringPointer("/spec"), Value: utils.StringPointer("a: b")},
+			err:   "invalid overlay patch #1 (merge): path is not supported, the value is merged into the whole object",
+		},
+		"strategic merge patch with list value": {
+			patch: K8SResourceOverlayPatch{Type: StrategicMergeOverlayPatchType, Value: utils.StringPointer("- a")},
+			err:   "invalid overlay patch #1 (strategic-merge): value must be an object",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := PatchYAMLModifier(K8SResourceOverlay{Patches: []K8SResourceOverlayPatch{
+				{Type: DeleteOverlayPatchType, Path: utils.StringPointer("/spec/replicas")},
+				tt.patch,
+			}}, NewObjectParser(clientgoscheme.Scheme))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestPatchYAMLModifierTargeting(t *testing.T) {
+	objects := []runtime.Object{
+		&appsv1.Deployment{
+			TypeMeta: v12.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+			ObjectMeta: v12.ObjectMeta{
+				Name:        "test-controller",
+				Namespace:   "test-ns",
+				Labels:      map[string]string{"app": "test", "tier": "backend"},
+				Annotations: map[string]string{"team": "core"},
+			},
+		},
+		&appsv1.StatefulSet{
+			TypeMeta:   v12.TypeMeta{Kind: "StatefulSet", APIVersion: "apps/v1"},
+			ObjectMeta: v12.ObjectMeta{Name: "test-db", Namespace: "test-ns", Labels: map[string]string{"app": "test"}},
+		},
+		&v1.Service{
+			TypeMeta:   v12.TypeMeta{Kind: "Service", APIVersion: "v1"},
+			ObjectMeta: v12.ObjectMeta{Name: "test-controller", Namespace: "test-ns", Labels: map[string]string{"app": "test"}},
+		},
+	}
+
+	parser := NewObjectParser(clientgoscheme.Scheme)
+	tests := map[string]struct {
+		overlay K8SResourceOverlay
+		want    []string
+	}{
+		"list of kinds": {
+			overlay: K8SResourceOverlay{Kinds: []string{"Deployment", "StatefulSet"}},
+			want:    []string{"Deployment/test-controller", "StatefulSet/test-db"},
+		},
+		"label selector": {
+			overlay: K8SResourceOverlay{LabelSelector: &v12.LabelSelector{MatchLabels: map[string]string{"tier": "backend"}}},
+			want:    []string{"Deployment/test-controller"},
+		},
+		"label selector expression": {
+			overlay: K8SResourceOverlay{LabelSelector: &v12.LabelSelector{MatchExpressions: []v12.LabelSelectorRequirement{
+				{Key: "tier", Operator: v12.LabelSelectorOpDoesNotExist},
+			}}},
+			want: []string{"StatefulSet/test-db", "Service/test-controller"},
+		},
+		"annotation selector": {
+			overlay: K8SResourceOverlay{AnnotationSelector: &v12.LabelSelector{MatchLabels: map[string]string{"team": "core"}}},
+			want:    []string{"Deployment/test-controller"},
+		},
+		"name glob": {
+			overlay: K8SResourceOverlay{NamePattern: "*-controller"},
+			want:    []string{"Deployment/test-controller", "Service/test-controller"},
+		},
+		"name regex and kind": {
+			overlay: K8SResourceOverlay{NameRegex: "^test-(db|cache)$", Kinds: []string{"StatefulSet"}},
+			want:    []string{"StatefulSet/test-db"},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			tt.overlay.Patches = []K8SResourceOverlayPatch{
+				{Type: MergeOverlayPatchType, Value: utils.StringPointer("metadata: {annotations: {patched: 'true'}}")},
+			}
+			modifier, err := PatchYAMLModifier(tt.overlay, parser)
+			require.NoError(t, err)
+
+			var patched []string
+			for _, o := range objects {
+				result, err := modifier(o.DeepCopyObject())
+				require.NoError(t, err)
+				meta := result.(v12.Object)
+				if meta.GetAnnotations()["patched"] == "true" {
+					patched = append(patched, result.GetObjectKind().GroupVersionKind().Kind+"/"+meta.GetName())
+				}
+			}
+			assert.Equal(t, tt.want, patched)
+		})
+	}
+
+	_, err := PatchYAMLModifier(K8SResourceOverlay{NameRegex: "(", Patches: []K8SResourceOverlayPatch{
+		{Type: DeleteOverlayPatchType, Path: utils.StringPointer("/spec")},
+	}}, parser)
+	assert.ErrorContains(t, err, "invalid name regex")
+}
@@ -0,0 +1,281 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"emperror.dev/errors"
+	ypatch "github.com/cppforlife/go-patch/patch"
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	k8syaml "sigs.k8s.io/yaml"
+
+	"github.com/cisco-open/operator-tools/pkg/utils"
+)
+
+// overlayPatchFunc applies a single patch to the unstructured content of an object.
+// The content may be modified in place, the result has to be used in any case.
+type overlayPatchFunc func(content map[string]interface{}, original runtime.Object) (map[string]interface{}, error)
+
+func newOverlayPatchFunc(patch K8SResourceOverlayPatch) (overlayPatchFunc, error) {
+	switch patch.Type {
+	case ReplaceOverlayPatchType, DeleteOverlayPatchType:
+		return newGoPatchFunc(patch)
+	case AddOverlayPatchType, MoveOverlayPatchType, CopyOverlayPatchType, TestOverlayPatchType:
+		return newJSONPatchFunc(patch)
+	case StrategicMergeOverlayPatchType, MergeOverlayPatchType:
+		if patch.Path != nil {
+			return nil, errors.New("path is not supported, the value is merged into the whole object")
+		}
+		if patch.Value == nil {
+			return nil, errors.New("value is required")
+		}
+		value, err := parseJSONValue(*patch.Value)
+		if err != nil {
+			return nil, errors.WrapIf(err, "could not parse value")
+		}
+		patchMap, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("value must be an object")
+		}
+		patchJSON, err := json.Marshal(patchMap)
+		if err != nil {
+			return nil, err
+		}
+		if patch.Type == MergeOverlayPatchType {
+			return func(content map[string]interface{}, _ runtime.Object) (map[string]interface{}, error) {
+				return mergePatch(content, patchJSON)
+			}, nil
+		}
+		return func(content map[string]interface{}, original runtime.Object) (map[string]interface{}, error) {
+			if _, ok := original.(*unstructured.Unstructured); ok {
+				// no patch metadata is available for unstructured objects
+				return mergePatch(content, patchJSON)
+			}
+			meta, err := strategicpatch.NewPatchMetaFromStruct(original)
+			if err != nil {
+				return nil, err
+			}
+			return strategicpatch.StrategicMergeMapPatchUsingLookupPatchMeta(content, runtime.DeepCopyJSON(patchMap), meta)
+		}, nil
+	default:
+		return nil, errors.Errorf("unsupported patch type %q", patch.Type)
+	}
+}
+
+// newGoPatchFunc creates a go-patch operation, which works on the representation of yaml.v2
+func newGoPatchFunc(patch K8SResourceOverlayPatch) (overlayPatchFunc, error) {
+	op := ypatch.OpDefinition{
+		Type: string(patch.Type),
+		Path: patch.Path,
+	}
+	if patch.Type == ReplaceOverlayPatchType {
+		var value interface{}
+		if patch.ParseValue {
+			err := yaml.Unmarshal([]byte(utils.PointerToString(patch.Value)), &value)
+			if err != nil {
+				return nil, errors.WrapIf(err, "could not unmarshal value")
+			}
+		} else {
+			value = interface{}(patch.Value)
+		}
+		op.Value = &value
+	}
+
+	ops, err := ypatch.NewOpsFromDefinitions([]ypatch.OpDefinition{op})
+	if err != nil {
+		return nil, errors.WrapIf(err, "could not init patch ops from definitions")
+	}
+
+	return func(content map[string]interface{}, _ runtime.Object) (map[string]interface{}, error) {
+		res, err := ops.Apply(toYAMLValue(content))
+		if err != nil {
+			return nil, errors.WrapIf(err, "could not apply patch ops")
+		}
+
+		patched, ok := fromYAMLValue(res).(map[string]interface{})
+		if !ok {
+			return nil, errors.New("patched object is not a map")
+		}
+		return patched, nil
+	}, nil
+}
+
+// newJSONPatchFunc creates an RFC 6902 JSON patch operation
+func newJSONPatchFunc(patch K8SResourceOverlayPatch) (overlayPatchFunc, error) {
+	if err := validateJSONPointer("path", patch.Path); err != nil {
+		return nil, err
+	}
+	op := map[string]interface{}{
+		"op":   string(patch.Type),
+		"path": *patch.Path,
+	}
+
+	switch patch.Type {
+	case MoveOverlayPatchType, CopyOverlayPatchType:
+		if err := validateJSONPointer("from", patch.From); err != nil {
+			return nil, err
+		}
+		// not checked by the json-patch library, even though RFC 6902 forbids it
+		if patch.Type == MoveOverlayPatchType && strings.HasPrefix(*patch.Path+"/", *patch.From+"/") && *patch.Path != *patch.From {
+			return nil, errors.New("a location cannot be moved into one of its children")
+		}
+		op["from"] = *patch.From
+	default:
+		var value interface{}
+		if patch.ParseValue {
+			var err error
+			if value, err = parseJSONValue(utils.PointerToString(patch.Value)); err != nil {
+				return nil, errors.WrapIf(err, "could not unmarshal value")
+			}
+		} else if patch.Value != nil {
+			value = *patch.Value
+		}
+		op["value"] = value
+	}
+
+	patchJSON, err := json.Marshal([]interface{}{op})
+	if err != nil {
+		return nil, err
+	}
+	ops, err := jsonpatch.DecodePatch(patchJSON)
+	if err != nil {
+		return nil, errors.WrapIf(err, "could not decode patch")
+	}
+	options := jsonpatch.NewApplyOptions()
+	// negative array indices are an extension of the library, not part of RFC 6902
+	options.SupportNegativeIndices = false
+
+	return func(content map[string]interface{}, _ runtime.Object) (map[string]interface{}, error) {
+		return patchJSONContent(content, func(doc []byte) ([]byte, error) {
+			return ops.ApplyWithOptions(doc, options)
+		})
+	}, nil
+}
+
+// patchJSONContent applies a patch to the JSON representation of unstructured content
+func patchJSONContent(content map[string]interface{}, patch func([]byte) ([]byte, error)) (map[string]interface{}, error) {
+	doc, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	if doc, err = patch(doc); err != nil {
+		return nil, err
+	}
+	var patched map[string]interface{}
+	// keeps integers as int64, the way unstructured content represents them
+	if err := utiljson.Unmarshal(doc, &patched); err != nil {
+		return nil, errors.WrapIf(err, "patched object is not a map")
+	}
+	return patched, nil
+}
+
+// parseJSONValue parses a YAML or JSON value into the representation used by unstructured objects
+func parseJSONValue(value string) (interface{}, error) {
+	j, err := k8syaml.YAMLToJSON([]byte(value))
+	if err != nil {
+		return nil, err
+	}
+
+	var v interface{}
+	if err := utiljson.Unmarshal(j, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// validateJSONPointer checks that the field is an RFC 6901 JSON pointer, which are interpreted by the json-patch library
+func validateJSONPointer(field string, pointer *string) error {
+	if pointer == nil {
+		return errors.Errorf("%s is required", field)
+	}
+	if *pointer != "" && !strings.HasPrefix(*pointer, "/") {
+		return errors.Errorf("%s %q is not a JSON pointer", field, *pointer)
+	}
+	return nil
+}
+
+// mergePatch applies an RFC 7386 JSON merge patch to the content
+func mergePatch(content map[string]interface{}, patch []byte) (map[string]interface{}, error) {
+	return patchJSONContent(content, func(doc []byte) ([]byte, error) {
+		return jsonpatch.MergePatch(doc, patch)
+	})
+}
+
+// toYAMLValue converts unstructured content to the representation of yaml.v2
+func toYAMLValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		m := make(map[interface{}]interface{}, len(t))
+		for k, v := range t {
+			m[k] = toYAMLValue(v)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(t))
+		for i, v := range t {
+			l[i] = toYAMLValue(v)
+		}
+		return l
+	default:
+		return v
+	}
+}
+
+// fromYAMLValue converts the representation of yaml.v2 to unstructured content
+func fromYAMLValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			m[fmt.Sprint(k)] = fromYAMLValue(v)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			m[k] = fromYAMLValue(v)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(t))
+		for i, v := range t {
+			l[i] = fromYAMLValue(v)
+		}
+		return l
+	case *string:
+		if t == nil {
+			return nil
+		}
+		return *t
+	case int:
+		return int64(t)
+	case int32:
+		return int64(t)
+	case uint64:
+		return int64(t)
+	case float32:
+		return float64(t)
+	default:
+		return v
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"fmt"
+	"strings"
+
+	"emperror.dev/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// OverlayReport describes how a list of overlays has been applied to a set of objects.
+// It can be embedded into the status of CRDs.
+// +kubebuilder:object:generate=true
+type OverlayReport struct {
+	Overlays []OverlayResult `json:"overlays,omitempty"`
+}
+
+// OverlayResult describes the application of a single overlay
+// +kubebuilder:object:generate=true
+type OverlayResult struct {
+	// Index of the overlay in the list of overlays
+	Index int `json:"index"`
+	// Matched lists the objects targeted by the overlay in `Kind.group:namespace/name` format
+	Matched []string `json:"matched,omitempty"`
+	// Failures lists the patches that could not be applied to the targeted objects
+	Failures []OverlayPatchFailure `json:"failures,omitempty"`
+}
+
+// OverlayPatchFailure describes a patch of an overlay that could not be applied to an object
+type OverlayPatchFailure struct {
+	Object string `json:"object"`
+	// Patch is the index of the failed patch within the overlay, it is -1 if the failure is not specific to a patch
+	Patch int    `json:"patch"`
+	Error string `json:"error"`
+}
+
+func newOverlayReport(overlays int) *OverlayReport {
+	r := &OverlayReport{
+		Overlays: make([]OverlayResult, overlays),
+	}
+	for i := range r.Overlays {
+		r.Overlays[i].Index = i
+	}
+	return r
+}
+
+// Unmatched returns the indexes of the overlays that haven't matched any object
+func (r *OverlayReport) Unmatched() []int {
+	if r == nil {
+		return nil
+	}
+
+	var unmatched []int
+	for _, o := range r.Overlays {
+		if len(o.Matched) == 0 {
+			unmatched = append(unmatched, o.Index)
+		}
+	}
+	return unmatched
+}
+
+// HasFailures returns true if any of the patches failed
+func (r *OverlayReport) HasFailures() bool {
+	if r == nil {
+		return false
+	}
+
+	for _, o := range r.Overlays {
+		if len(o.Failures) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Err returns an error describing the failed patches and, in strict mode, the unmatched overlays
+func (r *OverlayReport) Err(strict bool) error {
+	if r == nil {
+		return nil
+	}
+
+	var combinedErr error
+	for _, o := range r.Overlays {
+		for _, f := range o.Failures {
+			combinedErr = errors.Combine(combinedErr, errors.Errorf("overlay #%d failed on %s: %s", o.Index, f.Object, f.Error))
+		}
+	}
+	if unmatched := r.Unmatched(); strict && len(unmatched) > 0 {
+		indexes := make([]string, 0, len(unmatched))
+		for _, i := range unmatched {
+			indexes = append(indexes, fmt.Sprintf("#%d", i))
+		}
+		combinedErr = errors.Combine(combinedErr, errors.Errorf("overlays matched no objects: %s", strings.Join(indexes, ", ")))
+	}
+
+	return combinedErr
+}
+
+func (r *OverlayReport) recordMatch(index int, object string) {
+	r.Overlays[index].Matched = append(r.Overlays[index].Matched, object)
+}
+
+func (r *OverlayReport) recordFailure(index int, object string, err error) {
+	failure := OverlayPatchFailure{
+		Object: object,
+		Patch:  -1,
+		Error:  err.Error(),
+	}
+	var patchErr *overlayPatchError
+	if errors.As(err, &patchErr) {
+		failure.Patch = patchErr.patch
+	}
+	r.Overlays[index].Failures = append(r.Overlays[index].Failures, failure)
+}
+
+// OverlayModifier creates a modifier that applies all the overlays and records their application into the returned report.
+// Each object is converted to unstructured content once, the overlays are matched against and applied to the content
+// in order, and the result is converted back to a typed object once.
+// Patch failures don't stop the modifier, they are recorded in the report and leave the object as it was
+// before the failing overlay, so that all the failures can be reported at once.
+func OverlayModifier(overlays []K8SResourceOverlay, parser *ObjectParser) (ObjectModifierFunc, *OverlayReport, error) {
+	report := newOverlayReport(len(overlays))
+
+	compiled := make([]*compiledOverlay, 0, len(overlays))
+	for i, overlay := range overlays {
+		c, err := compileOverlay(overlay)
+		if err != nil {
+			return nil, nil, errors.WrapIff(err, "invalid overlay #%d", i)
+		}
+		compiled = append(compiled, c)
+	}
+
+	return func(o runtime.Object) (runtime.Object, error) {
+		var content map[string]interface{}
+		current := o
+		for i, c := range compiled {
+			if !c.target.matches(current) {
+				continue
+			}
+			if content == nil {
+				var err error
+				if content, err = toUnstructuredContent(o); err != nil {
+					return o, err
+				}
+				current = &unstructured.Unstructured{Object: content}
+			}
+
+			ref := objectRef(current)
+			report.recordMatch(i, ref)
+			patched, err := c.apply(content, o)
+			if err != nil {
+				report.recordFailure(i, ref, err)
+				continue
+			}
+			content = patched
+			current = &unstructured.Unstructured{Object: content}
+		}
+
+		if content == nil {
+			return o, nil
+		}
+
+		return parser.fromUnstructuredContent(content)
+	}, report, nil
+}
+
+func objectRef(o runtime.Object) string {
+	gvk := o.GetObjectKind().GroupVersionKind()
+	gk := gvk.Kind
+	if gvk.Group != "" {
+		gk = gvk.Kind + "." + gvk.Group
+	}
+
+	objMeta, err := meta.Accessor(o)
+	if err != nil {
+		return gk
+	}
+	if objMeta.GetNamespace() != "" {
+		return fmt.Sprintf("%s:%s/%s", gk, objMeta.GetNamespace(), objMeta.GetName())
+	}
+	return fmt.Sprintf("%s:%s", gk, objMeta.GetName())
+}
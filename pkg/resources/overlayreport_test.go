@@ -0,0 +1,145 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+
+	"github.com/cisco-open/operator-tools/pkg/types"
+	"github.com/cisco-open/operator-tools/pkg/utils"
+)
+
+func TestOverlayModifiers(t *testing.T) {
+	objects := []runtime.Object{
+		&appsv1.Deployment{
+			TypeMeta:   metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test-ns"},
+			Spec:       appsv1.DeploymentSpec{Replicas: utils.IntPointer(1)},
+		},
+		&v1.Service{
+			TypeMeta:   metav1.TypeMeta{Kind: "Service", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test-ns"},
+		},
+	}
+
+	overlays := []K8SResourceOverlay{
+		{
+			// matches both objects, but the path only exists in the deployment
+			ObjectKey: types.ObjectKey{Name: "test"},
+			Patches: []K8SResourceOverlayPatch{
+				{Type: ReplaceOverlayPatchType, Path: utils.StringPointer("/spec/replicas"), Value: utils.StringPointer("3"), ParseValue: true},
+			},
+		},
+		{
+			Kinds: []string{"StatefulSet"},
+			Patches: []K8SResourceOverlayPatch{
+				{Type: DeleteOverlayPatchType, Path: utils.StringPointer("/spec/replicas")},
+			},
+		},
+	}
+
+	modifier, report, err := OverlayModifier(overlays, NewObjectParser(clientgoscheme.Scheme))
+	require.NoError(t, err)
+
+	var results []runtime.Object
+	for _, o := range objects {
+		o, err = modifier(o)
+		require.NoError(t, err)
+		results = append(results, o)
+	}
+
+	assert.Equal(t, int32(3), *results[0].(*appsv1.Deployment).Spec.Replicas)
+
+	require.Len(t, report.Overlays, 2)
+	assert.Equal(t, []string{"Deployment.apps:test-ns/test", "Service:test-ns/test"}, report.Overlays[0].Matched)
+	require.Len(t, report.Overlays[0].Failures, 1)
+	assert.Equal(t, "Service:test-ns/test", report.Overlays[0].Failures[0].Object)
+	assert.Equal(t, 0, report.Overlays[0].Failures[0].Patch)
+	assert.Empty(t, report.Overlays[1].Matched)
+
+	assert.True(t, report.HasFailures())
+	assert.Equal(t, []int{1}, report.Unmatched())
+	assert.ErrorContains(t, report.Err(false), "overlay #0 failed on Service:test-ns/test")
+	assert.NotContains(t, report.Err(false).Error(), "matched no objects")
+	assert.ErrorContains(t, report.Err(true), "overlays matched no objects: #1")
+
+	_, _, err = OverlayModifier([]K8SResourceOverlay{{}, {NamePattern: "["}}, NewObjectParser(clientgoscheme.Scheme))
+	assert.ErrorContains(t, err, "invalid overlay #1")
+}
+
+func TestOverlayModifierLayering(t *testing.T) {
+	deployment := &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test-ns", Annotations: map[string]string{"a/b": "c"}},
+		Spec: appsv1.DeploymentSpec{
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{{Name: "app", Image: "app:1.0"}},
+				},
+			},
+		},
+	}
+	service := &v1.Service{
+		TypeMeta:   metav1.TypeMeta{Kind: "Service", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "test-ns"},
+	}
+
+	overlays := []K8SResourceOverlay{
+		{
+			ObjectKey: types.ObjectKey{Name: "test"},
+			Patches: []K8SResourceOverlayPatch{
+				{Type: MergeOverlayPatchType, Value: utils.StringPointer("metadata: {labels: {tier: backend}}")},
+			},
+		},
+		{
+			// matches the label added by the previous overlay
+			LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "backend"}},
+			Patches: []K8SResourceOverlayPatch{
+				{Type: AddOverlayPatchType, Path: utils.StringPointer("/spec/template/spec/containers/-"), Value: utils.StringPointer("{name: sidecar, image: 'sidecar:1.0'}"), ParseValue: true},
+				{Type: MoveOverlayPatchType, From: utils.StringPointer("/metadata/annotations/a~1b"), Path: utils.StringPointer("/metadata/annotations/moved")},
+				{Type: AddOverlayPatchType, Path: utils.StringPointer("/spec/replicas"), Value: utils.StringPointer("2"), ParseValue: true},
+			},
+		},
+	}
+
+	modifier, report, err := OverlayModifier(overlays, NewObjectParser(clientgoscheme.Scheme))
+	require.NoError(t, err)
+
+	result, err := modifier(deployment)
+	require.NoError(t, err)
+	patched, ok := result.(*appsv1.Deployment)
+	require.True(t, ok, "object should stay typed")
+	assert.Equal(t, map[string]string{"tier": "backend"}, patched.Labels)
+	assert.Equal(t, map[string]string{"moved": "c"}, patched.Annotations)
+	assert.Equal(t, int32(2), *patched.Spec.Replicas)
+	require.Len(t, patched.Spec.Template.Spec.Containers, 2)
+	assert.Equal(t, "sidecar", patched.Spec.Template.Spec.Containers[1].Name)
+	assert.Nil(t, deployment.Labels, "the original object must not be modified")
+
+	result, err = modifier(service)
+	require.NoError(t, err)
+	assert.Same(t, service, result, "objects without matching overlays must be returned as is")
+
+	assert.False(t, report.HasFailures())
+	assert.Empty(t, report.Unmatched())
+}
@@ -0,0 +1,419 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"reflect"
+
+	"emperror.dev/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+
+	"github.com/cisco-open/operator-tools/pkg/utils"
+)
+
+// podSpecPaths holds the location of the pod spec within the pod-bearing kinds
+var podSpecPaths = map[string][]string{
+	"Pod":                   {"spec"},
+	"Deployment":            {"spec", "template", "spec"},
+	"DaemonSet":             {"spec", "template", "spec"},
+	"ReplicaSet":            {"spec", "template", "spec"},
+	"StatefulSet":           {"spec", "template", "spec"},
+	"ReplicationController": {"spec", "template", "spec"},
+	"Job":                   {"spec", "template", "spec"},
+	"CronJob":               {"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+// ImageOverride replaces the image of the containers selected by container name and/or image repository
+type ImageOverride struct {
+	ContainerName string
+	Repository    string
+	NewName       string
+	NewTag        string
+	Digest        string
+}
+
+// PodSpecModifier calls the function with the pod spec of every Pod, Deployment, DaemonSet, ReplicaSet,
+// StatefulSet, ReplicationController, Job and CronJob, regardless of being typed or unstructured
+func PodSpecModifier(fn func(spec *corev1.PodSpec) error) ObjectModifierFunc {
+	return func(obj runtime.Object) (runtime.Object, error) {
+		var spec *corev1.PodSpec
+		switch o := obj.(type) {
+		case *corev1.Pod:
+			spec = &o.Spec
+		case *appsv1.Deployment:
+			spec = &o.Spec.Template.Spec
+		case *appsv1.DaemonSet:
+			spec = &o.Spec.Template.Spec
+		case *appsv1.ReplicaSet:
+			spec = &o.Spec.Template.Spec
+		case *appsv1.StatefulSet:
+			spec = &o.Spec.Template.Spec
+		case *corev1.ReplicationController:
+			if o.Spec.Template == nil {
+				return obj, nil
+			}
+			spec = &o.Spec.Template.Spec
+		case *batchv1.Job:
+			spec = &o.Spec.Template.Spec
+		case *batchv1.CronJob:
+			spec = &o.Spec.JobTemplate.Spec.Template.Spec
+		case *batchv1beta1.CronJob:
+			spec = &o.Spec.JobTemplate.Spec.Template.Spec
+		case *unstructured.Unstructured:
+			return obj, modifyUnstructuredPodSpec(o, fn)
+		default:
+			return obj, nil
+		}
+
+		return obj, fn(spec)
+	}
+}
+
+func modifyUnstructuredPodSpec(u *unstructured.Unstructured, fn func(spec *corev1.PodSpec) error) error {
+	switch u.GroupVersionKind().Group {
+	case "", appsv1.GroupName, batchv1.GroupName, "extensions":
+	default:
+		return nil
+	}
+	path, ok := podSpecPaths[u.GetKind()]
+	if !ok {
+		return nil
+	}
+
+	return modifyUnstructuredContent(u, path, fn)
+}
+
+// PodTemplateModifier calls the function with the pod template of every Deployment, DaemonSet, ReplicaSet,
+// StatefulSet, ReplicationController, Job and CronJob, regardless of being typed or unstructured
+func PodTemplateModifier(fn func(template *corev1.PodTemplateSpec) error) ObjectModifierFunc {
+	return func(obj runtime.Object) (runtime.Object, error) {
+		var template *corev1.PodTemplateSpec
+		switch o := obj.(type) {
+		case *appsv1.Deployment:
+			template = &o.Spec.Template
+		case *appsv1.DaemonSet:
+			template = &o.Spec.Template
+		case *appsv1.ReplicaSet:
+			template = &o.Spec.Template
+		case *appsv1.StatefulSet:
+			template = &o.Spec.Template
+		case *corev1.ReplicationController:
+			if o.Spec.Template == nil {
+				return obj, nil
+			}
+			template = o.Spec.Template
+		case *batchv1.Job:
+			template = &o.Spec.Template
+		case *batchv1.CronJob:
+			template = &o.Spec.JobTemplate.Spec.Template
+		case *batchv1beta1.CronJob:
+			template = &o.Spec.JobTemplate.Spec.Template
+		case *unstructured.Unstructured:
+			return obj, modifyUnstructuredPodTemplate(o, fn)
+		default:
+			return obj, nil
+		}
+
+		return obj, fn(template)
+	}
+}
+
+func modifyUnstructuredPodTemplate(u *unstructured.Unstructured, fn func(template *corev1.PodTemplateSpec) error) error {
+	switch u.GroupVersionKind().Group {
+	case "", appsv1.GroupName, batchv1.GroupName, "extensions":
+	default:
+		return nil
+	}
+	specPath, ok := podSpecPaths[u.GetKind()]
+	if !ok || u.GetKind() == "Pod" {
+		return nil
+	}
+	// the pod template is the parent of the pod spec
+	return modifyUnstructuredContent(u, specPath[:len(specPath)-1], fn)
+}
+
+// modifyUnstructuredContent calls the function with the typed representation of the content at the path,
+// then applies the changes as a strategic merge patch, so the fields unknown to the type are kept
+func modifyUnstructuredContent[T any](u *unstructured.Unstructured, path []string, fn func(*T) error) error {
+	content, ok, err := unstructured.NestedMap(u.Object, path...)
+	if err != nil || !ok {
+		return err
+	}
+
+	typed := new(T)
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(content, typed); err != nil {
+		return errors.WrapIfWithDetails(err, "could not convert content", "kind", u.GetKind(), "name", u.GetName())
+	}
+	original, err := runtime.DefaultUnstructuredConverter.ToUnstructured(typed)
+	if err != nil {
+		return errors.WrapIfWithDetails(err, "could not convert content", "kind", u.GetKind(), "name", u.GetName())
+	}
+	if err := fn(typed); err != nil {
+		return err
+	}
+	modified, err := runtime.DefaultUnstructuredConverter.ToUnstructured(typed)
+	if err != nil {
+		return errors.WrapIfWithDetails(err, "could not convert content", "kind", u.GetKind(), "name", u.GetName())
+	}
+
+	patch, err := strategicpatch.CreateTwoWayMergeMapPatch(original, modified, typed)
+	if err != nil {
+		return errors.WrapIfWithDetails(err, "could not create patch", "kind", u.GetKind(), "name", u.GetName())
+	}
+	if len(patch) == 0 {
+		return nil
+	}
+	if content, err = strategicpatch.StrategicMergeMapPatch(content, patch, typed); err != nil {
+		return errors.WrapIfWithDetails(err, "could not apply patch", "kind", u.GetKind(), "name", u.GetName())
+	}
+
+	return unstructured.SetNestedMap(u.Object, content, path...)
+}
+
+// PodTemplateAnnotationsModifier sets the annotations on the pod templates, e.g. to roll the pods of workloads
+// when a checksum of their configuration changes
+func PodTemplateAnnotationsModifier(annotations map[string]string) ObjectModifierFunc {
+	return PodTemplateModifier(func(template *corev1.PodTemplateSpec) error {
+		if len(annotations) == 0 {
+			return nil
+		}
+		if template.Annotations == nil {
+			template.Annotations = make(map[string]string, len(annotations))
+		}
+		for k, v := range annotations {
+			template.Annotations[k] = v
+		}
+		return nil
+	})
+}
+
+// containers returns the init and regular containers of the pod spec with the given name, or all of them if the name is empty
+func containers(spec *corev1.PodSpec, name string) []*corev1.Container {
+	var selected []*corev1.Container
+	for _, list := range [][]corev1.Container{spec.InitContainers, spec.Containers} {
+		for i := range list {
+			if name == "" || list[i].Name == name {
+				selected = append(selected, &list[i])
+			}
+		}
+	}
+	return selected
+}
+
+// ImageOverrideModifier replaces the images of the matching containers
+func ImageOverrideModifier(overrides ...ImageOverride) ObjectModifierFunc {
+	return PodSpecModifier(func(spec *corev1.PodSpec) error {
+		for _, override := range overrides {
+			for _, c := range containers(spec, override.ContainerName) {
+				name, _, _ := ParseImage(c.Image)
+				if override.Repository != "" && name != override.Repository {
+					continue
+				}
+				c.Image = SubstituteImage(c.Image, ImageSubstitution{
+					Name:    name,
+					NewName: override.NewName,
+					NewTag:  override.NewTag,
+					Digest:  override.Digest,
+				})
+			}
+		}
+		return nil
+	})
+}
+
+// NodeSelectorModifier merges the node selector into the pod specs, overwriting existing keys
+func NodeSelectorModifier(nodeSelector map[string]string) ObjectModifierFunc {
+	return PodSpecModifier(func(spec *corev1.PodSpec) error {
+		if len(nodeSelector) == 0 {
+			return nil
+		}
+		if spec.NodeSelector == nil {
+			spec.NodeSelector = make(map[string]string, len(nodeSelector))
+		}
+		for k, v := range nodeSelector {
+			spec.NodeSelector[k] = v
+		}
+		return nil
+	})
+}
+
+// TolerationsModifier adds the tolerations to the pod specs unless they are already present
+func TolerationsModifier(tolerations ...corev1.Toleration) ObjectModifierFunc {
+	return PodSpecModifier(func(spec *corev1.PodSpec) error {
+	Tolerations:
+		for _, toleration := range tolerations {
+			for _, existing := range spec.Tolerations {
+				if reflect.DeepEqual(existing, toleration) {
+					continue Tolerations
+				}
+			}
+			spec.Tolerations = append(spec.Tolerations, toleration)
+		}
+		return nil
+	})
+}
+
+// AffinityModifier replaces the affinity of the pod specs
+func AffinityModifier(affinity *corev1.Affinity) ObjectModifierFunc {
+	return PodSpecModifier(func(spec *corev1.PodSpec) error {
+		spec.Affinity = affinity.DeepCopy()
+		return nil
+	})
+}
+
+// PriorityClassModifier sets the priority class of the pod specs
+func PriorityClassModifier(priorityClassName string) ObjectModifierFunc {
+	return PodSpecModifier(func(spec *corev1.PodSpec) error {
+		spec.PriorityClassName = priorityClassName
+		return nil
+	})
+}
+
+// TopologySpreadConstraintsModifier adds the constraints to the pod specs unless there is already
+// a constraint for the same topology key and unsatisfiable action
+func TopologySpreadConstraintsModifier(constraints ...corev1.TopologySpreadConstraint) ObjectModifierFunc {
+	return PodSpecModifier(func(spec *corev1.PodSpec) error {
+	Constraints:
+		for _, constraint := range constraints {
+			for _, existing := range spec.TopologySpreadConstraints {
+				if existing.TopologyKey == constraint.TopologyKey && existing.WhenUnsatisfiable == constraint.WhenUnsatisfiable {
+					continue Constraints
+				}
+			}
+			spec.TopologySpreadConstraints = append(spec.TopologySpreadConstraints, *constraint.DeepCopy())
+		}
+		return nil
+	})
+}
+
+// ResourceDefaultsModifier sets the requests and limits missing from the containers.
+// Defaults that would make a request exceed its limit are skipped.
+func ResourceDefaultsModifier(defaults corev1.ResourceRequirements) ObjectModifierFunc {
+	return PodSpecModifier(func(spec *corev1.PodSpec) error {
+		for _, c := range containers(spec, "") {
+			for name, request := range defaults.Requests {
+				if _, ok := c.Resources.Requests[name]; ok {
+					continue
+				}
+				if limit, ok := c.Resources.Limits[name]; ok && request.Cmp(limit) > 0 {
+					continue
+				}
+				if c.Resources.Requests == nil {
+					c.Resources.Requests = corev1.ResourceList{}
+				}
+				c.Resources.Requests[name] = request.DeepCopy()
+			}
+			for name, limit := range defaults.Limits {
+				if _, ok := c.Resources.Limits[name]; ok {
+					continue
+				}
+				if request, ok := c.Resources.Requests[name]; ok && request.Cmp(limit) > 0 {
+					continue
+				}
+				if c.Resources.Limits == nil {
+					c.Resources.Limits = corev1.ResourceList{}
+				}
+				c.Resources.Limits[name] = limit.DeepCopy()
+			}
+		}
+		return nil
+	})
+}
+
+// EnvModifier sets the environment variables on the containers with the given name, or on all containers if the name is empty.
+// Variables with the same name are replaced.
+func EnvModifier(containerName string, env ...corev1.EnvVar) ObjectModifierFunc {
+	return PodSpecModifier(func(spec *corev1.PodSpec) error {
+		for _, c := range containers(spec, containerName) {
+		Env:
+			for _, e := range env {
+				for i := range c.Env {
+					if c.Env[i].Name == e.Name {
+						c.Env[i] = *e.DeepCopy()
+						continue Env
+					}
+				}
+				c.Env = append(c.Env, *e.DeepCopy())
+			}
+		}
+		return nil
+	})
+}
+
+// VolumesModifier adds the volumes to the pod specs and mounts them into the containers with the given name,
+// or into all containers if the name is empty. Volumes with the same name and mounts with the same path are replaced.
+func VolumesModifier(containerName string, volumes []corev1.Volume, mounts ...corev1.VolumeMount) ObjectModifierFunc {
+	return PodSpecModifier(func(spec *corev1.PodSpec) error {
+	Volumes:
+		for _, v := range volumes {
+			for i := range spec.Volumes {
+				if spec.Volumes[i].Name == v.Name {
+					spec.Volumes[i] = *v.DeepCopy()
+					continue Volumes
+				}
+			}
+			spec.Volumes = append(spec.Volumes, *v.DeepCopy())
+		}
+
+		for _, c := range containers(spec, containerName) {
+		Mounts:
+			for _, m := range mounts {
+				for i := range c.VolumeMounts {
+					if c.VolumeMounts[i].MountPath == m.MountPath {
+						c.VolumeMounts[i] = m
+						continue Mounts
+					}
+				}
+				c.VolumeMounts = append(c.VolumeMounts, m)
+			}
+		}
+		return nil
+	})
+}
+
+// PodSecurityHardeningModifier applies the settings required by the restricted Pod Security Standard where they are unset:
+// non-root user, RuntimeDefault seccomp profile, no privilege escalation and all capabilities dropped
+func PodSecurityHardeningModifier(obj runtime.Object) (runtime.Object, error) {
+	return PodSpecModifier(func(spec *corev1.PodSpec) error {
+		if spec.SecurityContext == nil {
+			spec.SecurityContext = &corev1.PodSecurityContext{}
+		}
+		if spec.SecurityContext.RunAsNonRoot == nil {
+			spec.SecurityContext.RunAsNonRoot = utils.BoolPointer(true)
+		}
+		if spec.SecurityContext.SeccompProfile == nil {
+			spec.SecurityContext.SeccompProfile = &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault}
+		}
+
+		for _, c := range containers(spec, "") {
+			if c.SecurityContext == nil {
+				c.SecurityContext = &corev1.SecurityContext{}
+			}
+			if c.SecurityContext.AllowPrivilegeEscalation == nil {
+				c.SecurityContext.AllowPrivilegeEscalation = utils.BoolPointer(false)
+			}
+			if c.SecurityContext.Capabilities == nil {
+				c.SecurityContext.Capabilities = &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}}
+			}
+		}
+		return nil
+	})(obj)
+}
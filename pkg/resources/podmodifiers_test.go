@@ -0,0 +1,276 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/cisco-open/operator-tools/pkg/utils"
+)
+
+func testPodSpec() corev1.PodSpec {
+	return corev1.PodSpec{
+		InitContainers: []corev1.Container{
+			{Name: "init", Image: "busybox:1.36"},
+		},
+		Containers: []corev1.Container{
+			{
+				Name:  "app",
+				Image: "registry.example.com:5000/app:1.0",
+				Env:   []corev1.EnvVar{{Name: "LEVEL", Value: "info"}},
+				Resources: corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("64Mi")},
+				},
+			},
+			{Name: "sidecar", Image: "sidecar:1.0"},
+		},
+	}
+}
+
+// testPodBearingObjects returns the same pod spec in a typed deployment, a typed cron job and an unstructured job
+func testPodBearingObjects(t *testing.T) []runtime.Object {
+	spec := testPodSpec()
+	job := &batchv1.Job{
+		TypeMeta:   metav1.TypeMeta{Kind: "Job", APIVersion: "batch/v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		Spec:       batchv1.JobSpec{Template: corev1.PodTemplateSpec{Spec: *spec.DeepCopy()}},
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(job)
+	require.NoError(t, err)
+
+	return []runtime.Object{
+		&appsv1.Deployment{
+			TypeMeta:   metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "test"},
+			Spec:       appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: *spec.DeepCopy()}},
+		},
+		&batchv1.CronJob{
+			TypeMeta:   metav1.TypeMeta{Kind: "CronJob", APIVersion: "batch/v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "test"},
+			Spec: batchv1.CronJobSpec{JobTemplate: batchv1.JobTemplateSpec{Spec: batchv1.JobSpec{
+				Template: corev1.PodTemplateSpec{Spec: *spec.DeepCopy()},
+			}}},
+		},
+		&unstructured.Unstructured{Object: content},
+	}
+}
+
+func podSpecOf(t *testing.T, o runtime.Object) corev1.PodSpec {
+	switch obj := o.(type) {
+	case *appsv1.Deployment:
+		return obj.Spec.Template.Spec
+	case *batchv1.CronJob:
+		return obj.Spec.JobTemplate.Spec.Template.Spec
+	case *unstructured.Unstructured:
+		job := &batchv1.Job{}
+		require.NoError(t, runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, job))
+		return job.Spec.Template.Spec
+	}
+	t.Fatalf("unexpected object %T", o)
+	return corev1.PodSpec{}
+}
+
+func TestPodModifiers(t *testing.T) {
+	tests := map[string]struct {
+		modifier ObjectModifierFunc
+		want     func(spec *corev1.PodSpec)
+	}{
+		"image override by repository": {
+			modifier: ImageOverrideModifier(ImageOverride{Repository: "registry.example.com:5000/app", NewTag: "2.0"}),
+			want: func(spec *corev1.PodSpec) {
+				spec.Containers[0].Image = "registry.example.com:5000/app:2.0"
+			},
+		},
+		"image override by container name": {
+			modifier: ImageOverrideModifier(ImageOverride{ContainerName: "init", NewName: "mirror.example.com/busybox"}),
+			want: func(spec *corev1.PodSpec) {
+				spec.InitContainers[0].Image = "mirror.example.com/busybox:1.36"
+			},
+		},
+		"node selector, tolerations and affinity": {
+			modifier: chainModifiers(
+				NodeSelectorModifier(map[string]string{"pool": "system"}),
+				TolerationsModifier(corev1.Toleration{Key: "dedicated", Operator: corev1.TolerationOpExists}),
+				TolerationsModifier(corev1.Toleration{Key: "dedicated", Operator: corev1.TolerationOpExists}),
+				AffinityModifier(&corev1.Affinity{PodAntiAffinity: &corev1.PodAntiAffinity{}}),
+			),
+			want: func(spec *corev1.PodSpec) {
+				spec.NodeSelector = map[string]string{"pool": "system"}
+				spec.Tolerations = []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}}
+				spec.Affinity = &corev1.Affinity{PodAntiAffinity: &corev1.PodAntiAffinity{}}
+			},
+		},
+		"priority class and topology spread": {
+			modifier: chainModifiers(
+				PriorityClassModifier("high"),
+				TopologySpreadConstraintsModifier(corev1.TopologySpreadConstraint{MaxSkew: 1, TopologyKey: "zone", WhenUnsatisfiable: corev1.ScheduleAnyway}),
+			),
+			want: func(spec *corev1.PodSpec) {
+				spec.PriorityClassName = "high"
+				spec.TopologySpreadConstraints = []corev1.TopologySpreadConstraint{{MaxSkew: 1, TopologyKey: "zone", WhenUnsatisfiable: corev1.ScheduleAnyway}}
+			},
+		},
+		"resource defaults": {
+			modifier: ResourceDefaultsModifier(corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("128Mi")},
+				Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+			}),
+			want: func(spec *corev1.PodSpec) {
+				// the request default would exceed the existing limit of the app container
+				for _, c := range []*corev1.Container{&spec.InitContainers[0], &spec.Containers[1]} {
+					c.Resources = corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("128Mi")},
+						Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+					}
+				}
+			},
+		},
+		"env and volumes": {
+			modifier: chainModifiers(
+				EnvModifier("app", corev1.EnvVar{Name: "LEVEL", Value: "debug"}, corev1.EnvVar{Name: "EXTRA", Value: "1"}),
+				VolumesModifier("sidecar", []corev1.Volume{{Name: "config"}}, corev1.VolumeMount{Name: "config", MountPath: "/config"}),
+			),
+			want: func(spec *corev1.PodSpec) {
+				spec.Containers[0].Env = []corev1.EnvVar{{Name: "LEVEL", Value: "debug"}, {Name: "EXTRA", Value: "1"}}
+				spec.Volumes = []corev1.Volume{{Name: "config"}}
+				spec.Containers[1].VolumeMounts = []corev1.VolumeMount{{Name: "config", MountPath: "/config"}}
+			},
+		},
+		"pod security hardening": {
+			modifier: PodSecurityHardeningModifier,
+			want: func(spec *corev1.PodSpec) {
+				spec.SecurityContext = &corev1.PodSecurityContext{
+					RunAsNonRoot:   utils.BoolPointer(true),
+					SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+				}
+				for _, c := range []*corev1.Container{&spec.InitContainers[0], &spec.Containers[0], &spec.Containers[1]} {
+					c.SecurityContext = &corev1.SecurityContext{
+						AllowPrivilegeEscalation: utils.BoolPointer(false),
+						Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+					}
+				}
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			want := testPodSpec()
+			tt.want(&want)
+
+			for _, o := range testPodBearingObjects(t) {
+				modified, err := tt.modifier(o)
+				require.NoError(t, err)
+				assert.Equal(t, want, podSpecOf(t, modified), "%T", o)
+			}
+		})
+	}
+
+	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+	modified, err := PriorityClassModifier("high")(configMap)
+	require.NoError(t, err)
+	assert.Equal(t, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test"}}, modified)
+}
+
+func chainModifiers(modifiers ...ObjectModifierFunc) ObjectModifierFunc {
+	return func(o runtime.Object) (runtime.Object, error) {
+		var err error
+		for _, modifier := range modifiers {
+			if o, err = modifier(o); err != nil {
+				return nil, err
+			}
+		}
+		return o, nil
+	}
+}
+
+func TestPodTemplateAnnotationsModifier(t *testing.T) {
+	modifier := PodTemplateAnnotationsModifier(map[string]string{"checksum": "abc"})
+	for _, o := range testPodBearingObjects(t) {
+		modified, err := modifier(o)
+		require.NoError(t, err)
+
+		var annotations map[string]string
+		switch obj := modified.(type) {
+		case *appsv1.Deployment:
+			annotations = obj.Spec.Template.Annotations
+		case *batchv1.CronJob:
+			annotations = obj.Spec.JobTemplate.Spec.Template.Annotations
+		case *unstructured.Unstructured:
+			annotations, _, err = unstructured.NestedStringMap(obj.Object, "spec", "template", "metadata", "annotations")
+			require.NoError(t, err)
+		}
+		assert.Equal(t, map[string]string{"checksum": "abc"}, annotations, "%T", o)
+		assert.Equal(t, testPodSpec(), podSpecOf(t, modified), "%T", o)
+	}
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+	modified, err := modifier(pod)
+	require.NoError(t, err)
+	assert.Empty(t, modified.(*corev1.Pod).Annotations)
+}
+
+func TestUnstructuredPodModifiersKeepUnknownFields(t *testing.T) {
+	deployment := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "test"},
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"futureField": "kept",
+					"containers": []interface{}{
+						map[string]interface{}{"name": "app", "image": "app:1.0", "futureContainerField": int64(1)},
+						map[string]interface{}{"name": "sidecar", "image": "sidecar:1.0"},
+					},
+				},
+			},
+		},
+	}}
+
+	modified, err := chainModifiers(
+		EnvModifier("app", corev1.EnvVar{Name: "LEVEL", Value: "debug"}),
+		PodTemplateAnnotationsModifier(map[string]string{"checksum": "abc"}),
+	)(deployment)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{"checksum": "abc"},
+		},
+		"spec": map[string]interface{}{
+			"futureField": "kept",
+			"containers": []interface{}{
+				map[string]interface{}{
+					"name":                 "app",
+					"image":                "app:1.0",
+					"futureContainerField": int64(1),
+					"env":                  []interface{}{map[string]interface{}{"name": "LEVEL", "value": "debug"}},
+				},
+				map[string]interface{}{"name": "sidecar", "image": "sidecar:1.0"},
+			},
+		},
+	}, modified.(*unstructured.Unstructured).Object["spec"].(map[string]interface{})["template"])
+}
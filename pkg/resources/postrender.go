@@ -0,0 +1,528 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"emperror.dev/errors"
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"sigs.k8s.io/yaml"
+)
+
+// PostRenderer is a Kustomize-like declarative transformation of the full set of rendered objects.
+// Steps are applied in the following order: strategic merge patches, JSON 6902 patches, namespace,
+// name prefix and suffix, common labels and annotations and finally image substitutions.
+// +kubebuilder:object:generate=true
+type PostRenderer struct {
+	// Strategic merge patches as YAML documents, targeting objects by apiVersion, kind, name and namespace
+	PatchesStrategicMerge []string `json:"patchesStrategicMerge,omitempty"`
+	// RFC 6902 JSON patches with explicit targets
+	PatchesJSON6902 []JSON6902Patch `json:"patchesJson6902,omitempty"`
+	// Namespace to move all the namespaced objects into, along with the ServiceAccount subjects of their role bindings
+	Namespace string `json:"namespace,omitempty"`
+	// Prefix to prepend to the names of all objects, except CRDs and namespaces
+	NamePrefix string `json:"namePrefix,omitempty"`
+	// Suffix to append to the names of all objects, except CRDs and namespaces
+	NameSuffix string `json:"nameSuffix,omitempty"`
+	// Labels to add to all objects and pod templates. Selectors are left untouched as they are immutable.
+	CommonLabels map[string]string `json:"commonLabels,omitempty"`
+	// Annotations to add to all objects and pod templates
+	CommonAnnotations map[string]string `json:"commonAnnotations,omitempty"`
+	// Image substitutions matched by image name
+	Images []ImageSubstitution `json:"images,omitempty"`
+}
+
+// +kubebuilder:object:generate=true
+type JSON6902Patch struct {
+	Target PatchTarget `json:"target"`
+	// Patch operations in JSON or YAML format
+	Patch string `json:"patch"`
+}
+
+// +kubebuilder:object:generate=true
+type PatchTarget struct {
+	Group     string `json:"group,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// +kubebuilder:object:generate=true
+type ImageSubstitution struct {
+	// Name of the image to substitute without tag or digest
+	Name string `json:"name"`
+	// NewName replaces the name of the image
+	NewName string `json:"newName,omitempty"`
+	// NewTag replaces the tag of the image
+	NewTag string `json:"newTag,omitempty"`
+	// Digest replaces the tag of the image with a digest
+	Digest string `json:"digest,omitempty"`
+}
+
+func (t PatchTarget) matches(u *unstructured.Unstructured) bool {
+	gvk := u.GroupVersionKind()
+	return (t.Group == "" || t.Group == gvk.Group) &&
+		(t.Version == "" || t.Version == gvk.Version) &&
+		(t.Kind == "" || t.Kind == gvk.Kind) &&
+		(t.Name == "" || t.Name == u.GetName()) &&
+		(t.Namespace == "" || t.Namespace == u.GetNamespace())
+}
+
+type postRenderObject struct {
+	original runtime.Object
+	object   *unstructured.Unstructured
+}
+
+// Apply runs the post-rendering steps on the given objects. Objects keep their original Go type.
+func (p *PostRenderer) Apply(objects []runtime.Object) ([]runtime.Object, error) {
+	if p == nil {
+		return objects, nil
+	}
+
+	items := make([]*postRenderObject, 0, len(objects))
+	for _, o := range objects {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(o)
+		if err != nil {
+			return nil, errors.WrapIf(err, "could not convert object to unstructured")
+		}
+		items = append(items, &postRenderObject{
+			original: o,
+			object:   &unstructured.Unstructured{Object: content},
+		})
+	}
+
+	for i, patch := range p.PatchesStrategicMerge {
+		if err := applyStrategicMergePatch(items, patch); err != nil {
+			return nil, errors.WrapIfWithDetails(err, "could not apply strategic merge patch", "index", i)
+		}
+	}
+
+	for i, patch := range p.PatchesJSON6902 {
+		if err := applyJSON6902Patch(items, patch); err != nil {
+			return nil, errors.WrapIfWithDetails(err, "could not apply json 6902 patch", "index", i)
+		}
+	}
+
+	if p.Namespace != "" {
+		rewriteNamespace(items, p.Namespace)
+	}
+
+	if p.NamePrefix != "" || p.NameSuffix != "" {
+		rename(items, p.NamePrefix, p.NameSuffix)
+	}
+
+	for _, item := range items {
+		addCommonMetadata(item.object.Object, p.CommonLabels, p.CommonAnnotations)
+		for _, image := range p.Images {
+			substituteImage(item.object.Object, image)
+		}
+	}
+
+	result := make([]runtime.Object, 0, len(items))
+	for _, item := range items {
+		o, err := fromPostRenderObject(item)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, o)
+	}
+
+	return result, nil
+}
+
+func fromPostRenderObject(item *postRenderObject) (runtime.Object, error) {
+	if _, ok := item.original.(*unstructured.Unstructured); ok {
+		return item.object, nil
+	}
+
+	o := reflect.New(reflect.TypeOf(item.original).Elem()).Interface().(runtime.Object)
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.object.Object, o); err != nil {
+		return nil, errors.WrapIfWithDetails(err, "could not convert post-rendered object", "name", item.object.GetName())
+	}
+
+	return o, nil
+}
+
+func applyStrategicMergePatch(items []*postRenderObject, patch string) error {
+	patchJSON, err := yaml.YAMLToJSON([]byte(patch))
+	if err != nil {
+		return errors.WrapIf(err, "could not parse patch")
+	}
+
+	target := &unstructured.Unstructured{}
+	if err := target.UnmarshalJSON(patchJSON); err != nil {
+		return errors.WrapIf(err, "patch must identify its target by apiVersion, kind and name")
+	}
+
+	matched := false
+	for _, item := range items {
+		if item.object.GetAPIVersion() != target.GetAPIVersion() || item.object.GetKind() != target.GetKind() ||
+			item.object.GetName() != target.GetName() ||
+			(target.GetNamespace() != "" && item.object.GetNamespace() != target.GetNamespace()) {
+			continue
+		}
+		matched = true
+
+		original, err := json.Marshal(item.object.Object)
+		if err != nil {
+			return err
+		}
+
+		var patched []byte
+		if _, ok := item.original.(*unstructured.Unstructured); ok {
+			// no patch metadata is available for unstructured objects
+			patched, err = jsonpatch.MergePatch(original, patchJSON)
+		} else {
+			var meta strategicpatch.LookupPatchMeta
+			meta, err = strategicpatch.NewPatchMetaFromStruct(item.original)
+			if err != nil {
+				return err
+			}
+			patched, err = strategicpatch.StrategicMergePatchUsingLookupPatchMeta(original, patchJSON, meta)
+		}
+		if err != nil {
+			return errors.WrapIfWithDetails(err, "could not patch object", "name", item.object.GetName())
+		}
+
+		if err := item.object.UnmarshalJSON(patched); err != nil {
+			return err
+		}
+	}
+
+	if !matched {
+		return errors.Errorf("no object matches %s %s", target.GetKind(), target.GetName())
+	}
+
+	return nil
+}
+
+func applyJSON6902Patch(items []*postRenderObject, patch JSON6902Patch) error {
+	patchJSON, err := yaml.YAMLToJSON([]byte(patch.Patch))
+	if err != nil {
+		return errors.WrapIf(err, "could not parse patch")
+	}
+
+	ops, err := jsonpatch.DecodePatch(patchJSON)
+	if err != nil {
+		return errors.WrapIf(err, "could not decode patch")
+	}
+
+	for _, item := range items {
+		if !patch.Target.matches(item.object) {
+			continue
+		}
+
+		original, err := json.Marshal(item.object.Object)
+		if err != nil {
+			return err
+		}
+
+		patched, err := ops.Apply(original)
+		if err != nil {
+			return errors.WrapIfWithDetails(err, "could not patch object", "name", item.object.GetName())
+		}
+
+		if err := item.object.UnmarshalJSON(patched); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rewriteNamespace moves the namespaced objects and the ServiceAccount subjects of role bindings that refer to the
+// namespaces of the moved objects, which is the release namespace, or have no namespace at all
+func rewriteNamespace(items []*postRenderObject, namespace string) {
+	moved := sets.New[string]()
+	for _, item := range items {
+		previous := item.object.GetNamespace()
+		// cluster scoped objects and objects relying on the release namespace are left alone
+		if previous != "" {
+			moved.Insert(previous)
+			item.object.SetNamespace(namespace)
+		}
+	}
+
+	for _, item := range items {
+		switch item.object.GetKind() {
+		case "RoleBinding", "ClusterRoleBinding":
+			subjects, _, _ := unstructured.NestedSlice(item.object.Object, "subjects")
+			for _, s := range subjects {
+				subject, ok := s.(map[string]interface{})
+				if !ok || subject["kind"] != "ServiceAccount" {
+					continue
+				}
+				// service accounts of other namespaces, e.g. kube-system, are not part of the release
+				if previous, _ := subject["namespace"].(string); previous == "" || moved.Has(previous) {
+					subject["namespace"] = namespace
+				}
+			}
+			if subjects != nil {
+				_ = unstructured.SetNestedSlice(item.object.Object, subjects, "subjects")
+			}
+		}
+	}
+}
+
+func rename(items []*postRenderObject, prefix, suffix string) {
+	renamed := make(map[string]map[string]string)
+	for _, item := range items {
+		kind := item.object.GetKind()
+		if kind == "CustomResourceDefinition" || kind == "Namespace" {
+			continue
+		}
+		name := item.object.GetName()
+		newName := prefix + name + suffix
+		item.object.SetName(newName)
+		if renamed[kind] == nil {
+			renamed[kind] = make(map[string]string)
+		}
+		renamed[kind][name] = newName
+	}
+
+	for _, item := range items {
+		updateNameReferences(item.object, renamed)
+	}
+}
+
+// updateNameReferences follows the most common references between the objects of a release
+func updateNameReferences(u *unstructured.Unstructured, renamed map[string]map[string]string) {
+	ref := func(kind string, fields ...string) func(m map[string]interface{}) {
+		return func(m map[string]interface{}) {
+			if name, ok, _ := unstructured.NestedString(m, fields...); ok {
+				if newName, ok := renamed[kind][name]; ok {
+					_ = unstructured.SetNestedField(m, newName, fields...)
+				}
+			}
+		}
+	}
+
+	forEach := func(m map[string]interface{}, field string, fn func(map[string]interface{})) {
+		items, _, _ := unstructured.NestedSlice(m, field)
+		for _, i := range items {
+			if item, ok := i.(map[string]interface{}); ok {
+				fn(item)
+			}
+		}
+		if items != nil {
+			_ = unstructured.SetNestedSlice(m, items, field)
+		}
+	}
+
+	for _, podSpec := range podSpecs(u.Object) {
+		ref("ServiceAccount", "serviceAccountName")(podSpec)
+		forEach(podSpec, "imagePullSecrets", ref("Secret", "name"))
+		forEach(podSpec, "volumes", func(volume map[string]interface{}) {
+			ref("ConfigMap", "configMap", "name")(volume)
+			ref("Secret", "secret", "secretName")(volume)
+			ref("PersistentVolumeClaim", "persistentVolumeClaim", "claimName")(volume)
+			if projected, ok := volume["projected"].(map[string]interface{}); ok {
+				forEach(projected, "sources", func(source map[string]interface{}) {
+					ref("ConfigMap", "configMap", "name")(source)
+					ref("Secret", "secret", "name")(source)
+				})
+			}
+		})
+		for _, containers := range []string{"initContainers", "containers"} {
+			forEach(podSpec, containers, func(container map[string]interface{}) {
+				forEach(container, "env", func(env map[string]interface{}) {
+					ref("ConfigMap", "valueFrom", "configMapKeyRef", "name")(env)
+					ref("Secret", "valueFrom", "secretKeyRef", "name")(env)
+				})
+				forEach(container, "envFrom", func(envFrom map[string]interface{}) {
+					ref("ConfigMap", "configMapRef", "name")(envFrom)
+					ref("Secret", "secretRef", "name")(envFrom)
+				})
+			})
+		}
+	}
+
+	switch u.GetKind() {
+	case "RoleBinding", "ClusterRoleBinding":
+		if kind, ok, _ := unstructured.NestedString(u.Object, "roleRef", "kind"); ok {
+			ref(kind, "roleRef", "name")(u.Object)
+		}
+		forEach(u.Object, "subjects", func(subject map[string]interface{}) {
+			if subject["kind"] == "ServiceAccount" {
+				ref("ServiceAccount", "name")(subject)
+			}
+		})
+	case "StatefulSet":
+		ref("Service", "spec", "serviceName")(u.Object)
+	}
+}
+
+// podSpecs returns the pod specs embedded into the object in place
+func podSpecs(object map[string]interface{}) []map[string]interface{} {
+	var paths [][]string
+	switch object["kind"] {
+	case "Pod":
+		paths = [][]string{{"spec"}}
+	case "CronJob":
+		paths = [][]string{{"spec", "jobTemplate", "spec", "template", "spec"}}
+	default:
+		paths = [][]string{{"spec", "template", "spec"}}
+	}
+
+	var specs []map[string]interface{}
+	for _, path := range paths {
+		if spec, ok := nestedMap(object, path...); ok {
+			specs = append(specs, spec)
+		}
+	}
+	return specs
+}
+
+// podTemplateMetadata returns the pod template metadata embedded into the object in place, creating it if necessary
+func podTemplateMetadata(object map[string]interface{}) map[string]interface{} {
+	var path []string
+	switch object["kind"] {
+	case "CronJob":
+		path = []string{"spec", "jobTemplate", "spec", "template"}
+	default:
+		path = []string{"spec", "template"}
+	}
+
+	template, ok := nestedMap(object, path...)
+	if !ok {
+		return nil
+	}
+	if _, ok := template["spec"].(map[string]interface{}); !ok {
+		return nil
+	}
+	metadata, ok := template["metadata"].(map[string]interface{})
+	if !ok {
+		metadata = make(map[string]interface{})
+		template["metadata"] = metadata
+	}
+	return metadata
+}
+
+func nestedMap(object map[string]interface{}, fields ...string) (map[string]interface{}, bool) {
+	m := object
+	for _, field := range fields {
+		next, ok := m[field].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		m = next
+	}
+	return m, true
+}
+
+func addCommonMetadata(object map[string]interface{}, labels, annotations map[string]string) {
+	if len(labels) == 0 && len(annotations) == 0 {
+		return
+	}
+
+	metadatas := []map[string]interface{}{}
+	if metadata, ok := object["metadata"].(map[string]interface{}); ok {
+		metadatas = append(metadatas, metadata)
+	}
+	if metadata := podTemplateMetadata(object); metadata != nil {
+		metadatas = append(metadatas, metadata)
+	}
+
+	for _, metadata := range metadatas {
+		mergeStringMap(metadata, "labels", labels)
+		mergeStringMap(metadata, "annotations", annotations)
+	}
+}
+
+func mergeStringMap(metadata map[string]interface{}, field string, values map[string]string) {
+	if len(values) == 0 {
+		return
+	}
+	m, ok := metadata[field].(map[string]interface{})
+	if !ok {
+		m = make(map[string]interface{}, len(values))
+		metadata[field] = m
+	}
+	for k, v := range values {
+		m[k] = v
+	}
+}
+
+func substituteImage(object map[string]interface{}, substitution ImageSubstitution) {
+	for _, podSpec := range podSpecs(object) {
+		for _, field := range []string{"initContainers", "containers"} {
+			containers, ok := podSpec[field].([]interface{})
+			if !ok {
+				continue
+			}
+			for _, c := range containers {
+				container, ok := c.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				image, ok := container["image"].(string)
+				if !ok {
+					continue
+				}
+				container["image"] = SubstituteImage(image, substitution)
+			}
+		}
+	}
+}
+
+// SubstituteImage returns the image reference with the substitution applied if the image name matches
+func SubstituteImage(image string, substitution ImageSubstitution) string {
+	name, tag, digest := ParseImage(image)
+	if name != substitution.Name {
+		return image
+	}
+
+	if substitution.NewName != "" {
+		name = substitution.NewName
+	}
+	if substitution.NewTag != "" {
+		tag = substitution.NewTag
+		digest = ""
+	}
+	if substitution.Digest != "" {
+		tag = ""
+		digest = substitution.Digest
+	}
+
+	switch {
+	case digest != "":
+		return name + "@" + digest
+	case tag != "":
+		return name + ":" + tag
+	default:
+		return name
+	}
+}
+
+// ParseImage splits an image reference to name, tag and digest
+func ParseImage(image string) (name, tag, digest string) {
+	name = image
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, digest = name[:i], name[i+1:]
+	}
+	// a colon after the last slash separates the tag, otherwise it belongs to the registry port
+	if i := strings.LastIndex(name, ":"); i >= 0 && i > strings.LastIndex(name, "/") {
+		name, tag = name[:i], name[i+1:]
+	}
+	return name, tag, digest
+}
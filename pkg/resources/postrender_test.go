@@ -0,0 +1,199 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func postRenderTestObjects() []runtime.Object {
+	configMap := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "config",
+			Namespace: "default",
+		},
+	}
+
+	deployment := &appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Deployment",
+			APIVersion: "apps/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "app",
+			Namespace: "default",
+		},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": "app"},
+			},
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  "app",
+							Image: "registry.example.com:5000/app:1.0",
+						},
+						{
+							Name:  "sidecar",
+							Image: "sidecar:1.0",
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: "config",
+							VolumeSource: corev1.VolumeSource{
+								ConfigMap: &corev1.ConfigMapVolumeSource{
+									LocalObjectReference: corev1.LocalObjectReference{Name: "config"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	custom := &unstructured.Unstructured{}
+	custom.SetAPIVersion("example.com/v1")
+	custom.SetKind("Custom")
+	custom.SetName("custom")
+
+	return []runtime.Object{configMap, deployment, custom}
+}
+
+func TestPostRenderer(t *testing.T) {
+	postRenderer := &PostRenderer{
+		PatchesStrategicMerge: []string{`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      containers:
+      - name: sidecar
+        args: ["--verbose"]
+`},
+		PatchesJSON6902: []JSON6902Patch{
+			{
+				Target: PatchTarget{Kind: "Custom"},
+				Patch:  `[{"op": "add", "path": "/spec", "value": {"enabled": true}}]`,
+			},
+		},
+		Namespace:         "other",
+		NamePrefix:        "pre-",
+		NameSuffix:        "-suf",
+		CommonLabels:      map[string]string{"team": "a"},
+		CommonAnnotations: map[string]string{"note": "b"},
+		Images: []ImageSubstitution{
+			{
+				Name:   "registry.example.com:5000/app",
+				NewTag: "2.0",
+			},
+		},
+	}
+
+	objects, err := postRenderer.Apply(postRenderTestObjects())
+	require.NoError(t, err)
+	require.Len(t, objects, 3)
+
+	configMap, ok := objects[0].(*corev1.ConfigMap)
+	require.True(t, ok, "object should keep its type")
+	assert.Equal(t, "pre-config-suf", configMap.Name)
+	assert.Equal(t, "other", configMap.Namespace)
+	assert.Equal(t, map[string]string{"team": "a"}, configMap.Labels)
+
+	deployment, ok := objects[1].(*appsv1.Deployment)
+	require.True(t, ok, "object should keep its type")
+	assert.Equal(t, "pre-app-suf", deployment.Name)
+	assert.Equal(t, map[string]string{"app": "app"}, deployment.Spec.Selector.MatchLabels)
+	assert.Equal(t, map[string]string{"team": "a"}, deployment.Spec.Template.Labels)
+	assert.Equal(t, map[string]string{"note": "b"}, deployment.Spec.Template.Annotations)
+	assert.Equal(t, "registry.example.com:5000/app:2.0", deployment.Spec.Template.Spec.Containers[0].Image)
+	assert.Equal(t, "sidecar:1.0", deployment.Spec.Template.Spec.Containers[1].Image)
+	assert.Equal(t, []string{"--verbose"}, deployment.Spec.Template.Spec.Containers[1].Args)
+	assert.Equal(t, "pre-config-suf", deployment.Spec.Template.Spec.Volumes[0].ConfigMap.Name)
+
+	custom, ok := objects[2].(*unstructured.Unstructured)
+	require.True(t, ok, "object should keep its type")
+	assert.Equal(t, "pre-custom-suf", custom.GetName())
+	assert.Equal(t, "", custom.GetNamespace())
+	enabled, _, _ := unstructured.NestedBool(custom.Object, "spec", "enabled")
+	assert.True(t, enabled)
+}
+
+func TestPostRendererNamespaceSubjects(t *testing.T) {
+	binding := &rbacv1.RoleBinding{
+		TypeMeta:   metav1.TypeMeta{Kind: "RoleBinding", APIVersion: "rbac.authorization.k8s.io/v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Subjects: []rbacv1.Subject{
+			{Kind: rbacv1.ServiceAccountKind, Name: "app", Namespace: "default"},
+			{Kind: rbacv1.ServiceAccountKind, Name: "unqualified"},
+			{Kind: rbacv1.ServiceAccountKind, Name: "controller", Namespace: "kube-system"},
+			{Kind: rbacv1.UserKind, Name: "admin", APIGroup: rbacv1.GroupName},
+		},
+	}
+
+	objects, err := (&PostRenderer{Namespace: "other"}).Apply([]runtime.Object{binding})
+	require.NoError(t, err)
+
+	assert.Equal(t, []rbacv1.Subject{
+		{Kind: rbacv1.ServiceAccountKind, Name: "app", Namespace: "other"},
+		{Kind: rbacv1.ServiceAccountKind, Name: "unqualified", Namespace: "other"},
+		{Kind: rbacv1.ServiceAccountKind, Name: "controller", Namespace: "kube-system"},
+		{Kind: rbacv1.UserKind, Name: "admin", APIGroup: rbacv1.GroupName},
+	}, objects[0].(*rbacv1.RoleBinding).Subjects)
+}
+
+func TestPostRendererUnmatchedStrategicMergePatch(t *testing.T) {
+	postRenderer := &PostRenderer{
+		PatchesStrategicMerge: []string{`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: missing
+`},
+	}
+
+	_, err := postRenderer.Apply(postRenderTestObjects())
+	assert.ErrorContains(t, err, "no object matches Deployment missing")
+}
+
+func TestParseImage(t *testing.T) {
+	for image, want := range map[string][3]string{
+		"nginx":                                {"nginx", "", ""},
+		"nginx:1.25":                           {"nginx", "1.25", ""},
+		"localhost:5000/nginx":                 {"localhost:5000/nginx", "", ""},
+		"localhost:5000/nginx:1.25@sha256:abc": {"localhost:5000/nginx", "1.25", "sha256:abc"},
+	} {
+		name, tag, digest := ParseImage(image)
+		assert.Equal(t, want, [3]string{name, tag, digest}, image)
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+
+	"emperror.dev/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/cisco-open/operator-tools/pkg/reconciler"
+	"github.com/cisco-open/operator-tools/pkg/resources"
+)
+
+var invalidKeyChars = regexp.MustCompile(`[^-._a-zA-Z0-9]`)
+
+// SanitizeKey turns the key into a valid Secret key. Valid keys are returned as they are, otherwise
+// the invalid characters are replaced and a hash of the original key is appended to avoid collisions.
+func SanitizeKey(key string) string {
+	if len(validation.IsConfigMapKey(key)) == 0 {
+		return key
+	}
+	sum := sha256.Sum256([]byte(key))
+	suffix := "-" + hex.EncodeToString(sum[:])[:8]
+	sanitized := strings.Trim(invalidKeyChars.ReplaceAllString(key, "_"), ".")
+	if maxLength := validation.DNS1123SubdomainMaxLength - len(suffix); len(sanitized) > maxLength {
+		sanitized = sanitized[:maxLength]
+	}
+	return sanitized + suffix
+}
+
+// mountKey returns the key a mounted secret is stored with in the aggregated secret
+func mountKey(parts ...string) string {
+	return SanitizeKey(strings.Join(parts, "-"))
+}
+
+// AggregatedSecret is a Secret holding all the secrets loaded with MountFrom, to be mounted to the mount path
+// of the SecretLoader. Build the secret after the secrets have been loaded.
+type AggregatedSecret struct {
+	name       string
+	namespace  string
+	mountPath  string
+	secrets    *MountSecrets
+	volumeName string
+	labels     map[string]string
+}
+
+var _ reconciler.ResourceBuilder = (&AggregatedSecret{}).Build
+
+type AggregatedSecretOption = func(s *AggregatedSecret)
+
+// WithVolumeName sets the name of the volume the secret is mounted through, defaults to the name of the secret
+func WithVolumeName(name string) AggregatedSecretOption {
+	return func(s *AggregatedSecret) {
+		s.volumeName = name
+	}
+}
+
+// WithLabels sets the labels of the secret
+func WithLabels(labels map[string]string) AggregatedSecretOption {
+	return func(s *AggregatedSecret) {
+		s.labels = labels
+	}
+}
+
+// NewAggregatedSecret returns the secret collecting the mounted secrets, the mount path should be the one
+// the SecretLoader was created with
+func NewAggregatedSecret(name, namespace, mountPath string, secrets *MountSecrets, opts ...AggregatedSecretOption) *AggregatedSecret {
+	s := &AggregatedSecret{
+		name:       name,
+		namespace:  namespace,
+		mountPath:  mountPath,
+		secrets:    secrets,
+		volumeName: name,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Data returns the values of the mounted secrets by their sanitized mapped keys. Secrets loaded more than once
+// are stored once, but it's an error if different values are mapped to the same key.
+func (s *AggregatedSecret) Data() (map[string][]byte, error) {
+	if s.secrets == nil {
+		return map[string][]byte{}, nil
+	}
+	data := make(map[string][]byte, len(*s.secrets))
+	for _, secret := range *s.secrets {
+		key := SanitizeKey(secret.MappedKey)
+		if value, ok := data[key]; ok && !bytes.Equal(value, secret.Value) {
+			return nil, errors.NewWithDetails("conflicting values for the same secret key", "key", key, "secret", secret.Name)
+		}
+		data[key] = secret.Value
+	}
+	return data, nil
+}
+
+// Build returns the secret, it's a ResourceBuilder
+func (s *AggregatedSecret) Build() (runtime.Object, reconciler.DesiredState, error) {
+	data, err := s.Data()
+	if err != nil {
+		return nil, nil, err
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      s.name,
+			Namespace: s.namespace,
+			Labels:    s.labels,
+		},
+		Data: data,
+	}, reconciler.StatePresent, nil
+}
+
+// Volume returns the volume of the secret
+func (s *AggregatedSecret) Volume() corev1.Volume {
+	return corev1.Volume{
+		Name: s.volumeName,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{SecretName: s.name},
+		},
+	}
+}
+
+// VolumeMount returns the read-only mount of the volume at the mount path
+func (s *AggregatedSecret) VolumeMount() corev1.VolumeMount {
+	return corev1.VolumeMount{
+		Name:      s.volumeName,
+		MountPath: s.mountPath,
+		ReadOnly:  true,
+	}
+}
+
+// ApplyToPodSpec adds the volume to the pod spec and mounts it into the container, replacing the volume with the same
+// name and the mount at the same path
+func (s *AggregatedSecret) ApplyToPodSpec(spec *corev1.PodSpec, containerName string) error {
+	container := findContainer(spec, containerName)
+	if container == nil {
+		return errors.NewWithDetails("container not found in pod spec", "container", containerName)
+	}
+
+	volume := s.Volume()
+	found := false
+	for i := range spec.Volumes {
+		if spec.Volumes[i].Name == volume.Name {
+			spec.Volumes[i] = volume
+			found = true
+		}
+	}
+	if !found {
+		spec.Volumes = append(spec.Volumes, volume)
+	}
+
+	mount := s.VolumeMount()
+	for i := range container.VolumeMounts {
+		if container.VolumeMounts[i].MountPath == mount.MountPath {
+			container.VolumeMounts[i] = mount
+			return nil
+		}
+	}
+	container.VolumeMounts = append(container.VolumeMounts, mount)
+	return nil
+}
+
+// PodSpecModifier applies the volume and the mount to the pod spec of workloads, see ApplyToPodSpec
+func (s *AggregatedSecret) PodSpecModifier(containerName string) resources.ObjectModifierFunc {
+	return resources.PodSpecModifier(func(spec *corev1.PodSpec) error {
+		return s.ApplyToPodSpec(spec, containerName)
+	})
+}
+
+func findContainer(spec *corev1.PodSpec, name string) *corev1.Container {
+	for _, containers := range [][]corev1.Container{spec.InitContainers, spec.Containers} {
+		for i := range containers {
+			if containers[i].Name == name {
+				return &containers[i]
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/cisco-open/operator-tools/pkg/reconciler"
+)
+
+func TestSanitizeKey(t *testing.T) {
+	assert.Equal(t, "default-credentials-password", SanitizeKey("default-credentials-password"))
+
+	sanitized := SanitizeKey("vault-app-db-user name")
+	assert.True(t, strings.HasPrefix(sanitized, "vault-app-db-user_name-"), sanitized)
+	assert.NotEqual(t, sanitized, SanitizeKey("vault-app-db-user:name"))
+	assert.Equal(t, sanitized, SanitizeKey("vault-app-db-user name"))
+
+	for _, key := range []string{"..", strings.Repeat("a", 300), strings.Repeat("ü", 200)} {
+		assert.Empty(t, validation.IsConfigMapKey(SanitizeKey(key)), key)
+	}
+}
+
+func TestAggregatedSecret(t *testing.T) {
+	mountSecrets := &MountSecrets{}
+	loader := NewSecretLoader(testReader(), "default", "/secrets", mountSecrets)
+	for _, s := range []*Secret{
+		{MountFrom: &ValueFrom{SecretKeyRef: secretKeyRef("credentials", "password")}},
+		{MountFrom: &ValueFrom{SecretKeyRef: secretKeyRef("credentials", "password")}},
+		{MountFrom: &ValueFrom{ConfigMapKeyRef: configMapKeyRef("config", "ca.crt")}},
+	} {
+		_, err := loader.Load(s)
+		require.NoError(t, err)
+	}
+
+	aggregated := NewAggregatedSecret("app-secrets", "default", "/secrets", mountSecrets, WithLabels(map[string]string{"app": "test"}))
+	var builder reconciler.ResourceBuilder = aggregated.Build
+	object, state, err := builder()
+	require.NoError(t, err)
+	assert.Equal(t, reconciler.StatePresent, state)
+	secret := object.(*corev1.Secret)
+	assert.Equal(t, "app-secrets", secret.Name)
+	assert.Equal(t, map[string]string{"app": "test"}, secret.Labels)
+	assert.Equal(t, map[string][]byte{
+		"default-credentials-password":    []byte("secret"),
+		"default-configmap-config-ca.crt": []byte("ca"),
+	}, secret.Data)
+
+	*mountSecrets = append(*mountSecrets, MountSecret{MappedKey: "default-credentials-password", Value: []byte("other")})
+	_, _, err = aggregated.Build()
+	assert.Error(t, err)
+
+	spec := &corev1.PodSpec{
+		Containers: []corev1.Container{
+			{Name: "app", VolumeMounts: []corev1.VolumeMount{{Name: "old", MountPath: "/secrets"}}},
+			{Name: "sidecar"},
+		},
+		Volumes: []corev1.Volume{{Name: "app-secrets"}},
+	}
+	require.NoError(t, aggregated.ApplyToPodSpec(spec, "app"))
+	require.NoError(t, aggregated.ApplyToPodSpec(spec, "app"))
+	assert.Equal(t, []corev1.Volume{{
+		Name:         "app-secrets",
+		VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "app-secrets"}},
+	}}, spec.Volumes)
+	assert.Equal(t, []corev1.VolumeMount{{Name: "app-secrets", MountPath: "/secrets", ReadOnly: true}}, spec.Containers[0].VolumeMounts)
+	assert.Empty(t, spec.Containers[1].VolumeMounts)
+	assert.Error(t, aggregated.ApplyToPodSpec(spec, "missing"))
+
+	deployment := &appsv1.Deployment{}
+	deployment.Spec.Template.Spec.Containers = []corev1.Container{{Name: "app"}}
+	_, err = NewAggregatedSecret("app-secrets", "default", "/secrets", mountSecrets, WithVolumeName("secrets")).PodSpecModifier("app")(deployment)
+	require.NoError(t, err)
+	assert.Equal(t, "secrets", deployment.Spec.Template.Spec.Volumes[0].Name)
+	assert.Equal(t, "secrets", deployment.Spec.Template.Spec.Containers[0].VolumeMounts[0].Name)
+}
+
+func TestSecretLoaderSanitizesMountedKeys(t *testing.T) {
+	mountSecrets := &MountSecrets{}
+	provider := NewFSProvider(fstest.MapFS{"app/db/user name": {Data: []byte("admin")}})
+	loader := NewSecretLoader(testReader(), "default", "/secrets", mountSecrets, WithProvider("files", provider))
+
+	path, err := loader.Load(&Secret{MountFrom: &ValueFrom{ProviderKeyRef: &ProviderKeySelector{Provider: "files", Path: "app/db", Key: "user name"}}})
+	require.NoError(t, err)
+	data, err := NewAggregatedSecret("app-secrets", "default", "/secrets", mountSecrets).Data()
+	require.NoError(t, err)
+	require.Len(t, data, 1)
+	for key, value := range data {
+		assert.Equal(t, "/secrets/"+key, path)
+		assert.Equal(t, []byte("admin"), value)
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"sync"
+
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/cisco-open/operator-tools/pkg/resources"
+)
+
+// ChecksumAnnotation is set on pod templates by the ChecksumModifier to roll the pods when the loaded secrets change
+const ChecksumAnnotation = "banzaicloud.io/secret-checksum"
+
+// Reference identifies a Secret or ConfigMap a SecretLoader has read
+type Reference struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// LoadedSecrets records what the SecretLoaders configured with WithLoadedSecrets have loaded.
+// The zero value is ready to use and it's safe to share between loaders of a single reconcile.
+type LoadedSecrets struct {
+	mu     sync.Mutex
+	refs   map[Reference]struct{}
+	values map[loadedKey][]byte
+}
+
+// loadedKey identifies a loaded value, kind is the provider for the values of external secret stores
+type loadedKey struct {
+	kind      string
+	namespace string
+	name      string
+	key       string
+}
+
+func (l *LoadedSecrets) add(ref Reference, key string, value []byte) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.refs == nil {
+		l.refs = make(map[Reference]struct{})
+		l.values = make(map[loadedKey][]byte)
+	}
+	// provider values have no object to watch, they only contribute to the checksum
+	if ref.Namespace != "" {
+		l.refs[ref] = struct{}{}
+	}
+	l.values[loadedKey{kind: ref.Kind, namespace: ref.Namespace, name: ref.Name, key: key}] = value
+}
+
+// References returns the loaded Secrets and ConfigMaps in a stable order
+func (l *LoadedSecrets) References() []Reference {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	refs := make([]Reference, 0, len(l.refs))
+	for ref := range l.refs {
+		refs = append(refs, ref)
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].Kind != refs[j].Kind {
+			return refs[i].Kind < refs[j].Kind
+		}
+		if refs[i].Namespace != refs[j].Namespace {
+			return refs[i].Namespace < refs[j].Namespace
+		}
+		return refs[i].Name < refs[j].Name
+	})
+	return refs
+}
+
+// Checksum returns the hex encoded SHA-256 checksum of the loaded values, or an empty string if nothing was loaded
+func (l *LoadedSecrets) Checksum() string {
+	if l == nil {
+		return ""
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.values) == 0 {
+		return ""
+	}
+	keys := make([]loadedKey, 0, len(l.values))
+	for key := range l.values {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.kind != b.kind {
+			return a.kind < b.kind
+		}
+		if a.namespace != b.namespace {
+			return a.namespace < b.namespace
+		}
+		if a.name != b.name {
+			return a.name < b.name
+		}
+		return a.key < b.key
+	})
+	hash := sha256.New()
+	for _, key := range keys {
+		// the zero bytes separate the fields, so that the boundaries can't be shifted between them
+		for _, field := range []string{key.kind, key.namespace, key.name, key.key} {
+			hash.Write([]byte(field))
+			hash.Write([]byte{0})
+		}
+		hash.Write(l.values[key])
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// ChecksumModifier stamps the checksum of the loaded secrets onto the pod templates of workloads, so that their pods
+// are rolled when a referenced secret changes. The checksum is computed when the modifier runs, after the secrets
+// have been loaded. Jobs are skipped as their pod template is immutable.
+func ChecksumModifier(loaded *LoadedSecrets) resources.ObjectModifierFunc {
+	return func(o runtime.Object) (runtime.Object, error) {
+		// typed objects usually have no type meta set
+		if _, ok := o.(*batchv1.Job); ok || o.GetObjectKind().GroupVersionKind().GroupKind() == batchv1.SchemeGroupVersion.WithKind("Job").GroupKind() {
+			return o, nil
+		}
+		checksum := loaded.Checksum()
+		if checksum == "" {
+			return o, nil
+		}
+		return resources.PodTemplateAnnotationsModifier(map[string]string{ChecksumAnnotation: checksum})(o)
+	}
+}
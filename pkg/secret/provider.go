@@ -0,0 +1,99 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"emperror.dev/errors"
+)
+
+// ErrNotFound is wrapped by the errors of providers when the secret or the key does not exist
+const ErrNotFound = errors.Sentinel("secret not found")
+
+// Provider loads secrets from an external secret store, register it with WithProvider
+type Provider interface {
+	// Get returns the value of the key of the secret at the path, or an error wrapping ErrNotFound if either is missing
+	Get(ctx context.Context, path, key string) ([]byte, error)
+}
+
+// ProviderError is returned by the SecretLoader when a provider fails to load a secret
+type ProviderError struct {
+	Provider string
+	Path     string
+	Key      string
+	Err      error
+}
+
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("failed to load key %q of secret %q from provider %s: %s", e.Key, e.Path, e.Provider, e.Err)
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// FileProvider reads secrets laid out like a mounted secret volume, a directory per secret and a file per key.
+// Useful in tests, or to read secrets mounted into the operator by an external tool.
+type FileProvider struct {
+	fsys fs.FS
+}
+
+var _ Provider = &FileProvider{}
+
+// NewFileProvider returns a provider reading the secrets from the directory
+func NewFileProvider(dir string) *FileProvider {
+	return NewFSProvider(os.DirFS(dir))
+}
+
+// NewFSProvider returns a provider reading the secrets from the file system, e.g. an fstest.MapFS in tests
+func NewFSProvider(fsys fs.FS) *FileProvider {
+	return &FileProvider{fsys: fsys}
+}
+
+// FileError is returned by the FileProvider when a key can't be read
+type FileError struct {
+	File string
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	return fmt.Sprintf("failed to read %s: %s", e.File, e.Err)
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+func (p *FileProvider) Get(_ context.Context, secretPath, key string) ([]byte, error) {
+	file := path.Join(strings.Trim(secretPath, "/"), key)
+	// fs.ValidPath rejects paths escaping the directory, e.g. through ".."
+	if !fs.ValidPath(file) || strings.Contains(key, "/") {
+		return nil, &FileError{File: file, Err: errors.New("invalid secret path or key")}
+	}
+	value, err := fs.ReadFile(p.fsys, file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, &FileError{File: file, Err: ErrNotFound}
+	}
+	if err != nil {
+		return nil, &FileError{File: file, Err: err}
+	}
+	return value, nil
+}
@@ -16,7 +16,9 @@ package secret
 
 import (
 	"context"
-	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	"emperror.dev/errors"
 	corev1 "k8s.io/api/core/v1"
@@ -28,13 +30,45 @@ type SecretLoader interface {
 	Load(secret *Secret) (string, error)
 }
 
-func NewSecretLoader(client client.Reader, namespace, mountPath string, secrets *MountSecrets) SecretLoader {
-	return &secretLoader{
+type SecretLoaderOption = func(l *secretLoader)
+
+// WithProvider registers an external secret store the secrets can refer to by name through ProviderKeyRef
+func WithProvider(name string, provider Provider) SecretLoaderOption {
+	return func(l *secretLoader) {
+		l.providers[name] = provider
+	}
+}
+
+// WithCacheTTL expires the cached secrets, config maps and provider values after the given duration.
+// Values are cached for the lifetime of the loader by default, which is usually a single reconcile.
+func WithCacheTTL(ttl time.Duration) SecretLoaderOption {
+	return func(l *secretLoader) {
+		l.cacheTTL = ttl
+	}
+}
+
+// WithLoadedSecrets records the loaded secrets, to watch the referenced objects and to roll the workloads using them
+// when they change, see ReferenceIndex and ChecksumModifier
+func WithLoadedSecrets(loaded *LoadedSecrets) SecretLoaderOption {
+	return func(l *secretLoader) {
+		l.loaded = loaded
+	}
+}
+
+func NewSecretLoader(client client.Reader, namespace, mountPath string, secrets *MountSecrets, opts ...SecretLoaderOption) SecretLoader {
+	loader := &secretLoader{
 		client:    client,
 		mountPath: mountPath,
 		namespace: namespace,
 		secrets:   secrets,
+		providers: make(map[string]Provider),
+		cache:     make(map[cacheKey]cacheEntry),
+		now:       time.Now,
 	}
+	for _, opt := range opts {
+		opt(loader)
+	}
+	return loader
 }
 
 type MountSecrets []MountSecret
@@ -63,6 +97,26 @@ type secretLoader struct {
 	mountPath string
 	client    client.Reader
 	secrets   *MountSecrets
+	providers map[string]Provider
+	loaded    *LoadedSecrets
+
+	mu       sync.Mutex
+	cache    map[cacheKey]cacheEntry
+	cacheTTL time.Duration
+	now      func() time.Time
+}
+
+// cacheKey identifies a secret or config map by kind and name, or a provider value by provider, path and key
+type cacheKey struct {
+	kind string
+	name string
+	key  string
+}
+
+type cacheEntry struct {
+	data    map[string][]byte
+	value   []byte
+	expires time.Time
 }
 
 func (k *secretLoader) Load(secret *Secret) (string, error) {
@@ -71,38 +125,161 @@ func (k *secretLoader) Load(secret *Secret) (string, error) {
 	}
 
 	if secret.MountFrom != nil && secret.MountFrom.SecretKeyRef != nil {
-		mappedKey := fmt.Sprintf("%s-%s-%s", k.namespace, secret.MountFrom.SecretKeyRef.Name, secret.MountFrom.SecretKeyRef.Key)
-		secretItem := &corev1.Secret{}
-		err := k.client.Get(context.TODO(), types.NamespacedName{
-			Name:      secret.MountFrom.SecretKeyRef.Name,
-			Namespace: k.namespace}, secretItem)
+		mappedKey := mountKey(k.namespace, secret.MountFrom.SecretKeyRef.Name, secret.MountFrom.SecretKeyRef.Key)
+		data, err := k.secretData(secret.MountFrom.SecretKeyRef.Name)
 		if err != nil {
 			return "", errors.WrapIfWithDetails(
 				err, "failed to load secret", "secret", secret.MountFrom.SecretKeyRef.Name, "namespace", k.namespace)
 		}
-		k.secrets.Append(k.namespace, secret.MountFrom.SecretKeyRef, mappedKey, secretItem.Data[secret.MountFrom.SecretKeyRef.Key])
+		k.loaded.add(Reference{Kind: "Secret", Namespace: k.namespace, Name: secret.MountFrom.SecretKeyRef.Name},
+			secret.MountFrom.SecretKeyRef.Key, data[secret.MountFrom.SecretKeyRef.Key])
+		k.secrets.Append(k.namespace, secret.MountFrom.SecretKeyRef, mappedKey, data[secret.MountFrom.SecretKeyRef.Key])
 		return k.mountPath + "/" + mappedKey, nil
 	}
 
+	if secret.MountFrom != nil && (secret.MountFrom.ConfigMapKeyRef != nil || secret.MountFrom.ProviderKeyRef != nil) {
+		value, mountSecret, err := k.loadValue(secret.MountFrom)
+		if err != nil {
+			return "", err
+		}
+		mountSecret.Value = value
+		*k.secrets = append(*k.secrets, mountSecret)
+		return k.mountPath + "/" + mountSecret.MappedKey, nil
+	}
+
 	if secret.ValueFrom != nil && secret.ValueFrom.SecretKeyRef != nil {
-		k8sSecret := &corev1.Secret{}
-		err := k.client.Get(context.TODO(), types.NamespacedName{
-			Name:      secret.ValueFrom.SecretKeyRef.Name,
-			Namespace: k.namespace}, k8sSecret)
+		data, err := k.secretData(secret.ValueFrom.SecretKeyRef.Name)
 		if err != nil {
 			return "", errors.WrapIff(err, "failed to get kubernetes secret %s:%s",
 				k.namespace,
 				secret.ValueFrom.SecretKeyRef.Name)
 		}
-		value, ok := k8sSecret.Data[secret.ValueFrom.SecretKeyRef.Key]
+		value, ok := data[secret.ValueFrom.SecretKeyRef.Key]
 		if !ok {
 			return "", errors.Errorf("key %q not found in secret %q in namespace %q",
 				secret.ValueFrom.SecretKeyRef.Key,
 				secret.ValueFrom.SecretKeyRef.Name,
 				k.namespace)
 		}
+		k.loaded.add(Reference{Kind: "Secret", Namespace: k.namespace, Name: secret.ValueFrom.SecretKeyRef.Name},
+			secret.ValueFrom.SecretKeyRef.Key, value)
 		return string(value), nil
 	}
 
+	if secret.ValueFrom != nil && (secret.ValueFrom.ConfigMapKeyRef != nil || secret.ValueFrom.ProviderKeyRef != nil) {
+		value, _, err := k.loadValue(secret.ValueFrom)
+		return string(value), err
+	}
+
 	return "", errors.New("No secret Value or ValueFrom defined for field")
 }
+
+// loadValue loads the value of a config map key or a provider secret along with the secret to mount it through
+func (k *secretLoader) loadValue(valueFrom *ValueFrom) ([]byte, MountSecret, error) {
+	if ref := valueFrom.ConfigMapKeyRef; ref != nil {
+		mountSecret := MountSecret{
+			Namespace: k.namespace,
+			Name:      ref.Name,
+			Key:       ref.Key,
+			MappedKey: mountKey(k.namespace, "configmap", ref.Name, ref.Key),
+		}
+		data, err := k.configMapData(ref.Name)
+		if err != nil {
+			return nil, mountSecret, errors.WrapIff(err, "failed to get kubernetes config map %s:%s", k.namespace, ref.Name)
+		}
+		value, ok := data[ref.Key]
+		if !ok {
+			return nil, mountSecret, errors.Errorf("key %q not found in config map %q in namespace %q", ref.Key, ref.Name, k.namespace)
+		}
+		k.loaded.add(Reference{Kind: "ConfigMap", Namespace: k.namespace, Name: ref.Name}, ref.Key, value)
+		return value, mountSecret, nil
+	}
+
+	ref := valueFrom.ProviderKeyRef
+	mountSecret := MountSecret{
+		Name:      ref.Path,
+		Key:       ref.Key,
+		MappedKey: mountKey(ref.Provider, strings.ReplaceAll(strings.Trim(ref.Path, "/"), "/", "-"), ref.Key),
+	}
+	value, err := k.providerValue(ref)
+	if err != nil {
+		return nil, mountSecret, err
+	}
+	k.loaded.add(Reference{Kind: "Provider/" + ref.Provider, Name: ref.Path}, ref.Key, value)
+	return value, mountSecret, nil
+}
+
+func (k *secretLoader) secretData(name string) (map[string][]byte, error) {
+	return k.cachedData(cacheKey{kind: "Secret", name: name}, func() (map[string][]byte, error) {
+		secret := &corev1.Secret{}
+		err := k.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: k.namespace}, secret)
+		return secret.Data, err
+	})
+}
+
+func (k *secretLoader) configMapData(name string) (map[string][]byte, error) {
+	return k.cachedData(cacheKey{kind: "ConfigMap", name: name}, func() (map[string][]byte, error) {
+		configMap := &corev1.ConfigMap{}
+		if err := k.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: k.namespace}, configMap); err != nil {
+			return nil, err
+		}
+		data := make(map[string][]byte, len(configMap.Data)+len(configMap.BinaryData))
+		for key, value := range configMap.Data {
+			data[key] = []byte(value)
+		}
+		for key, value := range configMap.BinaryData {
+			data[key] = value
+		}
+		return data, nil
+	})
+}
+
+func (k *secretLoader) providerValue(ref *ProviderKeySelector) ([]byte, error) {
+	provider, ok := k.providers[ref.Provider]
+	if !ok {
+		return nil, errors.Errorf("secret provider %q is not registered", ref.Provider)
+	}
+	key := cacheKey{kind: "Provider/" + ref.Provider, name: ref.Path, key: ref.Key}
+	if entry, ok := k.cached(key); ok {
+		return entry.value, nil
+	}
+	value, err := provider.Get(context.TODO(), ref.Path, ref.Key)
+	if err != nil {
+		return nil, &ProviderError{Provider: ref.Provider, Path: ref.Path, Key: ref.Key, Err: err}
+	}
+	k.store(key, cacheEntry{value: value})
+	return value, nil
+}
+
+// cachedData returns the cached data of an object, or loads and caches it. Failures are not cached.
+func (k *secretLoader) cachedData(key cacheKey, load func() (map[string][]byte, error)) (map[string][]byte, error) {
+	if entry, ok := k.cached(key); ok {
+		return entry.data, nil
+	}
+	data, err := load()
+	if err != nil {
+		return nil, err
+	}
+	k.store(key, cacheEntry{data: data})
+	return data, nil
+}
+
+func (k *secretLoader) cached(key cacheKey) (cacheEntry, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	entry, ok := k.cache[key]
+	if ok && k.cacheTTL > 0 && !k.now().Before(entry.expires) {
+		delete(k.cache, key)
+		return cacheEntry{}, false
+	}
+	return entry, ok
+}
+
+func (k *secretLoader) store(key cacheKey, entry cacheEntry) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.cacheTTL > 0 {
+		entry.expires = k.now().Add(k.cacheTTL)
+	}
+	k.cache[key] = entry
+}
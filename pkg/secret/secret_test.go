@@ -0,0 +1,329 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// countingReader counts the reads of the underlying client
+type countingReader struct {
+	client.Reader
+	gets int
+}
+
+func (r *countingReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	r.gets++
+	return r.Reader.Get(ctx, key, obj, opts...)
+}
+
+func testReader() *countingReader {
+	return &countingReader{Reader: fake.NewClientBuilder().WithObjects(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "credentials", Namespace: "default"},
+			Data:       map[string][]byte{"password": []byte("secret")},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "default"},
+			Data:       map[string]string{"endpoint": "https://example.com"},
+			BinaryData: map[string][]byte{"ca.crt": []byte("ca")},
+		},
+	).Build()}
+}
+
+func secretKeyRef(name, key string) *corev1.SecretKeySelector {
+	return &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: name}, Key: key}
+}
+
+func configMapKeyRef(name, key string) *corev1.ConfigMapKeySelector {
+	return &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: name}, Key: key}
+}
+
+func TestSecretLoaderConfigMap(t *testing.T) {
+	mountSecrets := &MountSecrets{}
+	loader := NewSecretLoader(testReader(), "default", "/secrets", mountSecrets)
+
+	value, err := loader.Load(&Secret{ValueFrom: &ValueFrom{ConfigMapKeyRef: configMapKeyRef("config", "endpoint")}})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", value)
+
+	path, err := loader.Load(&Secret{MountFrom: &ValueFrom{ConfigMapKeyRef: configMapKeyRef("config", "ca.crt")}})
+	require.NoError(t, err)
+	assert.Equal(t, "/secrets/default-configmap-config-ca.crt", path)
+	assert.Equal(t, MountSecrets{{
+		Namespace: "default",
+		Name:      "config",
+		Key:       "ca.crt",
+		MappedKey: "default-configmap-config-ca.crt",
+		Value:     []byte("ca"),
+	}}, *mountSecrets)
+
+	_, err = loader.Load(&Secret{ValueFrom: &ValueFrom{ConfigMapKeyRef: configMapKeyRef("config", "missing")}})
+	assert.Error(t, err)
+}
+
+func TestSecretLoaderCache(t *testing.T) {
+	reader := testReader()
+	now := time.Now()
+	loader := NewSecretLoader(reader, "default", "/secrets", &MountSecrets{}, WithCacheTTL(time.Minute)).(*secretLoader)
+	loader.now = func() time.Time { return now }
+
+	for _, s := range []*Secret{
+		{ValueFrom: &ValueFrom{SecretKeyRef: secretKeyRef("credentials", "password")}},
+		{MountFrom: &ValueFrom{SecretKeyRef: secretKeyRef("credentials", "password")}},
+		{ValueFrom: &ValueFrom{ConfigMapKeyRef: configMapKeyRef("config", "endpoint")}},
+		{ValueFrom: &ValueFrom{ConfigMapKeyRef: configMapKeyRef("config", "ca.crt")}},
+	} {
+		_, err := loader.Load(s)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, reader.gets)
+
+	// failures are not cached
+	_, err := loader.Load(&Secret{ValueFrom: &ValueFrom{SecretKeyRef: secretKeyRef("missing", "password")}})
+	require.Error(t, err)
+	_, err = loader.Load(&Secret{ValueFrom: &ValueFrom{SecretKeyRef: secretKeyRef("missing", "password")}})
+	require.Error(t, err)
+	assert.Equal(t, 4, reader.gets)
+
+	now = now.Add(time.Minute)
+	_, err = loader.Load(&Secret{ValueFrom: &ValueFrom{SecretKeyRef: secretKeyRef("credentials", "password")}})
+	require.NoError(t, err)
+	assert.Equal(t, 5, reader.gets)
+}
+
+func TestSecretLoaderFileProvider(t *testing.T) {
+	provider := NewFSProvider(fstest.MapFS{
+		"app/db/password": {Data: []byte("file-secret")},
+	})
+	mountSecrets := &MountSecrets{}
+	loader := NewSecretLoader(testReader(), "default", "/secrets", mountSecrets, WithProvider("files", provider))
+
+	value, err := loader.Load(&Secret{ValueFrom: &ValueFrom{ProviderKeyRef: &ProviderKeySelector{Provider: "files", Path: "/app/db", Key: "password"}}})
+	require.NoError(t, err)
+	assert.Equal(t, "file-secret", value)
+
+	path, err := loader.Load(&Secret{MountFrom: &ValueFrom{ProviderKeyRef: &ProviderKeySelector{Provider: "files", Path: "/app/db", Key: "password"}}})
+	require.NoError(t, err)
+	assert.Equal(t, "/secrets/files-app-db-password", path)
+	require.Len(t, *mountSecrets, 1)
+	assert.Equal(t, []byte("file-secret"), (*mountSecrets)[0].Value)
+
+	_, err = loader.Load(&Secret{ValueFrom: &ValueFrom{ProviderKeyRef: &ProviderKeySelector{Provider: "files", Path: "app/db", Key: "user"}}})
+	var providerErr *ProviderError
+	require.True(t, errors.As(err, &providerErr))
+	assert.Equal(t, "files", providerErr.Provider)
+	var fileErr *FileError
+	require.True(t, errors.As(err, &fileErr))
+	assert.Equal(t, "app/db/user", fileErr.File)
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	_, err = loader.Load(&Secret{ValueFrom: &ValueFrom{ProviderKeyRef: &ProviderKeySelector{Provider: "files", Path: "../etc", Key: "passwd"}}})
+	require.True(t, errors.As(err, &fileErr))
+	assert.False(t, errors.Is(err, ErrNotFound))
+
+	_, err = loader.Load(&Secret{ValueFrom: &ValueFrom{ProviderKeyRef: &ProviderKeySelector{Provider: "vault", Path: "app", Key: "password"}}})
+	assert.EqualError(t, err, `secret provider "vault" is not registered`)
+}
+
+func TestVaultProvider(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors": ["permission denied"]}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/kv/data/app/db":
+			_, _ = w.Write([]byte(`{"data": {"data": {"password": "vault-secret", "port": 5432}, "metadata": {"version": 1}}}`))
+		case "/v1/kv/data/app/my db?":
+			_, _ = w.Write([]byte(`{"data": {"data": {"password": "escaped"}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors": []}`))
+		}
+	}))
+	defer server.Close()
+
+	provider, err := NewVaultProvider(server.URL, "token", WithVaultMount("/kv/"))
+	require.NoError(t, err)
+	loader := NewSecretLoader(testReader(), "default", "/secrets", &MountSecrets{}, WithProvider("vault", provider))
+
+	value, err := loader.Load(&Secret{ValueFrom: &ValueFrom{ProviderKeyRef: &ProviderKeySelector{Provider: "vault", Path: "app/db", Key: "password"}}})
+	require.NoError(t, err)
+	assert.Equal(t, "vault-secret", value)
+	value, err = loader.Load(&Secret{ValueFrom: &ValueFrom{ProviderKeyRef: &ProviderKeySelector{Provider: "vault", Path: "app/db", Key: "password"}}})
+	require.NoError(t, err)
+	assert.Equal(t, "vault-secret", value)
+	assert.Equal(t, 1, requests)
+
+	value, err = loader.Load(&Secret{ValueFrom: &ValueFrom{ProviderKeyRef: &ProviderKeySelector{Provider: "vault", Path: "app/db", Key: "port"}}})
+	require.NoError(t, err)
+	assert.Equal(t, "5432", value)
+
+	_, err = loader.Load(&Secret{ValueFrom: &ValueFrom{ProviderKeyRef: &ProviderKeySelector{Provider: "vault", Path: "app/missing", Key: "password"}}})
+	var vaultErr *VaultError
+	require.True(t, errors.As(err, &vaultErr))
+	assert.Equal(t, http.StatusNotFound, vaultErr.StatusCode)
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	_, err = loader.Load(&Secret{ValueFrom: &ValueFrom{ProviderKeyRef: &ProviderKeySelector{Provider: "vault", Path: "app/db", Key: "user"}}})
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	// paths escaping the mount are rejected without calling vault
+	for _, secretPath := range []string{"../sys/policy", "app/../../sys", "app//db", "/"} {
+		_, err = provider.Get(context.Background(), secretPath, "password")
+		assert.Error(t, err, secretPath)
+	}
+	assert.Equal(t, 4, requests)
+
+	value, err = loader.Load(&Secret{ValueFrom: &ValueFrom{ProviderKeyRef: &ProviderKeySelector{Provider: "vault", Path: "app/my db?", Key: "password"}}})
+	require.NoError(t, err)
+	assert.Equal(t, "escaped", value)
+
+	scoped, err := NewVaultProvider(server.URL, "token", WithVaultMount("kv"), WithVaultPathPrefix("/app/"))
+	require.NoError(t, err)
+	bytes, err := scoped.Get(context.Background(), "db", "password")
+	require.NoError(t, err)
+	assert.Equal(t, "vault-secret", string(bytes))
+	_, err = scoped.Get(context.Background(), "../app/db", "password")
+	assert.Error(t, err)
+
+	unauthorized, err := NewVaultProvider(server.URL, "invalid", WithVaultMount("kv"))
+	require.NoError(t, err)
+	_, err = unauthorized.Get(context.Background(), "app/db", "password")
+	require.True(t, errors.As(err, &vaultErr))
+	assert.Equal(t, []string{"permission denied"}, vaultErr.Errors)
+	assert.False(t, errors.Is(err, ErrNotFound))
+
+	_, err = NewVaultProvider(server.URL, "token", WithVaultKVVersion(3))
+	assert.Error(t, err)
+}
+
+func TestLoadedSecrets(t *testing.T) {
+	load := func(reader client.Reader) *LoadedSecrets {
+		loaded := &LoadedSecrets{}
+		loader := NewSecretLoader(reader, "default", "/secrets", &MountSecrets{},
+			WithLoadedSecrets(loaded),
+			WithProvider("files", NewFSProvider(fstest.MapFS{"app/token": {Data: []byte("token")}})))
+		for _, s := range []*Secret{
+			{Value: "plain"},
+			{ValueFrom: &ValueFrom{SecretKeyRef: secretKeyRef("credentials", "password")}},
+			{MountFrom: &ValueFrom{ConfigMapKeyRef: configMapKeyRef("config", "ca.crt")}},
+			{ValueFrom: &ValueFrom{ProviderKeyRef: &ProviderKeySelector{Provider: "files", Path: "app", Key: "token"}}},
+		} {
+			_, err := loader.Load(s)
+			require.NoError(t, err)
+		}
+		return loaded
+	}
+
+	reader := testReader()
+	loaded := load(reader)
+	assert.Equal(t, []Reference{
+		{Kind: "ConfigMap", Namespace: "default", Name: "config"},
+		{Kind: "Secret", Namespace: "default", Name: "credentials"},
+	}, loaded.References())
+	checksum := loaded.Checksum()
+	assert.Len(t, checksum, 64)
+	assert.Equal(t, checksum, load(reader).Checksum())
+
+	// keys that were not loaded don't change the checksum
+	configMap := &corev1.ConfigMap{}
+	require.NoError(t, reader.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "config"}, configMap))
+	configMap.Data["endpoint"] = "https://example.org"
+	require.NoError(t, reader.Reader.(client.Client).Update(context.Background(), configMap))
+	assert.Equal(t, checksum, load(reader).Checksum())
+
+	secret := &corev1.Secret{}
+	require.NoError(t, reader.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "credentials"}, secret))
+	secret.Data["password"] = []byte("rotated")
+	require.NoError(t, reader.Reader.(client.Client).Update(context.Background(), secret))
+	assert.NotEqual(t, checksum, load(reader).Checksum())
+
+	assert.Empty(t, (&LoadedSecrets{}).Checksum())
+}
+
+func TestChecksumModifier(t *testing.T) {
+	loaded := &LoadedSecrets{}
+	modifier := ChecksumModifier(loaded)
+
+	deployment := &appsv1.Deployment{}
+	_, err := modifier(deployment)
+	require.NoError(t, err)
+	assert.Empty(t, deployment.Spec.Template.Annotations)
+
+	_, err = NewSecretLoader(testReader(), "default", "/secrets", &MountSecrets{}, WithLoadedSecrets(loaded)).
+		Load(&Secret{ValueFrom: &ValueFrom{SecretKeyRef: secretKeyRef("credentials", "password")}})
+	require.NoError(t, err)
+
+	_, err = modifier(deployment)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{ChecksumAnnotation: loaded.Checksum()}, deployment.Spec.Template.Annotations)
+
+	job := &batchv1.Job{}
+	_, err = modifier(job)
+	require.NoError(t, err)
+	assert.Empty(t, job.Spec.Template.Annotations)
+
+	deployment = &appsv1.Deployment{}
+	_, err = ChecksumModifier(nil)(deployment)
+	require.NoError(t, err)
+	assert.Empty(t, deployment.Spec.Template.Annotations)
+	assert.Empty(t, (*LoadedSecrets)(nil).References())
+}
+
+func TestReferenceIndex(t *testing.T) {
+	index := NewReferenceIndex()
+	credentials := Reference{Kind: "Secret", Namespace: "default", Name: "credentials"}
+	config := Reference{Kind: "ConfigMap", Namespace: "default", Name: "config"}
+	first := client.ObjectKey{Namespace: "default", Name: "first"}
+	second := client.ObjectKey{Namespace: "default", Name: "second"}
+
+	index.Update(first, []Reference{credentials, config})
+	index.Update(second, []Reference{credentials})
+
+	mapper := index.EnqueueOwnersMapper()
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "credentials"}}
+	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "config"}}
+	assert.Equal(t, []reconcile.Request{{NamespacedName: first}, {NamespacedName: second}}, mapper(context.Background(), secret))
+	assert.Equal(t, []reconcile.Request{{NamespacedName: first}}, mapper(context.Background(), configMap))
+	// a config map with the same name as a secret is a different object
+	assert.Empty(t, mapper(context.Background(), &corev1.ConfigMap{ObjectMeta: secret.ObjectMeta}))
+
+	index.Update(first, []Reference{credentials})
+	assert.Empty(t, mapper(context.Background(), configMap))
+
+	index.Delete(second)
+	assert.Equal(t, []reconcile.Request{{NamespacedName: first}}, mapper(context.Background(), secret))
+}
@@ -45,4 +45,20 @@ type Secret struct {
 
 type ValueFrom struct {
 	SecretKeyRef *corev1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+	// Refers to a non-secret value in a config map
+	ConfigMapKeyRef *corev1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+	// Refers to a secret in an external secret store, see WithProvider
+	ProviderKeyRef *ProviderKeySelector `json:"providerKeyRef,omitempty"`
+}
+
+// +kubebuilder:object:generate=true
+
+// ProviderKeySelector selects a key of a secret in an external secret store
+type ProviderKeySelector struct {
+	// Name of the provider the SecretLoader is configured with
+	Provider string `json:"provider"`
+	// Path of the secret in the secret store
+	Path string `json:"path"`
+	// The key of the secret to select from
+	Key string `json:"key"`
 }
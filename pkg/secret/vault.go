@@ -0,0 +1,180 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"emperror.dev/errors"
+)
+
+// VaultProvider reads secrets through the HTTP API of the Vault KV secrets engine, or any store compatible with it
+type VaultProvider struct {
+	address    string
+	token      string
+	mount      string
+	pathPrefix string
+	kvVersion  int
+	client     *http.Client
+}
+
+var _ Provider = &VaultProvider{}
+
+type VaultProviderOption = func(p *VaultProvider)
+
+// WithVaultMount sets the path the KV secrets engine is mounted to, defaults to "secret"
+func WithVaultMount(mount string) VaultProviderOption {
+	return func(p *VaultProvider) {
+		p.mount = strings.Trim(mount, "/")
+	}
+}
+
+// WithVaultPathPrefix limits the secrets that can be read to the ones under the prefix, the paths of the secrets
+// are relative to it, e.g. to give every namespace its own provider
+func WithVaultPathPrefix(prefix string) VaultProviderOption {
+	return func(p *VaultProvider) {
+		p.pathPrefix = strings.Trim(prefix, "/")
+	}
+}
+
+// WithVaultKVVersion sets the version of the KV secrets engine, defaults to 2
+func WithVaultKVVersion(version int) VaultProviderOption {
+	return func(p *VaultProvider) {
+		p.kvVersion = version
+	}
+}
+
+// WithVaultHTTPClient sets the client used to call the API, e.g. to configure TLS
+func WithVaultHTTPClient(client *http.Client) VaultProviderOption {
+	return func(p *VaultProvider) {
+		p.client = client
+	}
+}
+
+// NewVaultProvider returns a provider for the Vault API at the address, authenticating with the token
+func NewVaultProvider(address, token string, opts ...VaultProviderOption) (*VaultProvider, error) {
+	if _, err := url.Parse(address); err != nil {
+		return nil, errors.WrapIff(err, "invalid vault address %s", address)
+	}
+	p := &VaultProvider{
+		address:   strings.TrimSuffix(address, "/"),
+		token:     token,
+		mount:     "secret",
+		kvVersion: 2,
+		client:    http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.kvVersion != 1 && p.kvVersion != 2 {
+		return nil, errors.Errorf("unsupported KV secrets engine version %d", p.kvVersion)
+	}
+	if p.pathPrefix != "" && !fs.ValidPath(p.pathPrefix) {
+		return nil, errors.Errorf("invalid vault path prefix %s", p.pathPrefix)
+	}
+	return p, nil
+}
+
+// VaultError is returned by the VaultProvider when the API responds with an error
+type VaultError struct {
+	StatusCode int
+	Errors     []string
+}
+
+func (e *VaultError) Error() string {
+	return fmt.Sprintf("vault responded with status %d: %s", e.StatusCode, strings.Join(e.Errors, "; "))
+}
+
+// Is makes missing secrets match ErrNotFound
+func (e *VaultError) Is(target error) bool {
+	return target == ErrNotFound && e.StatusCode == http.StatusNotFound
+}
+
+func (p *VaultProvider) Get(ctx context.Context, secretPath, key string) ([]byte, error) {
+	secretPath = strings.Trim(secretPath, "/")
+	// the path comes from custom resources, it must not escape the mount or the prefix, e.g. through ".."
+	if !fs.ValidPath(secretPath) || secretPath == "." {
+		return nil, errors.Errorf("invalid vault secret path %q", secretPath)
+	}
+	data, err := p.read(ctx, secretPath)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := data[key]
+	if !ok {
+		return nil, errors.WrapIff(ErrNotFound, "key %q not found in vault secret %q", key, secretPath)
+	}
+	// KV values are arbitrary JSON, only strings are returned as they are
+	if s, ok := value.(string); ok {
+		return []byte(s), nil
+	}
+	return json.Marshal(value)
+}
+
+// read returns the data of the secret, unwrapping it from the versioned response of KV version 2
+func (p *VaultProvider) read(ctx context.Context, secretPath string) (map[string]interface{}, error) {
+	segments := strings.Split(path.Join(p.pathPrefix, secretPath), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	escapedPath := strings.Join(segments, "/")
+	endpoint := fmt.Sprintf("%s/v1/%s/%s", p.address, p.mount, escapedPath)
+	if p.kvVersion == 2 {
+		endpoint = fmt.Sprintf("%s/v1/%s/data/%s", p.address, p.mount, escapedPath)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, errors.WrapIf(err, "failed to create vault request")
+	}
+	req.Header.Set("X-Vault-Token", p.token)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, errors.WrapIff(err, "failed to read vault secret %s", secretPath)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		vaultErr := &VaultError{StatusCode: resp.StatusCode}
+		// the body lists the errors, but it's not guaranteed to be JSON, e.g. behind a proxy
+		_ = json.NewDecoder(resp.Body).Decode(&struct {
+			Errors *[]string `json:"errors"`
+		}{Errors: &vaultErr.Errors})
+		return nil, vaultErr
+	}
+
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, errors.WrapIff(err, "failed to decode vault secret %s", secretPath)
+	}
+	if p.kvVersion == 1 {
+		return body.Data, nil
+	}
+	data, _ := body.Data["data"].(map[string]interface{})
+	if data == nil {
+		// the latest version of the secret is deleted
+		return nil, errors.WrapIff(ErrNotFound, "vault secret %s has no data", secretPath)
+	}
+	return data, nil
+}
@@ -0,0 +1,112 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// ReferenceIndex maps the Secrets and ConfigMaps loaded during reconciles back to the owners that loaded them.
+// Update it at the end of every reconcile with the References of the LoadedSecrets and watch the Secrets
+// and ConfigMaps with the EnqueueOwnersMapper to reconcile the owners when they change.
+type ReferenceIndex struct {
+	mu     sync.RWMutex
+	refs   map[client.ObjectKey][]Reference
+	owners map[Reference]map[client.ObjectKey]struct{}
+}
+
+func NewReferenceIndex() *ReferenceIndex {
+	return &ReferenceIndex{
+		refs:   make(map[client.ObjectKey][]Reference),
+		owners: make(map[Reference]map[client.ObjectKey]struct{}),
+	}
+}
+
+// Update replaces the references of the owner
+func (i *ReferenceIndex) Update(owner client.ObjectKey, refs []Reference) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.delete(owner)
+	if len(refs) == 0 {
+		return
+	}
+	i.refs[owner] = append([]Reference(nil), refs...)
+	for _, ref := range refs {
+		if i.owners[ref] == nil {
+			i.owners[ref] = make(map[client.ObjectKey]struct{})
+		}
+		i.owners[ref][owner] = struct{}{}
+	}
+}
+
+// Delete removes the references of the owner, call it when the owner is deleted
+func (i *ReferenceIndex) Delete(owner client.ObjectKey) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.delete(owner)
+}
+
+func (i *ReferenceIndex) delete(owner client.ObjectKey) {
+	for _, ref := range i.refs[owner] {
+		delete(i.owners[ref], owner)
+		if len(i.owners[ref]) == 0 {
+			delete(i.owners, ref)
+		}
+	}
+	delete(i.refs, owner)
+}
+
+// Owners returns the owners referencing the object in a stable order
+func (i *ReferenceIndex) Owners(ref Reference) []client.ObjectKey {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	owners := make([]client.ObjectKey, 0, len(i.owners[ref]))
+	for owner := range i.owners[ref] {
+		owners = append(owners, owner)
+	}
+	sort.Slice(owners, func(a, b int) bool {
+		return owners[a].String() < owners[b].String()
+	})
+	return owners
+}
+
+// EnqueueOwnersMapper maps the events of Secrets and ConfigMaps to reconcile requests of the owners referencing them
+func (i *ReferenceIndex) EnqueueOwnersMapper() handler.MapFunc {
+	return func(_ context.Context, o client.Object) []reconcile.Request {
+		ref := Reference{Namespace: o.GetNamespace(), Name: o.GetName()}
+		switch o.(type) {
+		case *corev1.Secret:
+			ref.Kind = "Secret"
+		case *corev1.ConfigMap:
+			ref.Kind = "ConfigMap"
+		default:
+			return []reconcile.Request{}
+		}
+
+		owners := i.Owners(ref)
+		requests := make([]reconcile.Request, 0, len(owners))
+		for _, owner := range owners {
+			requests = append(requests, reconcile.Request{NamespacedName: owner})
+		}
+		return requests
+	}
+}
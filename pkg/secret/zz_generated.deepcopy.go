@@ -22,6 +22,21 @@ import (
 	"k8s.io/api/core/v1"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProviderKeySelector) DeepCopyInto(out *ProviderKeySelector) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderKeySelector.
+func (in *ProviderKeySelector) DeepCopy() *ProviderKeySelector {
+	if in == nil {
+		return nil
+	}
+	out := new(ProviderKeySelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Secret) DeepCopyInto(out *Secret) {
 	*out = *in
@@ -55,6 +70,16 @@ func (in *ValueFrom) DeepCopyInto(out *ValueFrom) {
 		*out = new(v1.SecretKeySelector)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ConfigMapKeyRef != nil {
+		in, out := &in.ConfigMapKeyRef, &out.ConfigMapKeyRef
+		*out = new(v1.ConfigMapKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ProviderKeyRef != nil {
+		in, out := &in.ProviderKeyRef, &out.ProviderKeyRef
+		*out = new(ProviderKeySelector)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ValueFrom.
@@ -0,0 +1,409 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package typeoverride
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// The Validate methods check the values of the overrides before they get merged into the resources of the operator,
+// so that admission webhooks can reject them early instead of the API server rejecting the resulting workload, e.g.:
+//
+//	if errs := spec.Template.Validate(field.NewPath("spec", "template")); len(errs) > 0 {
+//		return apierrors.NewInvalid(gvk.GroupKind(), name, errs)
+//	}
+//
+// Constraints that don't depend on the operator are also declared as markers, so that they are part of the CRD schema.
+
+// Validate checks the annotations and labels of the pod template and its pod spec
+func (o *PodTemplateSpec) Validate(path *field.Path) field.ErrorList {
+	if o == nil {
+		return nil
+	}
+	errs := validateObjectMeta(o.ObjectMeta, path.Child("metadata"))
+	return append(errs, o.Spec.Validate(path.Child("spec"))...)
+}
+
+// ValidateTarget checks the pod spec of the override against the pod spec of the template it is merged into
+func (o *PodTemplateSpec) ValidateTarget(target v1.PodTemplateSpec, path *field.Path) field.ErrorList {
+	if o == nil {
+		return nil
+	}
+	return o.Spec.ValidateTarget(target.Spec, path.Child("spec"))
+}
+
+// Validate checks that containers and volumes are named uniquely, as they are merged by name, and that resource quantities, ports and tolerations are valid
+func (o *PodSpec) Validate(path *field.Path) field.ErrorList {
+	if o == nil {
+		return nil
+	}
+	errs := ValidateVolumes(o.Volumes, path.Child("volumes"))
+	errs = append(errs, ValidateTolerations(o.Tolerations, path.Child("tolerations"))...)
+	// names have to be unique among all containers of the pod
+	names := sets.New[string]()
+	errs = append(errs, validateContainers(o.InitContainers, names, path.Child("initContainers"))...)
+	errs = append(errs, validateContainers(o.Containers, names, path.Child("containers"))...)
+	return errs
+}
+
+// ValidateTarget checks the override against the pod spec it is merged into.
+// Containers missing from the target are added, so they need an image, and mounted volumes have to exist in either of them.
+func (o *PodSpec) ValidateTarget(target v1.PodSpec, path *field.Path) field.ErrorList {
+	if o == nil {
+		return nil
+	}
+	volumes := sets.New[string]()
+	for _, v := range append(append([]v1.Volume{}, target.Volumes...), o.Volumes...) {
+		volumes.Insert(v.Name)
+	}
+	errs := validateTargetContainers(o.InitContainers, target.InitContainers, volumes, path.Child("initContainers"))
+	return append(errs, validateTargetContainers(o.Containers, target.Containers, volumes, path.Child("containers"))...)
+}
+
+func validateContainers(containers []v1.Container, names sets.Set[string], path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for i, c := range containers {
+		errs = append(errs, ValidateContainerName(c.Name, names, path.Index(i).Child("name"))...)
+		errs = append(errs, ValidateResourceRequirements(&c.Resources, path.Index(i).Child("resources"))...)
+		errs = append(errs, ValidateContainerPorts(c.Ports, path.Index(i).Child("ports"))...)
+		errs = append(errs, ValidateVolumeMounts(c.VolumeMounts, path.Index(i).Child("volumeMounts"))...)
+	}
+	return errs
+}
+
+func validateTargetContainers(containers, target []v1.Container, volumes sets.Set[string], path *field.Path) field.ErrorList {
+	images := make(map[string]string, len(target))
+	for _, c := range target {
+		images[c.Name] = c.Image
+	}
+	var errs field.ErrorList
+	for i, c := range containers {
+		if c.Image == "" && images[c.Name] == "" {
+			errs = append(errs, field.Required(path.Index(i).Child("image"), fmt.Sprintf("container %s is not part of the target", c.Name)))
+		}
+		errs = append(errs, ValidateMountedVolumes(c.VolumeMounts, volumes, path.Index(i).Child("volumeMounts"))...)
+	}
+	return errs
+}
+
+// Validate checks the metadata and the spec of the deployment
+func (o *Deployment) Validate(path *field.Path) field.ErrorList {
+	if o == nil {
+		return nil
+	}
+	errs := validateObjectMeta(o.ObjectMeta, path.Child("metadata"))
+	return append(errs, o.Spec.Validate(path.Child("spec"))...)
+}
+
+// Validate checks the number of replicas and the pod template
+func (o *DeploymentSpec) Validate(path *field.Path) field.ErrorList {
+	if o == nil {
+		return nil
+	}
+	errs := validateNonNegative(o.Replicas, path.Child("replicas"))
+	return append(errs, o.Template.Validate(path.Child("template"))...)
+}
+
+// Validate checks the metadata and the spec of the statefulset
+func (o *StatefulSet) Validate(path *field.Path) field.ErrorList {
+	if o == nil {
+		return nil
+	}
+	errs := validateObjectMeta(o.ObjectMeta, path.Child("metadata"))
+	return append(errs, o.Spec.Validate(path.Child("spec"))...)
+}
+
+// Validate checks the number of replicas and the pod template
+func (o *StatefulSetSpec) Validate(path *field.Path) field.ErrorList {
+	if o == nil {
+		return nil
+	}
+	errs := validateNonNegative(o.Replicas, path.Child("replicas"))
+	return append(errs, o.Template.Validate(path.Child("template"))...)
+}
+
+// Validate checks the metadata and the spec of the daemonset
+func (o *DaemonSet) Validate(path *field.Path) field.ErrorList {
+	if o == nil {
+		return nil
+	}
+	errs := validateObjectMeta(o.ObjectMeta, path.Child("metadata"))
+	return append(errs, o.Spec.Validate(path.Child("spec"))...)
+}
+
+// Validate checks the pod template
+func (o *DaemonSetSpec) Validate(path *field.Path) field.ErrorList {
+	if o == nil {
+		return nil
+	}
+	return o.Template.Validate(path.Child("template"))
+}
+
+// Validate checks the metadata and the spec of the job
+func (o *Job) Validate(path *field.Path) field.ErrorList {
+	if o == nil {
+		return nil
+	}
+	errs := validateObjectMeta(o.ObjectMeta, path.Child("metadata"))
+	return append(errs, o.Spec.Validate(path.Child("spec"))...)
+}
+
+// Validate checks the counters and the pod template
+func (o *JobSpec) Validate(path *field.Path) field.ErrorList {
+	if o == nil {
+		return nil
+	}
+	errs := validateNonNegative(o.Parallelism, path.Child("parallelism"))
+	errs = append(errs, validateNonNegative(o.Completions, path.Child("completions"))...)
+	errs = append(errs, validateNonNegative(o.BackoffLimit, path.Child("backoffLimit"))...)
+	return append(errs, o.Template.Validate(path.Child("template"))...)
+}
+
+// Validate checks the metadata and the spec of the cronjob
+func (o *CronJob) Validate(path *field.Path) field.ErrorList {
+	if o == nil {
+		return nil
+	}
+	errs := validateObjectMeta(o.ObjectMeta, path.Child("metadata"))
+	return append(errs, o.Spec.Validate(path.Child("spec"))...)
+}
+
+// Validate checks the job template
+func (o *CronJobSpec) Validate(path *field.Path) field.ErrorList {
+	if o == nil {
+		return nil
+	}
+	return o.JobTemplate.Validate(path.Child("jobTemplate"))
+}
+
+// Validate checks the metadata and the spec of the job template
+func (o *JobTemplateSpec) Validate(path *field.Path) field.ErrorList {
+	if o == nil {
+		return nil
+	}
+	errs := validateObjectMeta(o.ObjectMeta, path.Child("metadata"))
+	return append(errs, o.Spec.Validate(path.Child("spec"))...)
+}
+
+// Validate checks the metadata and the spec of the autoscaler
+func (o *HorizontalPodAutoscaler) Validate(path *field.Path) field.ErrorList {
+	if o == nil {
+		return nil
+	}
+	errs := validateObjectMeta(o.ObjectMeta, path.Child("metadata"))
+	return append(errs, o.Spec.Validate(path.Child("spec"))...)
+}
+
+// Validate checks the replica bounds of the autoscaler
+func (o *HorizontalPodAutoscalerSpec) Validate(path *field.Path) field.ErrorList {
+	if o == nil {
+		return nil
+	}
+	return validateReplicaBounds(o.MinReplicas, o.MaxReplicas, path)
+}
+
+// Validate checks the metadata and the spec of the disruption budget
+func (o *PodDisruptionBudget) Validate(path *field.Path) field.ErrorList {
+	if o == nil {
+		return nil
+	}
+	errs := validateObjectMeta(o.ObjectMeta, path.Child("metadata"))
+	return append(errs, o.Spec.Validate(path.Child("spec"))...)
+}
+
+// Validate checks that only one of minAvailable and maxUnavailable is set
+func (o *PodDisruptionBudgetSpec) Validate(path *field.Path) field.ErrorList {
+	if o == nil || o.MinAvailable == nil || o.MaxUnavailable == nil {
+		return nil
+	}
+	return field.ErrorList{field.Forbidden(path.Child("maxUnavailable"), "minAvailable and maxUnavailable are mutually exclusive")}
+}
+
+// ValidateContainerName checks that the name, the merge key of containers, is a DNS label unique among the names already seen
+func ValidateContainerName(name string, names sets.Set[string], path *field.Path) field.ErrorList {
+	if name == "" {
+		return field.ErrorList{field.Required(path, "containers are merged by name")}
+	}
+	var errs field.ErrorList
+	for _, msg := range validation.IsDNS1123Label(name) {
+		errs = append(errs, field.Invalid(path, name, msg))
+	}
+	if names.Has(name) {
+		errs = append(errs, field.Duplicate(path, name))
+	}
+	names.Insert(name)
+	return errs
+}
+
+// ValidateResourceRequirements checks that the quantities are not negative and the requests don't exceed the limits
+func ValidateResourceRequirements(resources *v1.ResourceRequirements, path *field.Path) field.ErrorList {
+	if resources == nil {
+		return nil
+	}
+	var errs field.ErrorList
+	for _, name := range resourceNames(resources.Limits) {
+		if limit := resources.Limits[name]; limit.Sign() < 0 {
+			errs = append(errs, field.Invalid(path.Child("limits").Key(string(name)), limit.String(), "must be greater than or equal to 0"))
+		}
+	}
+	for _, name := range resourceNames(resources.Requests) {
+		request := resources.Requests[name]
+		if request.Sign() < 0 {
+			errs = append(errs, field.Invalid(path.Child("requests").Key(string(name)), request.String(), "must be greater than or equal to 0"))
+		}
+		if limit, ok := resources.Limits[name]; ok && request.Cmp(limit) > 0 {
+			errs = append(errs, field.Invalid(path.Child("requests").Key(string(name)), request.String(), fmt.Sprintf("must be less than or equal to %s limit of %s", name, limit.String())))
+		}
+	}
+	return errs
+}
+
+// ValidateContainerPorts checks that the port numbers are valid and unique per protocol, TCP by default
+func ValidateContainerPorts(ports []v1.ContainerPort, path *field.Path) field.ErrorList {
+	type portKey struct {
+		port     int32
+		protocol v1.Protocol
+	}
+	var errs field.ErrorList
+	seen := sets.New[portKey]()
+	for i, port := range ports {
+		for _, msg := range validation.IsValidPortNum(int(port.ContainerPort)) {
+			errs = append(errs, field.Invalid(path.Index(i).Child("containerPort"), port.ContainerPort, msg))
+		}
+		key := portKey{port: port.ContainerPort, protocol: port.Protocol}
+		if key.protocol == "" {
+			key.protocol = v1.ProtocolTCP
+		}
+		if seen.Has(key) {
+			errs = append(errs, field.Duplicate(path.Index(i), fmt.Sprintf("%d/%s", key.port, key.protocol)))
+		}
+		seen.Insert(key)
+	}
+	return errs
+}
+
+// ValidateTolerations checks that tolerations without a key use the Exists operator, as they match every taint
+func ValidateTolerations(tolerations []v1.Toleration, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for i, t := range tolerations {
+		if t.Key == "" && t.Operator != v1.TolerationOpExists {
+			errs = append(errs, field.Required(path.Index(i).Child("key"), "tolerations without a key have to use the Exists operator"))
+		}
+	}
+	return errs
+}
+
+// ValidateVolumes checks that the volumes are named uniquely, as they are merged by name, and have a single source
+func ValidateVolumes(volumes []v1.Volume, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	names := sets.New[string]()
+	for i, v := range volumes {
+		if v.Name == "" {
+			errs = append(errs, field.Required(path.Index(i).Child("name"), "volumes are merged by name"))
+		} else {
+			for _, msg := range validation.IsDNS1123Label(v.Name) {
+				errs = append(errs, field.Invalid(path.Index(i).Child("name"), v.Name, msg))
+			}
+			if names.Has(v.Name) {
+				errs = append(errs, field.Duplicate(path.Index(i).Child("name"), v.Name))
+			}
+			names.Insert(v.Name)
+		}
+		if volumeSources(v.VolumeSource) > 1 {
+			errs = append(errs, field.Forbidden(path.Index(i), "may not specify more than 1 volume type"))
+		}
+	}
+	return errs
+}
+
+// ValidateVolumeMounts checks that the mount paths, the merge key of volume mounts, are set and unique
+func ValidateVolumeMounts(mounts []v1.VolumeMount, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	mountPaths := sets.New[string]()
+	for i, m := range mounts {
+		switch {
+		case m.MountPath == "":
+			errs = append(errs, field.Required(path.Index(i).Child("mountPath"), "volume mounts are merged by mount path"))
+		case mountPaths.Has(m.MountPath):
+			errs = append(errs, field.Duplicate(path.Index(i).Child("mountPath"), m.MountPath))
+		}
+		mountPaths.Insert(m.MountPath)
+	}
+	return errs
+}
+
+// ValidateMountedVolumes checks that the mounts reference one of the given volumes
+func ValidateMountedVolumes(mounts []v1.VolumeMount, volumes sets.Set[string], path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for i, m := range mounts {
+		if !volumes.Has(m.Name) {
+			errs = append(errs, field.NotFound(path.Index(i).Child("name"), m.Name))
+		}
+	}
+	return errs
+}
+
+func validateObjectMeta(meta ObjectMeta, path *field.Path) field.ErrorList {
+	errs := apivalidation.ValidateAnnotations(meta.Annotations, path.Child("annotations"))
+	return append(errs, metav1validation.ValidateLabels(meta.Labels, path.Child("labels"))...)
+}
+
+func validateNonNegative(value *int32, path *field.Path) field.ErrorList {
+	if value == nil {
+		return nil
+	}
+	return apivalidation.ValidateNonnegativeField(int64(*value), path)
+}
+
+func validateReplicaBounds(minReplicas *int32, maxReplicas int32, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if minReplicas != nil && *minReplicas < 1 {
+		errs = append(errs, field.Invalid(path.Child("minReplicas"), *minReplicas, "must be greater than or equal to 1"))
+	}
+	if minReplicas != nil && maxReplicas != 0 && *minReplicas > maxReplicas {
+		errs = append(errs, field.Invalid(path.Child("maxReplicas"), maxReplicas, "must be greater than or equal to minReplicas"))
+	}
+	return errs
+}
+
+func resourceNames(resources v1.ResourceList) []v1.ResourceName {
+	names := make([]v1.ResourceName, 0, len(resources))
+	for name := range resources {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
+
+// volumeSources counts the sources set in the volume source, each of them is a pointer
+func volumeSources(source v1.VolumeSource) int {
+	count := 0
+	value := reflect.ValueOf(source)
+	for i := 0; i < value.NumField(); i++ {
+		if !value.Field(i).IsNil() {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,84 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package typeoverride
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func fieldPaths(errs field.ErrorList) []string {
+	paths := make([]string, 0, len(errs))
+	for _, err := range errs {
+		paths = append(paths, err.Field)
+	}
+	return paths
+}
+
+func TestPodTemplateSpecValidate(t *testing.T) {
+	template := &PodTemplateSpec{
+		ObjectMeta: ObjectMeta{Annotations: map[string]string{"-invalid": "value"}},
+		Spec: PodSpec{
+			InitContainers: []v1.Container{{Name: "init"}},
+			Containers: []v1.Container{
+				{
+					Name: "init",
+					Resources: v1.ResourceRequirements{
+						Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("-1")},
+					},
+					Ports:        []v1.ContainerPort{{ContainerPort: 53, Protocol: v1.ProtocolUDP}, {ContainerPort: 53}, {ContainerPort: 53, Protocol: v1.ProtocolUDP}},
+					VolumeMounts: []v1.VolumeMount{{Name: "a", MountPath: "/a"}, {Name: "b", MountPath: "/a"}},
+				},
+				{Name: "Sidecar"},
+			},
+			Volumes:     []v1.Volume{{}},
+			Tolerations: []v1.Toleration{{Key: "dedicated", Value: "infra"}, {Value: "infra"}},
+		},
+	}
+
+	assert.ElementsMatch(t, []string{
+		"spec.template.metadata.annotations",
+		"spec.template.spec.volumes[0].name",
+		"spec.template.spec.containers[0].name",
+		"spec.template.spec.tolerations[1].key",
+		"spec.template.spec.containers[0].resources.requests[cpu]",
+		"spec.template.spec.containers[0].ports[2]",
+		"spec.template.spec.containers[0].volumeMounts[1].mountPath",
+		"spec.template.spec.containers[1].name",
+	}, fieldPaths(template.Validate(field.NewPath("spec", "template"))))
+
+	assert.Empty(t, (*PodTemplateSpec)(nil).Validate(nil))
+}
+
+func TestPodSpecValidateTarget(t *testing.T) {
+	spec := &PodSpec{
+		InitContainers: []v1.Container{{Name: "setup", VolumeMounts: []v1.VolumeMount{{Name: "data", MountPath: "/data"}}}},
+		Containers: []v1.Container{
+			{Name: "app", VolumeMounts: []v1.VolumeMount{{Name: "cache", MountPath: "/cache"}}},
+			{Name: "sidecar", Image: "sidecar", VolumeMounts: []v1.VolumeMount{{Name: "data", MountPath: "/data"}}},
+		},
+		Volumes: []v1.Volume{{Name: "data"}},
+	}
+	target := v1.PodSpec{Containers: []v1.Container{{Name: "app", Image: "app"}}}
+
+	assert.Equal(t, []string{
+		"initContainers[0].image",
+		"containers[0].volumeMounts[0].name",
+	}, fieldPaths(spec.ValidateTarget(target, nil)))
+}
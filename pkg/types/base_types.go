@@ -16,8 +16,13 @@ package types
 
 import (
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	"github.com/cisco-open/operator-tools/pkg/utils"
 )
@@ -138,26 +143,12 @@ type MetaBase struct {
 }
 
 func (base *MetaBase) Merge(meta metav1.ObjectMeta) metav1.ObjectMeta {
-	if base == nil {
-		return meta
-	}
-	if len(base.Annotations) > 0 {
-		if meta.Annotations == nil {
-			meta.Annotations = make(map[string]string)
-		}
-		for key, val := range base.Annotations {
-			meta.Annotations[key] = val
-		}
-	}
-	if len(base.Labels) > 0 {
-		if meta.Labels == nil {
-			meta.Labels = make(map[string]string)
-		}
-		for key, val := range base.Labels {
-			meta.Labels[key] = val
-		}
-	}
-	return meta
+	return logOverrideError(base.TryMerge(meta))
+}
+
+// TryMerge is Merge returning the error instead of logging it, the original is returned unchanged on errors
+func (base *MetaBase) TryMerge(meta metav1.ObjectMeta) (metav1.ObjectMeta, error) {
+	return override(base, meta)
 }
 
 // +kubebuilder:object:generate=true
@@ -170,16 +161,12 @@ type PodTemplateBase struct {
 }
 
 func (base *PodTemplateBase) Override(template corev1.PodTemplateSpec) corev1.PodTemplateSpec {
-	if base == nil {
-		return template
-	}
-	if base.Metadata != nil {
-		template.ObjectMeta = base.Metadata.Merge(template.ObjectMeta)
-	}
-	if base.PodSpec != nil {
-		template.Spec = base.PodSpec.Override(template.Spec)
-	}
-	return template
+	return logOverrideError(base.TryOverride(template))
+}
+
+// TryOverride is Override returning the error instead of logging it, the original is returned unchanged on errors
+func (base *PodTemplateBase) TryOverride(template corev1.PodTemplateSpec) (corev1.PodTemplateSpec, error) {
+	return override(base, template)
 }
 
 // +kubebuilder:object:generate=true
@@ -187,46 +174,38 @@ func (base *PodTemplateBase) Override(template corev1.PodTemplateSpec) corev1.Po
 // Deprecated
 // Consider using Container in the typeoverrides package combined with the merge package
 type ContainerBase struct {
-	Name            string                       `json:"name,omitempty"`
-	Resources       *corev1.ResourceRequirements `json:"resources,omitempty"`
-	Image           string                       `json:"image,omitempty"`
-	PullPolicy      corev1.PullPolicy            `json:"pullPolicy,omitempty"`
-	Command         []string                     `json:"command,omitempty"`
-	VolumeMounts    []corev1.VolumeMount         `json:"volumeMounts,omitempty" patchStrategy:"merge" patchMergeKey:"mountPath" `
-	SecurityContext *corev1.SecurityContext      `json:"securityContext,omitempty"`
-	LivenessProbe   *corev1.Probe                `json:"livenessProbe,omitempty"`
-	ReadinessProbe  *corev1.Probe                `json:"readinessProbe,omitempty"`
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name      string                       `json:"name,omitempty"`
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+	Image     string                       `json:"image,omitempty"`
+	// +kubebuilder:validation:Enum=Always;Never;IfNotPresent
+	PullPolicy corev1.PullPolicy `json:"pullPolicy,omitempty" overridePath:"imagePullPolicy"`
+	Command    []string          `json:"command,omitempty"`
+	Args       []string          `json:"args,omitempty"`
+	// +listType=map
+	// +listMapKey=name
+	Env []corev1.EnvVar `json:"env,omitempty" overrideStrategy:"merge" overrideMergeKey:"name"`
+	// +listType=map
+	// +listMapKey=containerPort
+	Ports []corev1.ContainerPort `json:"ports,omitempty" overrideStrategy:"merge" overrideMergeKey:"containerPort"`
+	// +listType=map
+	// +listMapKey=mountPath
+	VolumeMounts    []corev1.VolumeMount    `json:"volumeMounts,omitempty" patchStrategy:"merge" patchMergeKey:"mountPath" overrideStrategy:"merge" overrideMergeKey:"mountPath"`
+	SecurityContext *corev1.SecurityContext `json:"securityContext,omitempty" overrideStrategy:"replace"`
+	LivenessProbe   *corev1.Probe           `json:"livenessProbe,omitempty" overrideStrategy:"replace"`
+	ReadinessProbe  *corev1.Probe           `json:"readinessProbe,omitempty" overrideStrategy:"replace"`
 }
 
+// Override applies the base to the container using the patch strategies of the container, refined by the override strategy of the fields.
+// Security contexts and probes are replaced as a whole, merging them could leave more than one of their profiles or handlers set.
 func (base *ContainerBase) Override(container corev1.Container) corev1.Container {
-	if base == nil {
-		return container
-	}
-	if base.Resources != nil {
-		container.Resources = *base.Resources
-	}
-	if base.Image != "" {
-		container.Image = base.Image
-	}
-	if base.PullPolicy != "" {
-		container.ImagePullPolicy = base.PullPolicy
-	}
-	if len(base.Command) > 0 {
-		container.Command = base.Command
-	}
-	if len(base.VolumeMounts) > 0 {
-		container.VolumeMounts = base.VolumeMounts
-	}
-	if base.SecurityContext != nil {
-		container.SecurityContext = base.SecurityContext
-	}
-	if base.LivenessProbe != nil {
-		container.LivenessProbe = base.LivenessProbe
-	}
-	if base.ReadinessProbe != nil {
-		container.LivenessProbe = base.LivenessProbe
-	}
-	return container
+	return logOverrideError(base.TryOverride(container))
+}
+
+// TryOverride is Override returning the error instead of logging it, the original is returned unchanged on errors
+func (base *ContainerBase) TryOverride(container corev1.Container) (corev1.Container, error) {
+	return override(base, container)
 }
 
 // +kubebuilder:object:generate=true
@@ -234,68 +213,32 @@ func (base *ContainerBase) Override(container corev1.Container) corev1.Container
 // Deprecated
 // Consider using PodSpec in the typeoverrides package combined with the merge package
 type PodSpecBase struct {
-	Tolerations        []corev1.Toleration           `json:"tolerations,omitempty"`
-	NodeSelector       map[string]string             `json:"nodeSelector,omitempty"`
-	ServiceAccountName string                        `json:"serviceAccountName,omitempty"`
-	Affinity           *corev1.Affinity              `json:"affinity,omitempty"`
-	SecurityContext    *corev1.PodSecurityContext    `json:"securityContext,omitempty"`
-	Volumes            []corev1.Volume               `json:"volumes,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
-	PriorityClassName  string                        `json:"priorityClassName,omitempty"`
-	Containers         []ContainerBase               `json:"containers,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
-	InitContainers     []ContainerBase               `json:"initContainers,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
-	ImagePullSecrets   []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+	// Tolerations replace the tolerations of the pod spec with the same key, the ones without a key replace the keyless ones
+	Tolerations        []corev1.Toleration        `json:"tolerations,omitempty" overrideStrategy:"replaceByKey" overrideMergeKey:"key"`
+	NodeSelector       map[string]string          `json:"nodeSelector,omitempty" overrideStrategy:"merge"`
+	ServiceAccountName string                     `json:"serviceAccountName,omitempty"`
+	Affinity           *corev1.Affinity           `json:"affinity,omitempty"`
+	SecurityContext    *corev1.PodSecurityContext `json:"securityContext,omitempty" overrideStrategy:"replace"`
+	// +listType=map
+	// +listMapKey=name
+	Volumes           []corev1.Volume `json:"volumes,omitempty" patchStrategy:"merge" patchMergeKey:"name" overrideStrategy:"merge" overrideMergeKey:"name"`
+	PriorityClassName string          `json:"priorityClassName,omitempty"`
+	// +kubebuilder:validation:XValidation:rule="self.all(c, has(c.name))",message="containers are merged by name"
+	Containers []ContainerBase `json:"containers,omitempty" patchStrategy:"merge" patchMergeKey:"name" overrideStrategy:"merge" overrideMergeKey:"name"`
+	// +kubebuilder:validation:XValidation:rule="self.all(c, has(c.name))",message="containers are merged by name"
+	InitContainers   []ContainerBase               `json:"initContainers,omitempty" patchStrategy:"merge" patchMergeKey:"name" overrideStrategy:"merge" overrideMergeKey:"name"`
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty" overrideStrategy:"append"`
 }
 
+// Override applies the base to the pod spec using the patch strategies of the pod spec, refined by the override strategy of the fields.
+// Containers are overridden by name, containers missing from the spec are added, e.g. to inject sidecars.
 func (base *PodSpecBase) Override(spec corev1.PodSpec) corev1.PodSpec {
-	if base == nil {
-		return spec
-	}
-	if base.SecurityContext != nil {
-		spec.SecurityContext = base.SecurityContext
-	}
-	if base.Tolerations != nil {
-		spec.Tolerations = base.Tolerations
-	}
-	if base.NodeSelector != nil {
-		spec.NodeSelector = base.NodeSelector
-	}
-	if base.ServiceAccountName != "" {
-		spec.ServiceAccountName = base.ServiceAccountName
-	}
-	if base.Affinity != nil {
-		spec.Affinity = base.Affinity
-	}
-	if len(base.Volumes) > 0 {
-		spec.Volumes = base.Volumes
-	}
-	if base.PriorityClassName != "" {
-		spec.PriorityClassName = base.PriorityClassName
-	}
-	if len(base.Containers) > 0 {
-		for _, baseContainer := range base.Containers {
-			for o, originalContainer := range spec.Containers {
-				if baseContainer.Name == originalContainer.Name {
-					spec.Containers[o] = baseContainer.Override(originalContainer)
-					break
-				}
-			}
-		}
-	}
-	if len(base.InitContainers) > 0 {
-		for _, baseContainer := range base.InitContainers {
-			for o, originalContainer := range spec.InitContainers {
-				if baseContainer.Name == originalContainer.Name {
-					spec.InitContainers[o] = baseContainer.Override(originalContainer)
-					break
-				}
-			}
-		}
-	}
-
-	spec.ImagePullSecrets = append(([]corev1.LocalObjectReference)(nil), spec.ImagePullSecrets...)
-	spec.ImagePullSecrets = append(spec.ImagePullSecrets, base.ImagePullSecrets...)
+	return logOverrideError(base.TryOverride(spec))
+}
 
-	return spec
+// TryOverride is Override returning the error instead of logging it, the original is returned unchanged on errors
+func (base *PodSpecBase) TryOverride(spec corev1.PodSpec) (corev1.PodSpec, error) {
+	return override(base, spec)
 }
 
 // +kubebuilder:object:generate=true
@@ -303,21 +246,31 @@ func (base *PodSpecBase) Override(spec corev1.PodSpec) corev1.PodSpec {
 // Deprecated
 // Consider using Deployment in the typeoverrides package combined with the merge package
 type DeploymentBase struct {
-	*MetaBase `json:",inline"`
+	*MetaBase `json:",inline" overridePath:"metadata"`
 	Spec      *DeploymentSpecBase `json:"spec,omitempty"`
 }
 
+// Override delegates to the Override of the spec base to apply its additional rules
 func (base *DeploymentBase) Override(deployment appsv1.Deployment) appsv1.Deployment {
+	return logOverrideError(base.TryOverride(deployment))
+}
+
+// TryOverride is Override returning the error instead of logging it, the original is returned unchanged on errors
+func (base *DeploymentBase) TryOverride(deployment appsv1.Deployment) (appsv1.Deployment, error) {
 	if base == nil {
-		return deployment
+		return deployment, nil
 	}
-	if base.MetaBase != nil {
-		deployment.ObjectMeta = base.MetaBase.Merge(deployment.ObjectMeta)
+	meta, err := base.MetaBase.TryMerge(deployment.ObjectMeta)
+	if err != nil {
+		return deployment, err
 	}
-	if base.Spec != nil {
-		deployment.Spec = base.Spec.Override(deployment.Spec)
+	spec, err := base.Spec.TryOverride(deployment.Spec)
+	if err != nil {
+		return deployment, err
 	}
-	return deployment
+	deployment.ObjectMeta = meta
+	deployment.Spec = spec
+	return deployment, nil
 }
 
 // +kubebuilder:object:generate=true
@@ -325,27 +278,29 @@ func (base *DeploymentBase) Override(deployment appsv1.Deployment) appsv1.Deploy
 // Deprecated
 // Consider using DeploymentSpec in the typeoverrides package combined with the merge package
 type DeploymentSpecBase struct {
+	// +kubebuilder:validation:Minimum=0
 	Replicas *int32                     `json:"replicas,omitempty"`
 	Selector *metav1.LabelSelector      `json:"selector,omitempty"`
 	Strategy *appsv1.DeploymentStrategy `json:"strategy,omitempty"`
 	Template *PodTemplateBase           `json:"template,omitempty"`
 }
 
+// Override appends the match expressions of the selector to the original ones instead of replacing them
 func (base *DeploymentSpecBase) Override(spec appsv1.DeploymentSpec) appsv1.DeploymentSpec {
+	return logOverrideError(base.TryOverride(spec))
+}
+
+// TryOverride is Override returning the error instead of logging it, the original is returned unchanged on errors
+func (base *DeploymentSpecBase) TryOverride(spec appsv1.DeploymentSpec) (appsv1.DeploymentSpec, error) {
 	if base == nil {
-		return spec
-	}
-	if base.Replicas != nil {
-		spec.Replicas = base.Replicas
-	}
-	spec.Selector = mergeSelectors(base.Selector, spec.Selector)
-	if base.Strategy != nil {
-		spec.Strategy = *base.Strategy
+		return spec, nil
 	}
-	if base.Template != nil {
-		spec.Template = base.Template.Override(spec.Template)
+	result, err := override(base, spec)
+	if err != nil {
+		return spec, err
 	}
-	return spec
+	result.Selector = appendMatchExpressions(result.Selector, spec.Selector, base.Selector)
+	return result, nil
 }
 
 // +kubebuilder:object:generate=true
@@ -353,21 +308,31 @@ func (base *DeploymentSpecBase) Override(spec appsv1.DeploymentSpec) appsv1.Depl
 // Deprecated
 // Consider using StatefulSet in the typeoverrides package combined with the merge package
 type StatefulSetBase struct {
-	*MetaBase `json:",inline"`
+	*MetaBase `json:",inline" overridePath:"metadata"`
 	Spec      *StatefulsetSpecBase `json:"spec,omitempty"`
 }
 
+// Override delegates to the Override of the spec base to apply its additional rules
 func (base *StatefulSetBase) Override(statefulSet appsv1.StatefulSet) appsv1.StatefulSet {
+	return logOverrideError(base.TryOverride(statefulSet))
+}
+
+// TryOverride is Override returning the error instead of logging it, the original is returned unchanged on errors
+func (base *StatefulSetBase) TryOverride(statefulSet appsv1.StatefulSet) (appsv1.StatefulSet, error) {
 	if base == nil {
-		return statefulSet
+		return statefulSet, nil
 	}
-	if base.MetaBase != nil {
-		statefulSet.ObjectMeta = base.MetaBase.Merge(statefulSet.ObjectMeta)
+	meta, err := base.MetaBase.TryMerge(statefulSet.ObjectMeta)
+	if err != nil {
+		return statefulSet, err
 	}
-	if base.Spec != nil {
-		statefulSet.Spec = base.Spec.Override(statefulSet.Spec)
+	spec, err := base.Spec.TryOverride(statefulSet.Spec)
+	if err != nil {
+		return statefulSet, err
 	}
-	return statefulSet
+	statefulSet.ObjectMeta = meta
+	statefulSet.Spec = spec
+	return statefulSet, nil
 }
 
 // +kubebuilder:object:generate=true
@@ -375,31 +340,31 @@ func (base *StatefulSetBase) Override(statefulSet appsv1.StatefulSet) appsv1.Sta
 // Deprecated
 // Consider using StatefulSetSpec in the typeoverrides package combined with the merge package
 type StatefulsetSpecBase struct {
-	Replicas            *int32                            `json:"replicas,omitempty"`
-	Selector            *metav1.LabelSelector             `json:"selector,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	Replicas *int32                `json:"replicas,omitempty"`
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+	// +kubebuilder:validation:Enum=OrderedReady;Parallel
 	PodManagementPolicy appsv1.PodManagementPolicyType    `json:"podManagementPolicy,omitempty"`
 	UpdateStrategy      *appsv1.StatefulSetUpdateStrategy `json:"updateStrategy,omitempty"`
 	Template            *PodTemplateBase                  `json:"template,omitempty"`
 }
 
+// Override appends the match expressions of the selector to the original ones instead of replacing them
 func (base *StatefulsetSpecBase) Override(spec appsv1.StatefulSetSpec) appsv1.StatefulSetSpec {
+	return logOverrideError(base.TryOverride(spec))
+}
+
+// TryOverride is Override returning the error instead of logging it, the original is returned unchanged on errors
+func (base *StatefulsetSpecBase) TryOverride(spec appsv1.StatefulSetSpec) (appsv1.StatefulSetSpec, error) {
 	if base == nil {
-		return spec
-	}
-	if base.Replicas != nil {
-		spec.Replicas = base.Replicas
+		return spec, nil
 	}
-	spec.Selector = mergeSelectors(base.Selector, spec.Selector)
-	if base.PodManagementPolicy != "" {
-		spec.PodManagementPolicy = base.PodManagementPolicy
+	result, err := override(base, spec)
+	if err != nil {
+		return spec, err
 	}
-	if base.UpdateStrategy != nil {
-		spec.UpdateStrategy = *base.UpdateStrategy
-	}
-	if base.Template != nil {
-		spec.Template = base.Template.Override(spec.Template)
-	}
-	return spec
+	result.Selector = appendMatchExpressions(result.Selector, spec.Selector, base.Selector)
+	return result, nil
 }
 
 // +kubebuilder:object:generate=true
@@ -407,21 +372,31 @@ func (base *StatefulsetSpecBase) Override(spec appsv1.StatefulSetSpec) appsv1.St
 // Deprecated
 // Consider using DaemonSet in the typeoverrides package combined with the merge package
 type DaemonSetBase struct {
-	*MetaBase `json:",inline"`
+	*MetaBase `json:",inline" overridePath:"metadata"`
 	Spec      *DaemonSetSpecBase `json:"spec,omitempty"`
 }
 
+// Override delegates to the Override of the spec base to apply its additional rules
 func (base *DaemonSetBase) Override(daemonset appsv1.DaemonSet) appsv1.DaemonSet {
+	return logOverrideError(base.TryOverride(daemonset))
+}
+
+// TryOverride is Override returning the error instead of logging it, the original is returned unchanged on errors
+func (base *DaemonSetBase) TryOverride(daemonset appsv1.DaemonSet) (appsv1.DaemonSet, error) {
 	if base == nil {
-		return daemonset
+		return daemonset, nil
 	}
-	if base.MetaBase != nil {
-		daemonset.ObjectMeta = base.MetaBase.Merge(daemonset.ObjectMeta)
+	meta, err := base.MetaBase.TryMerge(daemonset.ObjectMeta)
+	if err != nil {
+		return daemonset, err
 	}
-	if base.Spec != nil {
-		daemonset.Spec = base.Spec.Override(daemonset.Spec)
+	spec, err := base.Spec.TryOverride(daemonset.Spec)
+	if err != nil {
+		return daemonset, err
 	}
-	return daemonset
+	daemonset.ObjectMeta = meta
+	daemonset.Spec = spec
+	return daemonset, nil
 }
 
 // +kubebuilder:object:generate=true
@@ -436,45 +411,305 @@ type DaemonSetSpecBase struct {
 	Template             *PodTemplateBase                `json:"template,omitempty"`
 }
 
+// Override appends the match expressions of the selector to the original ones instead of replacing them
 func (base *DaemonSetSpecBase) Override(spec appsv1.DaemonSetSpec) appsv1.DaemonSetSpec {
+	return logOverrideError(base.TryOverride(spec))
+}
+
+// TryOverride is Override returning the error instead of logging it, the original is returned unchanged on errors
+func (base *DaemonSetSpecBase) TryOverride(spec appsv1.DaemonSetSpec) (appsv1.DaemonSetSpec, error) {
 	if base == nil {
-		return spec
+		return spec, nil
 	}
-	spec.Selector = mergeSelectors(base.Selector, spec.Selector)
-	if base.UpdateStrategy != nil {
-		spec.UpdateStrategy = *base.UpdateStrategy
+	result, err := override(base, spec)
+	if err != nil {
+		return spec, err
 	}
-	if base.MinReadySeconds != 0 {
-		spec.MinReadySeconds = base.MinReadySeconds
+	result.Selector = appendMatchExpressions(result.Selector, spec.Selector, base.Selector)
+	return result, nil
+}
+
+// +kubebuilder:object:generate=true
+
+// Consider using Job in the typeoverrides package combined with the merge package
+type JobBase struct {
+	*MetaBase `json:",inline" overridePath:"metadata"`
+	Spec      *JobSpecBase `json:"spec,omitempty"`
+}
+
+func (base *JobBase) Override(job batchv1.Job) batchv1.Job {
+	return logOverrideError(base.TryOverride(job))
+}
+
+// TryOverride is Override returning the error instead of logging it, the original is returned unchanged on errors
+func (base *JobBase) TryOverride(job batchv1.Job) (batchv1.Job, error) {
+	return override(base, job)
+}
+
+// +kubebuilder:object:generate=true
+
+// Consider using JobSpec in the typeoverrides package combined with the merge package
+type JobSpecBase struct {
+	// +kubebuilder:validation:Minimum=0
+	Parallelism *int32 `json:"parallelism,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	Completions           *int32 `json:"completions,omitempty"`
+	ActiveDeadlineSeconds *int64 `json:"activeDeadlineSeconds,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	BackoffLimit            *int32           `json:"backoffLimit,omitempty"`
+	TTLSecondsAfterFinished *int32           `json:"ttlSecondsAfterFinished,omitempty"`
+	Suspend                 *bool            `json:"suspend,omitempty"`
+	Template                *PodTemplateBase `json:"template,omitempty"`
+}
+
+func (base *JobSpecBase) Override(spec batchv1.JobSpec) batchv1.JobSpec {
+	return logOverrideError(base.TryOverride(spec))
+}
+
+// TryOverride is Override returning the error instead of logging it, the original is returned unchanged on errors
+func (base *JobSpecBase) TryOverride(spec batchv1.JobSpec) (batchv1.JobSpec, error) {
+	return override(base, spec)
+}
+
+// +kubebuilder:object:generate=true
+
+// Consider using CronJob in the typeoverrides package combined with the merge package
+type CronJobBase struct {
+	*MetaBase `json:",inline" overridePath:"metadata"`
+	Spec      *CronJobSpecBase `json:"spec,omitempty"`
+}
+
+func (base *CronJobBase) Override(cronJob batchv1.CronJob) batchv1.CronJob {
+	return logOverrideError(base.TryOverride(cronJob))
+}
+
+// TryOverride is Override returning the error instead of logging it, the original is returned unchanged on errors
+func (base *CronJobBase) TryOverride(cronJob batchv1.CronJob) (batchv1.CronJob, error) {
+	return override(base, cronJob)
+}
+
+// +kubebuilder:object:generate=true
+
+// Consider using CronJobSpec in the typeoverrides package combined with the merge package
+type CronJobSpecBase struct {
+	Schedule                string  `json:"schedule,omitempty"`
+	TimeZone                *string `json:"timeZone,omitempty"`
+	StartingDeadlineSeconds *int64  `json:"startingDeadlineSeconds,omitempty"`
+	// +kubebuilder:validation:Enum=Allow;Forbid;Replace
+	ConcurrencyPolicy          batchv1.ConcurrencyPolicy `json:"concurrencyPolicy,omitempty"`
+	Suspend                    *bool                     `json:"suspend,omitempty"`
+	SuccessfulJobsHistoryLimit *int32                    `json:"successfulJobsHistoryLimit,omitempty"`
+	FailedJobsHistoryLimit     *int32                    `json:"failedJobsHistoryLimit,omitempty"`
+	JobTemplate                *JobTemplateBase          `json:"jobTemplate,omitempty"`
+}
+
+func (base *CronJobSpecBase) Override(spec batchv1.CronJobSpec) batchv1.CronJobSpec {
+	return logOverrideError(base.TryOverride(spec))
+}
+
+// TryOverride is Override returning the error instead of logging it, the original is returned unchanged on errors
+func (base *CronJobSpecBase) TryOverride(spec batchv1.CronJobSpec) (batchv1.CronJobSpec, error) {
+	return override(base, spec)
+}
+
+// +kubebuilder:object:generate=true
+
+// Consider using JobTemplateSpec in the typeoverrides package combined with the merge package
+type JobTemplateBase struct {
+	Metadata *MetaBase    `json:"metadata,omitempty"`
+	Spec     *JobSpecBase `json:"spec,omitempty"`
+}
+
+func (base *JobTemplateBase) Override(template batchv1.JobTemplateSpec) batchv1.JobTemplateSpec {
+	return logOverrideError(base.TryOverride(template))
+}
+
+// TryOverride is Override returning the error instead of logging it, the original is returned unchanged on errors
+func (base *JobTemplateBase) TryOverride(template batchv1.JobTemplateSpec) (batchv1.JobTemplateSpec, error) {
+	return override(base, template)
+}
+
+// +kubebuilder:object:generate=true
+
+// Consider using HorizontalPodAutoscaler in the typeoverrides package combined with the merge package
+type HorizontalPodAutoscalerBase struct {
+	*MetaBase `json:",inline" overridePath:"metadata"`
+	Spec      *HorizontalPodAutoscalerSpecBase `json:"spec,omitempty"`
+}
+
+func (base *HorizontalPodAutoscalerBase) Override(hpa autoscalingv2.HorizontalPodAutoscaler) autoscalingv2.HorizontalPodAutoscaler {
+	return logOverrideError(base.TryOverride(hpa))
+}
+
+// TryOverride is Override returning the error instead of logging it, the original is returned unchanged on errors
+func (base *HorizontalPodAutoscalerBase) TryOverride(hpa autoscalingv2.HorizontalPodAutoscaler) (autoscalingv2.HorizontalPodAutoscaler, error) {
+	return override(base, hpa)
+}
+
+// +kubebuilder:object:generate=true
+// +kubebuilder:validation:XValidation:rule="!has(self.minReplicas) || !has(self.maxReplicas) || self.minReplicas <= self.maxReplicas",message="minReplicas must not exceed maxReplicas"
+
+// Consider using HorizontalPodAutoscalerSpec in the typeoverrides package combined with the merge package
+type HorizontalPodAutoscalerSpecBase struct {
+	// +kubebuilder:validation:Minimum=1
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+	// +kubebuilder:validation:Minimum=1
+	MaxReplicas *int32                                         `json:"maxReplicas,omitempty"`
+	Metrics     []autoscalingv2.MetricSpec                     `json:"metrics,omitempty"`
+	Behavior    *autoscalingv2.HorizontalPodAutoscalerBehavior `json:"behavior,omitempty"`
+}
+
+func (base *HorizontalPodAutoscalerSpecBase) Override(spec autoscalingv2.HorizontalPodAutoscalerSpec) autoscalingv2.HorizontalPodAutoscalerSpec {
+	return logOverrideError(base.TryOverride(spec))
+}
+
+// TryOverride is Override returning the error instead of logging it, the original is returned unchanged on errors
+func (base *HorizontalPodAutoscalerSpecBase) TryOverride(spec autoscalingv2.HorizontalPodAutoscalerSpec) (autoscalingv2.HorizontalPodAutoscalerSpec, error) {
+	return override(base, spec)
+}
+
+// +kubebuilder:object:generate=true
+
+// Consider using PodDisruptionBudget in the typeoverrides package combined with the merge package
+type PodDisruptionBudgetBase struct {
+	*MetaBase `json:",inline" overridePath:"metadata"`
+	Spec      *PodDisruptionBudgetSpecBase `json:"spec,omitempty"`
+}
+
+// Override delegates to the Override of the spec base to apply its additional rules
+func (base *PodDisruptionBudgetBase) Override(pdb policyv1.PodDisruptionBudget) policyv1.PodDisruptionBudget {
+	return logOverrideError(base.TryOverride(pdb))
+}
+
+// TryOverride is Override returning the error instead of logging it, the original is returned unchanged on errors
+func (base *PodDisruptionBudgetBase) TryOverride(pdb policyv1.PodDisruptionBudget) (policyv1.PodDisruptionBudget, error) {
+	if base == nil {
+		return pdb, nil
 	}
-	if base.RevisionHistoryLimit != nil {
-		spec.RevisionHistoryLimit = base.RevisionHistoryLimit
+	meta, err := base.MetaBase.TryMerge(pdb.ObjectMeta)
+	if err != nil {
+		return pdb, err
 	}
-	if base.Template != nil {
-		spec.Template = base.Template.Override(spec.Template)
+	spec, err := base.Spec.TryOverride(pdb.Spec)
+	if err != nil {
+		return pdb, err
 	}
-	return spec
+	pdb.ObjectMeta = meta
+	pdb.Spec = spec
+	return pdb, nil
+}
+
+// +kubebuilder:object:generate=true
+// +kubebuilder:validation:XValidation:rule="!(has(self.minAvailable) && has(self.maxUnavailable))",message="minAvailable and maxUnavailable are mutually exclusive"
+
+// Consider using PodDisruptionBudgetSpec in the typeoverrides package combined with the merge package
+type PodDisruptionBudgetSpecBase struct {
+	MinAvailable               *intstr.IntOrString                      `json:"minAvailable,omitempty"`
+	MaxUnavailable             *intstr.IntOrString                      `json:"maxUnavailable,omitempty"`
+	Selector                   *metav1.LabelSelector                    `json:"selector,omitempty"`
+	UnhealthyPodEvictionPolicy *policyv1.UnhealthyPodEvictionPolicyType `json:"unhealthyPodEvictionPolicy,omitempty"`
+}
+
+// Override sets minAvailable and maxUnavailable mutually exclusively, as the API rejects budgets with both set
+func (base *PodDisruptionBudgetSpecBase) Override(spec policyv1.PodDisruptionBudgetSpec) policyv1.PodDisruptionBudgetSpec {
+	return logOverrideError(base.TryOverride(spec))
 }
 
-func mergeSelectors(base, spec *metav1.LabelSelector) *metav1.LabelSelector {
+// TryOverride is Override returning the error instead of logging it, the original is returned unchanged on errors
+func (base *PodDisruptionBudgetSpecBase) TryOverride(spec policyv1.PodDisruptionBudgetSpec) (policyv1.PodDisruptionBudgetSpec, error) {
 	if base == nil {
-		return spec
+		return spec, nil
 	}
-
-	if base.MatchLabels != nil {
-		if spec == nil {
-			spec = &metav1.LabelSelector{}
-		}
-		if spec.MatchLabels == nil {
-			spec.MatchLabels = make(map[string]string)
-		}
-		for k, v := range base.MatchLabels {
-			spec.MatchLabels[k] = v
-		}
+	spec, err := override(base, spec)
+	if err != nil {
+		return spec, err
+	}
+	if base.MinAvailable != nil {
+		spec.MaxUnavailable = nil
 	}
-	if base.MatchExpressions != nil {
-		spec.MatchExpressions = append(spec.MatchExpressions, base.MatchExpressions...)
+	if base.MaxUnavailable != nil {
+		spec.MinAvailable = nil
 	}
+	return spec, nil
+}
+
+// +kubebuilder:object:generate=true
+
+// Consider using Ingress in the typeoverrides package combined with the merge package
+type IngressBase struct {
+	*MetaBase `json:",inline" overridePath:"metadata"`
+	Spec      *IngressSpecBase `json:"spec,omitempty"`
+}
+
+func (base *IngressBase) Override(ingress networkingv1.Ingress) networkingv1.Ingress {
+	return logOverrideError(base.TryOverride(ingress))
+}
+
+// TryOverride is Override returning the error instead of logging it, the original is returned unchanged on errors
+func (base *IngressBase) TryOverride(ingress networkingv1.Ingress) (networkingv1.Ingress, error) {
+	return override(base, ingress)
+}
+
+// +kubebuilder:object:generate=true
+
+// Consider using Ingress in the typeoverrides package combined with the merge package
+type IngressSpecBase struct {
+	IngressClassName *string                      `json:"ingressClassName,omitempty"`
+	DefaultBackend   *networkingv1.IngressBackend `json:"defaultBackend,omitempty"`
+	TLS              []networkingv1.IngressTLS    `json:"tls,omitempty"`
+	Rules            []networkingv1.IngressRule   `json:"rules,omitempty"`
+}
 
-	return spec
+func (base *IngressSpecBase) Override(spec networkingv1.IngressSpec) networkingv1.IngressSpec {
+	return logOverrideError(base.TryOverride(spec))
+}
+
+// TryOverride is Override returning the error instead of logging it, the original is returned unchanged on errors
+func (base *IngressSpecBase) TryOverride(spec networkingv1.IngressSpec) (networkingv1.IngressSpec, error) {
+	return override(base, spec)
+}
+
+// +kubebuilder:object:generate=true
+
+// Consider using NetworkPolicy in the typeoverrides package combined with the merge package
+type NetworkPolicyBase struct {
+	*MetaBase `json:",inline" overridePath:"metadata"`
+	Spec      *NetworkPolicySpecBase `json:"spec,omitempty"`
+}
+
+func (base *NetworkPolicyBase) Override(networkPolicy networkingv1.NetworkPolicy) networkingv1.NetworkPolicy {
+	return logOverrideError(base.TryOverride(networkPolicy))
+}
+
+// TryOverride is Override returning the error instead of logging it, the original is returned unchanged on errors
+func (base *NetworkPolicyBase) TryOverride(networkPolicy networkingv1.NetworkPolicy) (networkingv1.NetworkPolicy, error) {
+	return override(base, networkPolicy)
+}
+
+// +kubebuilder:object:generate=true
+
+// Consider using NetworkPolicySpec in the typeoverrides package combined with the merge package
+type NetworkPolicySpecBase struct {
+	PodSelector *metav1.LabelSelector                   `json:"podSelector,omitempty"`
+	Ingress     []networkingv1.NetworkPolicyIngressRule `json:"ingress,omitempty"`
+	Egress      []networkingv1.NetworkPolicyEgressRule  `json:"egress,omitempty"`
+	PolicyTypes []networkingv1.PolicyType               `json:"policyTypes,omitempty"`
+}
+
+func (base *NetworkPolicySpecBase) Override(spec networkingv1.NetworkPolicySpec) networkingv1.NetworkPolicySpec {
+	return logOverrideError(base.TryOverride(spec))
+}
+
+// TryOverride is Override returning the error instead of logging it, the original is returned unchanged on errors
+func (base *NetworkPolicySpecBase) TryOverride(spec networkingv1.NetworkPolicySpec) (networkingv1.NetworkPolicySpec, error) {
+	return override(base, spec)
+}
+
+// appendMatchExpressions restores the match expressions of the original selector in front of the ones of the base
+func appendMatchExpressions(result, original, base *metav1.LabelSelector) *metav1.LabelSelector {
+	if result == nil || original == nil || base == nil || len(base.MatchExpressions) == 0 {
+		return result
+	}
+	result.MatchExpressions = append(append([]metav1.LabelSelectorRequirement(nil), original.MatchExpressions...), base.MatchExpressions...)
+	return result
 }
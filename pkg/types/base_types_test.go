@@ -20,9 +20,15 @@ import (
 
 	"github.com/stretchr/testify/require"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
 	v12 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	"github.com/cisco-open/operator-tools/pkg/types"
 	"github.com/cisco-open/operator-tools/pkg/utils"
@@ -606,7 +612,7 @@ func TestPodSpecOverride(t *testing.T) {
 						Image: "override-image",
 					},
 					{
-						Name:  "new", // this one does not exist in the original
+						Name:  "new", // this one does not exist in the original and will be added
 						Image: "new-image",
 					},
 				},
@@ -633,6 +639,10 @@ func TestPodSpecOverride(t *testing.T) {
 						Name:  "old",
 						Image: "old-image",
 					},
+					{
+						Name:  "new",
+						Image: "new-image",
+					},
 				},
 			},
 		},
@@ -645,7 +655,7 @@ func TestPodSpecOverride(t *testing.T) {
 						Image: "override-image",
 					},
 					{
-						Name:  "new", // this one does not exist in the original
+						Name:  "new", // this one does not exist in the original and will be added
 						Image: "new-image",
 					},
 				},
@@ -672,6 +682,110 @@ func TestPodSpecOverride(t *testing.T) {
 						Name:  "old",
 						Image: "old-image",
 					},
+					{
+						Name:  "new",
+						Image: "new-image",
+					},
+				},
+			},
+		},
+		{
+			name: "volumes merged by key, tolerations replaced by key, node selector merged",
+			base: &types.PodSpecBase{
+				Volumes: []v12.Volume{
+					{Name: "config", VolumeSource: v12.VolumeSource{Secret: &v12.SecretVolumeSource{SecretName: "override"}}},
+					{Name: "extra", VolumeSource: v12.VolumeSource{EmptyDir: &v12.EmptyDirVolumeSource{}}},
+				},
+				Tolerations: []v12.Toleration{
+					{Key: "dedicated", Operator: v12.TolerationOpEqual, Value: "logging", Effect: v12.TaintEffectNoSchedule},
+				},
+				NodeSelector: map[string]string{"b": "3", "c": "4"},
+			},
+			spec: v12.PodSpec{
+				Volumes: []v12.Volume{
+					{Name: "data", VolumeSource: v12.VolumeSource{EmptyDir: &v12.EmptyDirVolumeSource{}}},
+					{Name: "config", VolumeSource: v12.VolumeSource{ConfigMap: &v12.ConfigMapVolumeSource{}}},
+				},
+				Tolerations: []v12.Toleration{
+					{Key: "dedicated", Operator: v12.TolerationOpExists},
+					{Key: "other", Operator: v12.TolerationOpExists},
+				},
+				NodeSelector: map[string]string{"a": "1", "b": "2"},
+			},
+			want: v12.PodSpec{
+				Volumes: []v12.Volume{
+					{Name: "data", VolumeSource: v12.VolumeSource{EmptyDir: &v12.EmptyDirVolumeSource{}}},
+					{Name: "config", VolumeSource: v12.VolumeSource{Secret: &v12.SecretVolumeSource{SecretName: "override"}}},
+					{Name: "extra", VolumeSource: v12.VolumeSource{EmptyDir: &v12.EmptyDirVolumeSource{}}},
+				},
+				Tolerations: []v12.Toleration{
+					{Key: "dedicated", Operator: v12.TolerationOpEqual, Value: "logging", Effect: v12.TaintEffectNoSchedule},
+					{Key: "other", Operator: v12.TolerationOpExists},
+				},
+				NodeSelector: map[string]string{"a": "1", "b": "3", "c": "4"},
+			},
+		},
+		{
+			name: "tolerations of a key replaced as a whole",
+			base: &types.PodSpecBase{
+				Tolerations: []v12.Toleration{
+					{Key: "gpu", Operator: v12.TolerationOpExists},
+					{Key: "dedicated", Operator: v12.TolerationOpExists},
+				},
+			},
+			spec: v12.PodSpec{
+				Tolerations: []v12.Toleration{
+					{Key: "dedicated", Operator: v12.TolerationOpEqual, Value: "logging", Effect: v12.TaintEffectNoSchedule},
+					{Key: "other", Operator: v12.TolerationOpExists},
+					{Key: "dedicated", Operator: v12.TolerationOpEqual, Value: "logging", Effect: v12.TaintEffectNoExecute},
+				},
+			},
+			want: v12.PodSpec{
+				Tolerations: []v12.Toleration{
+					{Key: "dedicated", Operator: v12.TolerationOpExists},
+					{Key: "other", Operator: v12.TolerationOpExists},
+					{Key: "gpu", Operator: v12.TolerationOpExists},
+				},
+			},
+		},
+		{
+			name: "image pull secrets appended",
+			base: &types.PodSpecBase{
+				ImagePullSecrets: []v12.LocalObjectReference{{Name: "new"}},
+			},
+			spec: v12.PodSpec{
+				ImagePullSecrets: []v12.LocalObjectReference{{Name: "old"}},
+			},
+			want: v12.PodSpec{
+				ImagePullSecrets: []v12.LocalObjectReference{{Name: "old"}, {Name: "new"}},
+			},
+		},
+		{
+			name: "sidecar injected with its env, ports and mounts",
+			base: &types.PodSpecBase{
+				Containers: []types.ContainerBase{
+					{
+						Name:         "sidecar",
+						Image:        "sidecar-image",
+						Env:          []v12.EnvVar{{Name: "A", Value: "1"}},
+						Ports:        []v12.ContainerPort{{Name: "metrics", ContainerPort: 9090}},
+						VolumeMounts: []v12.VolumeMount{{Name: "config", MountPath: "/config"}},
+					},
+				},
+			},
+			spec: v12.PodSpec{
+				Containers: []v12.Container{{Name: "app", Image: "app-image"}},
+			},
+			want: v12.PodSpec{
+				Containers: []v12.Container{
+					{Name: "app", Image: "app-image"},
+					{
+						Name:         "sidecar",
+						Image:        "sidecar-image",
+						Env:          []v12.EnvVar{{Name: "A", Value: "1"}},
+						Ports:        []v12.ContainerPort{{Name: "metrics", ContainerPort: 9090}},
+						VolumeMounts: []v12.VolumeMount{{Name: "config", MountPath: "/config"}},
+					},
 				},
 			},
 		},
@@ -724,3 +838,366 @@ func TestPodTemplateOverride(t *testing.T) {
 		})
 	}
 }
+
+func TestJobOverride(t *testing.T) {
+	base := &types.JobBase{
+		MetaBase: &types.MetaBase{Labels: map[string]string{"a": "1"}},
+		Spec: &types.JobSpecBase{
+			BackoffLimit: utils.IntPointer(0),
+			Template: &types.PodTemplateBase{
+				PodSpec: &types.PodSpecBase{
+					Containers: []types.ContainerBase{
+						{Name: "job", Image: "image-2"},
+					},
+				},
+			},
+		},
+	}
+	job := batchv1.Job{
+		Spec: batchv1.JobSpec{
+			BackoffLimit: utils.IntPointer(6),
+			Completions:  utils.IntPointer(2),
+			Template: v12.PodTemplateSpec{
+				Spec: v12.PodSpec{
+					Containers: []v12.Container{
+						{Name: "job", Image: "image"},
+					},
+				},
+			},
+		},
+	}
+
+	require.Equal(t, batchv1.Job{
+		ObjectMeta: v1.ObjectMeta{Labels: map[string]string{"a": "1"}},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: utils.IntPointer(0),
+			Completions:  utils.IntPointer(2),
+			Template: v12.PodTemplateSpec{
+				Spec: v12.PodSpec{
+					Containers: []v12.Container{
+						{Name: "job", Image: "image-2"},
+					},
+				},
+			},
+		},
+	}, base.Override(job))
+}
+
+func TestCronJobOverride(t *testing.T) {
+	base := &types.CronJobBase{
+		Spec: &types.CronJobSpecBase{
+			Schedule:          "*/5 * * * *",
+			ConcurrencyPolicy: batchv1.ForbidConcurrent,
+			JobTemplate: &types.JobTemplateBase{
+				Metadata: &types.MetaBase{Annotations: map[string]string{"a": "1"}},
+				Spec:     &types.JobSpecBase{ActiveDeadlineSeconds: utils.IntPointer64(60)},
+			},
+		},
+	}
+	cronJob := batchv1.CronJob{
+		Spec: batchv1.CronJobSpec{
+			Schedule:               "0 * * * *",
+			FailedJobsHistoryLimit: utils.IntPointer(1),
+		},
+	}
+
+	require.Equal(t, batchv1.CronJob{
+		Spec: batchv1.CronJobSpec{
+			Schedule:               "*/5 * * * *",
+			ConcurrencyPolicy:      batchv1.ForbidConcurrent,
+			FailedJobsHistoryLimit: utils.IntPointer(1),
+			JobTemplate: batchv1.JobTemplateSpec{
+				ObjectMeta: v1.ObjectMeta{Annotations: map[string]string{"a": "1"}},
+				Spec:       batchv1.JobSpec{ActiveDeadlineSeconds: utils.IntPointer64(60)},
+			},
+		},
+	}, base.Override(cronJob))
+}
+
+func TestHorizontalPodAutoscalerOverride(t *testing.T) {
+	var nilBase *types.HorizontalPodAutoscalerBase
+	hpa := autoscalingv2.HorizontalPodAutoscaler{
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "app"},
+			MinReplicas:    utils.IntPointer(1),
+			MaxReplicas:    3,
+		},
+	}
+	require.Equal(t, hpa, nilBase.Override(hpa))
+
+	base := &types.HorizontalPodAutoscalerBase{
+		Spec: &types.HorizontalPodAutoscalerSpecBase{
+			MaxReplicas: utils.IntPointer(10),
+		},
+	}
+	require.Equal(t, autoscalingv2.HorizontalPodAutoscaler{
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "app"},
+			MinReplicas:    utils.IntPointer(1),
+			MaxReplicas:    10,
+		},
+	}, base.Override(hpa))
+}
+
+func TestPodDisruptionBudgetOverride(t *testing.T) {
+	minAvailable := intstr.FromInt(1)
+	maxUnavailable := intstr.FromString("50%")
+
+	tests := []struct {
+		name string
+		base *types.PodDisruptionBudgetSpecBase
+		spec policyv1.PodDisruptionBudgetSpec
+		want policyv1.PodDisruptionBudgetSpec
+	}{
+		{
+			name: "maxUnavailable replaces minAvailable",
+			base: &types.PodDisruptionBudgetSpecBase{MaxUnavailable: &maxUnavailable},
+			spec: policyv1.PodDisruptionBudgetSpec{MinAvailable: &minAvailable},
+			want: policyv1.PodDisruptionBudgetSpec{MaxUnavailable: &maxUnavailable},
+		},
+		{
+			// the selector of a PodDisruptionBudgetSpec has the replace patch strategy
+			name: "replace selector",
+			base: &types.PodDisruptionBudgetSpecBase{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"b": "2"}},
+			},
+			spec: policyv1.PodDisruptionBudgetSpec{
+				MinAvailable: &minAvailable,
+				Selector:     &metav1.LabelSelector{MatchLabels: map[string]string{"a": "1"}},
+			},
+			want: policyv1.PodDisruptionBudgetSpec{
+				MinAvailable: &minAvailable,
+				Selector:     &metav1.LabelSelector{MatchLabels: map[string]string{"b": "2"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.base.Override(tt.spec); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("base.Override() = \n%#v\nwant\n%#v\n", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIngressOverride(t *testing.T) {
+	base := &types.IngressBase{
+		MetaBase: &types.MetaBase{Annotations: map[string]string{"b": "2"}},
+		Spec: &types.IngressSpecBase{
+			IngressClassName: utils.StringPointer("traefik"),
+			TLS:              []networkingv1.IngressTLS{{Hosts: []string{"example.com"}, SecretName: "tls"}},
+		},
+	}
+	ingress := networkingv1.Ingress{
+		ObjectMeta: v1.ObjectMeta{Annotations: map[string]string{"a": "1"}},
+		Spec: networkingv1.IngressSpec{
+			IngressClassName: utils.StringPointer("nginx"),
+			Rules:            []networkingv1.IngressRule{{Host: "example.com"}},
+		},
+	}
+
+	require.Equal(t, networkingv1.Ingress{
+		ObjectMeta: v1.ObjectMeta{Annotations: map[string]string{"a": "1", "b": "2"}},
+		Spec: networkingv1.IngressSpec{
+			IngressClassName: utils.StringPointer("traefik"),
+			TLS:              []networkingv1.IngressTLS{{Hosts: []string{"example.com"}, SecretName: "tls"}},
+			Rules:            []networkingv1.IngressRule{{Host: "example.com"}},
+		},
+	}, base.Override(ingress))
+}
+
+func TestNetworkPolicyOverride(t *testing.T) {
+	base := &types.NetworkPolicyBase{
+		Spec: &types.NetworkPolicySpecBase{
+			PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"b": "2"}},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+		},
+	}
+	networkPolicy := networkingv1.NetworkPolicy{
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"a": "1"}},
+			Ingress:     []networkingv1.NetworkPolicyIngressRule{{}},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+		},
+	}
+
+	require.Equal(t, networkingv1.NetworkPolicy{
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"a": "1", "b": "2"}},
+			Ingress:     []networkingv1.NetworkPolicyIngressRule{{}},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+		},
+	}, base.Override(networkPolicy))
+}
+
+func TestContainerOverride(t *testing.T) {
+	tests := []struct {
+		name string
+		base *types.ContainerBase
+		spec v12.Container
+		want v12.Container
+	}{
+		{
+			name: "env merged by name",
+			base: &types.ContainerBase{
+				Env: []v12.EnvVar{
+					{Name: "B", Value: "override"},
+					{Name: "C", ValueFrom: &v12.EnvVarSource{FieldRef: &v12.ObjectFieldSelector{FieldPath: "metadata.name"}}},
+				},
+			},
+			spec: v12.Container{
+				Env: []v12.EnvVar{{Name: "A", Value: "1"}, {Name: "B", Value: "2"}},
+			},
+			want: v12.Container{
+				Env: []v12.EnvVar{
+					{Name: "A", Value: "1"},
+					{Name: "B", Value: "override"},
+					{Name: "C", ValueFrom: &v12.EnvVarSource{FieldRef: &v12.ObjectFieldSelector{FieldPath: "metadata.name"}}},
+				},
+			},
+		},
+		{
+			name: "mounts merged by mount path, ports by container port",
+			base: &types.ContainerBase{
+				VolumeMounts: []v12.VolumeMount{{Name: "other", MountPath: "/data", ReadOnly: true}},
+				Ports:        []v12.ContainerPort{{Name: "http-alt", ContainerPort: 8080}},
+			},
+			spec: v12.Container{
+				VolumeMounts: []v12.VolumeMount{{Name: "data", MountPath: "/data"}, {Name: "config", MountPath: "/config"}},
+				Ports:        []v12.ContainerPort{{Name: "http", ContainerPort: 8080}, {Name: "metrics", ContainerPort: 9090}},
+			},
+			want: v12.Container{
+				VolumeMounts: []v12.VolumeMount{{Name: "other", MountPath: "/data", ReadOnly: true}, {Name: "config", MountPath: "/config"}},
+				Ports:        []v12.ContainerPort{{Name: "http-alt", ContainerPort: 8080}, {Name: "metrics", ContainerPort: 9090}},
+			},
+		},
+		{
+			name: "args replaced, probes overridden",
+			base: &types.ContainerBase{
+				Args:           []string{"--debug"},
+				ReadinessProbe: &v12.Probe{PeriodSeconds: 5},
+			},
+			spec: v12.Container{
+				Args:          []string{"--verbose", "--port=80"},
+				LivenessProbe: &v12.Probe{PeriodSeconds: 10},
+			},
+			want: v12.Container{
+				Args:           []string{"--debug"},
+				LivenessProbe:  &v12.Probe{PeriodSeconds: 10},
+				ReadinessProbe: &v12.Probe{PeriodSeconds: 5},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			original := tt.spec.DeepCopy()
+			if got := tt.base.Override(tt.spec); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("base.Override() = \n%#v\nwant\n%#v\n", got, tt.want)
+			}
+			// the lists of the original container are not modified in place
+			require.Equal(t, original, &tt.spec)
+		})
+	}
+}
+
+func TestDeploymentBaseOverrideDelegatesToMerge(t *testing.T) {
+	base := &types.DeploymentBase{
+		MetaBase: &types.MetaBase{Labels: map[string]string{"b": "2"}},
+		Spec: &types.DeploymentSpecBase{
+			Template: &types.PodTemplateBase{
+				PodSpec: &types.PodSpecBase{
+					Containers: []types.ContainerBase{{Name: "app", PullPolicy: v12.PullAlways, Resources: &v12.ResourceRequirements{
+						Limits: v12.ResourceList{v12.ResourceCPU: resource.MustParse("200m")},
+					}}},
+				},
+			},
+		},
+	}
+	deployment := appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "name", Labels: map[string]string{"a": "1"}},
+		Spec: appsv1.DeploymentSpec{
+			Template: v12.PodTemplateSpec{
+				Spec: v12.PodSpec{
+					Containers: []v12.Container{{Name: "app", Image: "app-image", Resources: v12.ResourceRequirements{
+						Limits: v12.ResourceList{v12.ResourceCPU: resource.MustParse("100m"), v12.ResourceMemory: resource.MustParse("100M")},
+					}}},
+				},
+			},
+		},
+	}
+
+	result := base.Override(deployment)
+
+	require.Equal(t, "name", result.Name)
+	require.Equal(t, map[string]string{"a": "1", "b": "2"}, result.Labels)
+	container := result.Spec.Template.Spec.Containers[0]
+	require.Equal(t, "app-image", container.Image)
+	require.Equal(t, v12.PullAlways, container.ImagePullPolicy)
+	// resources are merged the same way merge.Merge does it
+	require.Equal(t, "200m", container.Resources.Limits.Cpu().String())
+	require.Equal(t, "100M", container.Resources.Limits.Memory().String())
+}
+
+func TestContainerBaseOverrideReplacesProbes(t *testing.T) {
+	base := &types.ContainerBase{
+		LivenessProbe: &v12.Probe{ProbeHandler: v12.ProbeHandler{HTTPGet: &v12.HTTPGetAction{Path: "/healthz", Port: intstr.FromInt(8080)}}},
+	}
+	container := v12.Container{
+		Name: "app",
+		LivenessProbe: &v12.Probe{
+			ProbeHandler:  v12.ProbeHandler{Exec: &v12.ExecAction{Command: []string{"check"}}},
+			PeriodSeconds: 30,
+		},
+	}
+
+	result := base.Override(container)
+	require.Equal(t, base.LivenessProbe, result.LivenessProbe)
+}
+
+func TestPodSpecBaseOverrideMissingMergeKeys(t *testing.T) {
+	base := &types.PodSpecBase{
+		Tolerations: []v12.Toleration{{Operator: v12.TolerationOpExists}},
+		Containers:  []types.ContainerBase{{Image: "unnamed"}, {Name: "app", Env: []v12.EnvVar{{Value: "unnamed"}}}},
+	}
+	spec := v12.PodSpec{
+		Tolerations: []v12.Toleration{{Operator: v12.TolerationOpExists, Effect: v12.TaintEffectNoSchedule}},
+		Containers:  []v12.Container{{Name: "app", Image: "app"}},
+	}
+
+	result := base.Override(spec)
+	require.Equal(t, []v12.Toleration{{Operator: v12.TolerationOpExists}}, result.Tolerations)
+	require.Equal(t, []v12.Container{
+		{Name: "app", Image: "app", Env: []v12.EnvVar{{Value: "unnamed"}}},
+		{Image: "unnamed"},
+	}, result.Containers)
+}
+
+func TestTryOverrideReturnsError(t *testing.T) {
+	// ports are merged by containerPort, so the same port with two protocols can't be applied
+	base := &types.ContainerBase{
+		Image: "app:2.0",
+		Ports: []v12.ContainerPort{{ContainerPort: 53, Protocol: v12.ProtocolTCP}, {ContainerPort: 53, Protocol: v12.ProtocolUDP}},
+	}
+	container := v12.Container{Name: "app", Image: "app:1.0", Ports: []v12.ContainerPort{{ContainerPort: 53, Protocol: v12.ProtocolTCP}}}
+
+	result, err := base.TryOverride(container)
+	require.Error(t, err)
+	require.Equal(t, container, result)
+	require.Equal(t, container, base.Override(container))
+
+	deployment := appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app"},
+		Spec:       appsv1.DeploymentSpec{Template: v12.PodTemplateSpec{Spec: v12.PodSpec{Containers: []v12.Container{container}}}},
+	}
+	deploymentBase := &types.DeploymentBase{
+		MetaBase: &types.MetaBase{Labels: map[string]string{"app": "test"}},
+		Spec: &types.DeploymentSpecBase{Template: &types.PodTemplateBase{PodSpec: &types.PodSpecBase{
+			Containers: []types.ContainerBase{{Name: "app", Ports: base.Ports}},
+		}}},
+	}
+	overridden, err := deploymentBase.TryOverride(deployment)
+	require.Error(t, err)
+	require.Equal(t, deployment, overridden)
+}
@@ -0,0 +1,65 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"emperror.dev/errors"
+
+	"github.com/cisco-open/operator-tools/pkg/merge"
+	"github.com/cisco-open/operator-tools/pkg/utils"
+)
+
+// Struct tags that control how list and map fields of the Base types are applied to the original object,
+// e.g. `overrideStrategy:"merge" overrideMergeKey:"name"`
+const (
+	OverrideStrategyTag = merge.OverrideStrategyTag
+	OverrideMergeKeyTag = merge.OverrideMergeKeyTag
+	OverridePathTag     = merge.OverridePathTag
+)
+
+// OverrideStrategy of a list or map field of a Base type
+type OverrideStrategy = merge.OverrideStrategy
+
+const (
+	OverrideStrategyReplace = merge.OverrideStrategyReplace
+	OverrideStrategyMerge   = merge.OverrideStrategyMerge
+	OverrideStrategyAppend  = merge.OverrideStrategyAppend
+	// OverrideStrategyReplaceByKey replaces the items with the same merge key as a whole instead of merging them
+	OverrideStrategyReplaceByKey = merge.OverrideStrategyReplaceByKey
+)
+
+// override applies the base to the target with merge.Override, keeping the order of the items of the target.
+// List items missing their merge key are matched with each other, like the Base types did before merge.Override.
+// The target is returned unchanged along with the error if the base can't be applied, e.g. because of duplicate merge keys.
+//
+// Null values can't clear fields of the target, as the Base types are typed overrides, see the merge package.
+func override[B, T any](base *B, target T) (T, error) {
+	if base == nil {
+		return target, nil
+	}
+	result, err := merge.Override(target, base, merge.WithTargetOrder(), merge.WithMissingMergeKeys())
+	if err != nil {
+		return target, errors.WrapIf(err, "could not apply base")
+	}
+	return result, nil
+}
+
+// logOverrideError logs the error of the TryOverride methods for the Override methods, that can't return it
+func logOverrideError[T any](result T, err error) T {
+	if err != nil {
+		utils.Log.Error(err, "base override is not applied")
+	}
+	return result
+}
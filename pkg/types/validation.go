@@ -0,0 +1,312 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/cisco-open/operator-tools/pkg/typeoverride"
+)
+
+var supportedPullPolicies = sets.New(corev1.PullAlways, corev1.PullNever, corev1.PullIfNotPresent)
+
+// Validate checks the values of the container base, see the Validate methods of the typeoverride package
+func (base *ContainerBase) Validate(path *field.Path) field.ErrorList {
+	if base == nil {
+		return nil
+	}
+	var errs field.ErrorList
+	if base.Name != "" {
+		for _, msg := range validation.IsDNS1123Label(base.Name) {
+			errs = append(errs, field.Invalid(path.Child("name"), base.Name, msg))
+		}
+	}
+	if base.PullPolicy != "" && !supportedPullPolicies.Has(base.PullPolicy) {
+		errs = append(errs, field.NotSupported(path.Child("pullPolicy"), base.PullPolicy, sets.List(supportedPullPolicies)))
+	}
+	errs = append(errs, typeoverride.ValidateResourceRequirements(base.Resources, path.Child("resources"))...)
+
+	envs := sets.New[string]()
+	for i, env := range base.Env {
+		switch {
+		case env.Name == "":
+			errs = append(errs, field.Required(path.Child("env").Index(i).Child("name"), "environment variables are merged by name"))
+		case envs.Has(env.Name):
+			errs = append(errs, field.Duplicate(path.Child("env").Index(i).Child("name"), env.Name))
+		}
+		envs.Insert(env.Name)
+	}
+
+	errs = append(errs, validateContainerBasePorts(base.Ports, path.Child("ports"))...)
+
+	return append(errs, typeoverride.ValidateVolumeMounts(base.VolumeMounts, path.Child("volumeMounts"))...)
+}
+
+// Validate checks that containers and volumes are named uniquely, as they are merged by name, the tolerations and the containers themselves
+func (base *PodSpecBase) Validate(path *field.Path) field.ErrorList {
+	if base == nil {
+		return nil
+	}
+	errs := typeoverride.ValidateVolumes(base.Volumes, path.Child("volumes"))
+	errs = append(errs, typeoverride.ValidateTolerations(base.Tolerations, path.Child("tolerations"))...)
+	// names have to be unique among all containers of the pod
+	names := sets.New[string]()
+	errs = append(errs, validateContainerBases(base.InitContainers, names, path.Child("initContainers"))...)
+	return append(errs, validateContainerBases(base.Containers, names, path.Child("containers"))...)
+}
+
+// ValidateTarget checks the base against the pod spec it is applied to.
+// Containers missing from the target are added, so they need an image, and mounted volumes have to exist in either of them.
+func (base *PodSpecBase) ValidateTarget(spec corev1.PodSpec, path *field.Path) field.ErrorList {
+	if base == nil {
+		return nil
+	}
+	volumes := sets.New[string]()
+	for _, v := range append(append([]corev1.Volume{}, spec.Volumes...), base.Volumes...) {
+		volumes.Insert(v.Name)
+	}
+	errs := validateTargetContainerBases(base.InitContainers, spec.InitContainers, volumes, path.Child("initContainers"))
+	return append(errs, validateTargetContainerBases(base.Containers, spec.Containers, volumes, path.Child("containers"))...)
+}
+
+// validateContainerBasePorts checks that the port numbers are valid and unique regardless of the protocol, as they are merged by containerPort
+func validateContainerBasePorts(ports []corev1.ContainerPort, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	seen := sets.New[int32]()
+	for i, port := range ports {
+		for _, msg := range validation.IsValidPortNum(int(port.ContainerPort)) {
+			errs = append(errs, field.Invalid(path.Index(i).Child("containerPort"), port.ContainerPort, msg))
+		}
+		if seen.Has(port.ContainerPort) {
+			errs = append(errs, field.Duplicate(path.Index(i).Child("containerPort"), port.ContainerPort))
+		}
+		seen.Insert(port.ContainerPort)
+	}
+	return errs
+}
+
+func validateContainerBases(containers []ContainerBase, names sets.Set[string], path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for i := range containers {
+		c := &containers[i]
+		switch {
+		case c.Name == "":
+			errs = append(errs, field.Required(path.Index(i).Child("name"), "containers are merged by name"))
+		case names.Has(c.Name):
+			errs = append(errs, field.Duplicate(path.Index(i).Child("name"), c.Name))
+		}
+		names.Insert(c.Name)
+		errs = append(errs, c.Validate(path.Index(i))...)
+	}
+	return errs
+}
+
+func validateTargetContainerBases(containers []ContainerBase, target []corev1.Container, volumes sets.Set[string], path *field.Path) field.ErrorList {
+	images := make(map[string]string, len(target))
+	for _, c := range target {
+		images[c.Name] = c.Image
+	}
+	var errs field.ErrorList
+	for i, c := range containers {
+		if c.Image == "" && images[c.Name] == "" {
+			errs = append(errs, field.Required(path.Index(i).Child("image"), fmt.Sprintf("container %s is not part of the target", c.Name)))
+		}
+		errs = append(errs, typeoverride.ValidateMountedVolumes(c.VolumeMounts, volumes, path.Index(i).Child("volumeMounts"))...)
+	}
+	return errs
+}
+
+// Validate checks the metadata and the pod spec of the template
+func (base *PodTemplateBase) Validate(path *field.Path) field.ErrorList {
+	if base == nil {
+		return nil
+	}
+	errs := validateMetaBase(base.Metadata, path.Child("metadata"))
+	return append(errs, base.PodSpec.Validate(path.Child("spec"))...)
+}
+
+// ValidateTarget checks the pod spec of the base against the pod spec of the template it is applied to
+func (base *PodTemplateBase) ValidateTarget(template corev1.PodTemplateSpec, path *field.Path) field.ErrorList {
+	if base == nil {
+		return nil
+	}
+	return base.PodSpec.ValidateTarget(template.Spec, path.Child("spec"))
+}
+
+// Validate checks the metadata and the spec of the deployment
+func (base *DeploymentBase) Validate(path *field.Path) field.ErrorList {
+	if base == nil {
+		return nil
+	}
+	errs := validateMetaBase(base.MetaBase, path.Child("metadata"))
+	return append(errs, base.Spec.Validate(path.Child("spec"))...)
+}
+
+// Validate checks the number of replicas and the pod template
+func (base *DeploymentSpecBase) Validate(path *field.Path) field.ErrorList {
+	if base == nil {
+		return nil
+	}
+	errs := validateNonNegative(base.Replicas, path.Child("replicas"))
+	return append(errs, base.Template.Validate(path.Child("template"))...)
+}
+
+// Validate checks the metadata and the spec of the statefulset
+func (base *StatefulSetBase) Validate(path *field.Path) field.ErrorList {
+	if base == nil {
+		return nil
+	}
+	errs := validateMetaBase(base.MetaBase, path.Child("metadata"))
+	return append(errs, base.Spec.Validate(path.Child("spec"))...)
+}
+
+// Validate checks the number of replicas and the pod template
+func (base *StatefulsetSpecBase) Validate(path *field.Path) field.ErrorList {
+	if base == nil {
+		return nil
+	}
+	errs := validateNonNegative(base.Replicas, path.Child("replicas"))
+	return append(errs, base.Template.Validate(path.Child("template"))...)
+}
+
+// Validate checks the metadata and the spec of the daemonset
+func (base *DaemonSetBase) Validate(path *field.Path) field.ErrorList {
+	if base == nil {
+		return nil
+	}
+	errs := validateMetaBase(base.MetaBase, path.Child("metadata"))
+	return append(errs, base.Spec.Validate(path.Child("spec"))...)
+}
+
+// Validate checks the pod template
+func (base *DaemonSetSpecBase) Validate(path *field.Path) field.ErrorList {
+	if base == nil {
+		return nil
+	}
+	return base.Template.Validate(path.Child("template"))
+}
+
+// Validate checks the metadata and the spec of the job
+func (base *JobBase) Validate(path *field.Path) field.ErrorList {
+	if base == nil {
+		return nil
+	}
+	errs := validateMetaBase(base.MetaBase, path.Child("metadata"))
+	return append(errs, base.Spec.Validate(path.Child("spec"))...)
+}
+
+// Validate checks the counters and the pod template
+func (base *JobSpecBase) Validate(path *field.Path) field.ErrorList {
+	if base == nil {
+		return nil
+	}
+	errs := validateNonNegative(base.Parallelism, path.Child("parallelism"))
+	errs = append(errs, validateNonNegative(base.Completions, path.Child("completions"))...)
+	errs = append(errs, validateNonNegative(base.BackoffLimit, path.Child("backoffLimit"))...)
+	return append(errs, base.Template.Validate(path.Child("template"))...)
+}
+
+// Validate checks the metadata and the spec of the cronjob
+func (base *CronJobBase) Validate(path *field.Path) field.ErrorList {
+	if base == nil {
+		return nil
+	}
+	errs := validateMetaBase(base.MetaBase, path.Child("metadata"))
+	return append(errs, base.Spec.Validate(path.Child("spec"))...)
+}
+
+// Validate checks the job template
+func (base *CronJobSpecBase) Validate(path *field.Path) field.ErrorList {
+	if base == nil {
+		return nil
+	}
+	return base.JobTemplate.Validate(path.Child("jobTemplate"))
+}
+
+// Validate checks the metadata and the spec of the job template
+func (base *JobTemplateBase) Validate(path *field.Path) field.ErrorList {
+	if base == nil {
+		return nil
+	}
+	errs := validateMetaBase(base.Metadata, path.Child("metadata"))
+	return append(errs, base.Spec.Validate(path.Child("spec"))...)
+}
+
+// Validate checks the metadata and the spec of the autoscaler
+func (base *HorizontalPodAutoscalerBase) Validate(path *field.Path) field.ErrorList {
+	if base == nil {
+		return nil
+	}
+	errs := validateMetaBase(base.MetaBase, path.Child("metadata"))
+	return append(errs, base.Spec.Validate(path.Child("spec"))...)
+}
+
+// Validate checks the replica bounds of the autoscaler
+func (base *HorizontalPodAutoscalerSpecBase) Validate(path *field.Path) field.ErrorList {
+	if base == nil {
+		return nil
+	}
+	var errs field.ErrorList
+	if base.MinReplicas != nil && *base.MinReplicas < 1 {
+		errs = append(errs, field.Invalid(path.Child("minReplicas"), *base.MinReplicas, "must be greater than or equal to 1"))
+	}
+	if base.MaxReplicas != nil && *base.MaxReplicas < 1 {
+		errs = append(errs, field.Invalid(path.Child("maxReplicas"), *base.MaxReplicas, "must be greater than or equal to 1"))
+	}
+	if base.MinReplicas != nil && base.MaxReplicas != nil && *base.MinReplicas > *base.MaxReplicas {
+		errs = append(errs, field.Invalid(path.Child("maxReplicas"), *base.MaxReplicas, "must be greater than or equal to minReplicas"))
+	}
+	return errs
+}
+
+// Validate checks the metadata and the spec of the disruption budget
+func (base *PodDisruptionBudgetBase) Validate(path *field.Path) field.ErrorList {
+	if base == nil {
+		return nil
+	}
+	errs := validateMetaBase(base.MetaBase, path.Child("metadata"))
+	return append(errs, base.Spec.Validate(path.Child("spec"))...)
+}
+
+// Validate checks that only one of minAvailable and maxUnavailable is set, Override would clear both otherwise
+func (base *PodDisruptionBudgetSpecBase) Validate(path *field.Path) field.ErrorList {
+	if base == nil || base.MinAvailable == nil || base.MaxUnavailable == nil {
+		return nil
+	}
+	return field.ErrorList{field.Forbidden(path.Child("maxUnavailable"), "minAvailable and maxUnavailable are mutually exclusive")}
+}
+
+// validateMetaBase is not a method of MetaBase, as it would be promoted to the types embedding it
+func validateMetaBase(base *MetaBase, path *field.Path) field.ErrorList {
+	if base == nil {
+		return nil
+	}
+	errs := apivalidation.ValidateAnnotations(base.Annotations, path.Child("annotations"))
+	return append(errs, metav1validation.ValidateLabels(base.Labels, path.Child("labels"))...)
+}
+
+func validateNonNegative(value *int32, path *field.Path) field.ErrorList {
+	if value == nil {
+		return nil
+	}
+	return apivalidation.ValidateNonnegativeField(int64(*value), path)
+}
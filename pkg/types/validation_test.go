@@ -0,0 +1,188 @@
+// Copyright © 2020 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/cisco-open/operator-tools/pkg/types"
+	"github.com/cisco-open/operator-tools/pkg/utils"
+)
+
+func fieldPaths(errs field.ErrorList) []string {
+	paths := make([]string, 0, len(errs))
+	for _, err := range errs {
+		paths = append(paths, err.Field)
+	}
+	return paths
+}
+
+func TestDeploymentBaseValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		base  *types.DeploymentBase
+		paths []string
+	}{
+		{
+			name: "nil",
+		},
+		{
+			name: "valid",
+			base: &types.DeploymentBase{
+				MetaBase: &types.MetaBase{Labels: map[string]string{"app": "test"}},
+				Spec: &types.DeploymentSpecBase{
+					Replicas: utils.IntPointer(2),
+					Template: &types.PodTemplateBase{
+						PodSpec: &types.PodSpecBase{
+							Containers: []types.ContainerBase{{
+								Name:       "app",
+								PullPolicy: v1.PullAlways,
+								Resources: &v1.ResourceRequirements{
+									Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("100m")},
+									Limits:   v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")},
+								},
+							}},
+							Volumes: []v1.Volume{{Name: "config", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}}},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "invalid",
+			base: &types.DeploymentBase{
+				MetaBase: &types.MetaBase{Labels: map[string]string{"app": "in valid"}},
+				Spec: &types.DeploymentSpecBase{
+					Replicas: utils.IntPointer(-1),
+					Template: &types.PodTemplateBase{
+						PodSpec: &types.PodSpecBase{
+							InitContainers: []types.ContainerBase{{Name: "app"}},
+							Containers: []types.ContainerBase{
+								{
+									Name:       "app",
+									PullPolicy: "Sometimes",
+									Resources: &v1.ResourceRequirements{
+										Requests: v1.ResourceList{v1.ResourceMemory: resource.MustParse("2Gi")},
+										Limits:   v1.ResourceList{v1.ResourceMemory: resource.MustParse("1Gi")},
+									},
+									Env: []v1.EnvVar{{Name: "A"}, {Name: "A"}},
+									Ports: []v1.ContainerPort{
+										{ContainerPort: 0},
+										{ContainerPort: 8080},
+										{ContainerPort: 8080, Protocol: v1.ProtocolTCP},
+										{ContainerPort: 8080, Protocol: v1.ProtocolUDP},
+									},
+									VolumeMounts: []v1.VolumeMount{{Name: "config"}},
+								},
+								{Image: "sidecar"},
+							},
+							Volumes: []v1.Volume{
+								{Name: "config", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}, Secret: &v1.SecretVolumeSource{}}},
+								{Name: "config"},
+							},
+							Tolerations: []v1.Toleration{
+								{Operator: v1.TolerationOpEqual, Value: "true"},
+								{Operator: v1.TolerationOpExists},
+							},
+						},
+					},
+				},
+			},
+			paths: []string{
+				"metadata.labels",
+				"spec.replicas",
+				"spec.template.spec.volumes[0]",
+				"spec.template.spec.volumes[1].name",
+				"spec.template.spec.containers[0].name",
+				"spec.template.spec.containers[0].pullPolicy",
+				"spec.template.spec.containers[0].resources.requests[memory]",
+				"spec.template.spec.containers[0].env[1].name",
+				"spec.template.spec.tolerations[0].key",
+				"spec.template.spec.containers[0].ports[0].containerPort",
+				"spec.template.spec.containers[0].ports[2].containerPort",
+				"spec.template.spec.containers[0].ports[3].containerPort",
+				"spec.template.spec.containers[0].volumeMounts[0].mountPath",
+				"spec.template.spec.containers[1].name",
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ElementsMatch(t, tt.paths, fieldPaths(tt.base.Validate(nil)))
+		})
+	}
+}
+
+func TestPodSpecBaseValidateTarget(t *testing.T) {
+	base := &types.PodSpecBase{
+		Containers: []types.ContainerBase{
+			{Name: "app", VolumeMounts: []v1.VolumeMount{{Name: "data", MountPath: "/data"}, {Name: "config", MountPath: "/config"}}},
+			{Name: "sidecar"},
+			{Name: "exporter", Image: "exporter", VolumeMounts: []v1.VolumeMount{{Name: "missing", MountPath: "/missing"}}},
+		},
+		Volumes: []v1.Volume{{Name: "config"}},
+	}
+	target := v1.PodSpec{
+		Containers: []v1.Container{{Name: "app", Image: "app"}},
+		Volumes:    []v1.Volume{{Name: "data"}},
+	}
+
+	errs := base.ValidateTarget(target, field.NewPath("spec"))
+	assert.Equal(t, []string{
+		"spec.containers[1].image",
+		"spec.containers[2].volumeMounts[0].name",
+	}, fieldPaths(errs))
+}
+
+func TestBoundsValidate(t *testing.T) {
+	pdb := &types.PodDisruptionBudgetSpecBase{
+		MinAvailable:   &intstr.IntOrString{Type: intstr.Int, IntVal: 1},
+		MaxUnavailable: &intstr.IntOrString{Type: intstr.String, StrVal: "10%"},
+	}
+	assert.Equal(t, []string{"maxUnavailable"}, fieldPaths(pdb.Validate(nil)))
+
+	hpa := &types.HorizontalPodAutoscalerSpecBase{MinReplicas: utils.IntPointer(3), MaxReplicas: utils.IntPointer(2)}
+	assert.Equal(t, []string{"maxReplicas"}, fieldPaths(hpa.Validate(nil)))
+
+	hpa.MinReplicas = utils.IntPointer(0)
+	assert.Equal(t, []string{"minReplicas"}, fieldPaths(hpa.Validate(nil)))
+}
+
+func TestContainerBasePortsValidatedOverride(t *testing.T) {
+	container := v1.Container{Name: "dns", Image: "dns:1.0", Ports: []v1.ContainerPort{{ContainerPort: 53, Protocol: v1.ProtocolTCP}}}
+
+	// the same port with two protocols passes the api server, but can't be merged by containerPort
+	invalid := &types.ContainerBase{
+		Ports: []v1.ContainerPort{{ContainerPort: 53, Protocol: v1.ProtocolTCP}, {ContainerPort: 53, Protocol: v1.ProtocolUDP}},
+	}
+	assert.Equal(t, []string{"ports[1].containerPort"}, fieldPaths(invalid.Validate(nil)))
+	_, err := invalid.TryOverride(container)
+	assert.Error(t, err)
+
+	valid := &types.ContainerBase{
+		Ports: []v1.ContainerPort{{ContainerPort: 53, Protocol: v1.ProtocolUDP}, {ContainerPort: 9153, Name: "metrics"}},
+	}
+	assert.Empty(t, valid.Validate(nil))
+	result, err := valid.TryOverride(container)
+	assert.NoError(t, err)
+	assert.Equal(t, []v1.ContainerPort{{ContainerPort: 53, Protocol: v1.ProtocolUDP}, {ContainerPort: 9153, Name: "metrics"}}, result.Ports)
+}